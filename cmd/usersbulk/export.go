@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Vadim-Makhnev/grpc/internal/data"
+	"github.com/Vadim-Makhnev/grpc/internal/validator"
+)
+
+const exportPageSize = 500
+
+var exportColumns = []string{
+	"id", "name", "display_name", "email", "phone", "locale", "time_zone",
+	"birthdate", "age", "attributes", "created_at", "updated_at", "erased_at", "version",
+}
+
+func parseColumns(list string) ([]string, error) {
+	var columns []string
+
+	for _, col := range strings.Split(list, ",") {
+		col = strings.ToLower(strings.TrimSpace(col))
+		if col == "" {
+			continue
+		}
+
+		if !validator.In(col, exportColumns...) {
+			return nil, fmt.Errorf("unknown column %q: must be one of %s", col, strings.Join(exportColumns, ", "))
+		}
+
+		columns = append(columns, col)
+	}
+
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("at least one column is required")
+	}
+
+	return columns, nil
+}
+
+func columnValue(user *data.User, column string) any {
+	switch column {
+	case "id":
+		return user.ID
+	case "name":
+		return user.Name
+	case "display_name":
+		return user.DisplayName
+	case "email":
+		return user.Email
+	case "phone":
+		return user.Phone
+	case "locale":
+		return user.Locale
+	case "time_zone":
+		return user.TimeZone
+	case "birthdate":
+		if user.Birthdate.IsZero() {
+			return ""
+		}
+		return user.Birthdate.Format(time.DateOnly)
+	case "age":
+		return user.Age
+	case "attributes":
+		if user.Attributes == nil {
+			return data.Attributes{}
+		}
+		return user.Attributes
+	case "created_at":
+		return user.CreatedAt.UTC().Format(time.RFC3339)
+	case "updated_at":
+		return user.UpdatedAt.UTC().Format(time.RFC3339)
+	case "erased_at":
+		if !user.Erased() {
+			return ""
+		}
+		return user.ErasedAt.UTC().Format(time.RFC3339)
+	case "version":
+		return user.Version
+	default:
+		return nil
+	}
+}
+
+func exportUsers(ctx context.Context, users data.UserStorage, w io.Writer, format string, columns []string) (int, error) {
+	var (
+		csvWriter   *csv.Writer
+		jsonEncoder *json.Encoder
+	)
+
+	switch format {
+	case formatCSV:
+		csvWriter = csv.NewWriter(w)
+		if err := csvWriter.Write(columns); err != nil {
+			return 0, err
+		}
+	default:
+		jsonEncoder = json.NewEncoder(w)
+	}
+
+	filters := data.Filters{
+		Page:         1,
+		PageSize:     exportPageSize,
+		Sort:         "id",
+		SortSafelist: []string{"id"},
+	}
+
+	total := 0
+
+	for {
+		page, _, err := users.GetAll(ctx, filters)
+		if err != nil {
+			return total, err
+		}
+
+		for _, user := range page {
+			if csvWriter != nil {
+				record := make([]string, len(columns))
+				for i, col := range columns {
+					switch value := columnValue(user, col).(type) {
+					case string:
+						record[i] = value
+					case int64:
+						record[i] = strconv.FormatInt(value, 10)
+					case int32:
+						record[i] = strconv.FormatInt(int64(value), 10)
+					case data.Attributes:
+						js, err := json.Marshal(value)
+						if err != nil {
+							return total, err
+						}
+						record[i] = string(js)
+					}
+				}
+
+				if err := csvWriter.Write(record); err != nil {
+					return total, err
+				}
+			} else {
+				object := make(map[string]any, len(columns))
+				for _, col := range columns {
+					object[col] = columnValue(user, col)
+				}
+
+				if err := jsonEncoder.Encode(object); err != nil {
+					return total, err
+				}
+			}
+
+			total++
+		}
+
+		if len(page) < filters.PageSize {
+			break
+		}
+
+		filters.Page++
+	}
+
+	if csvWriter != nil {
+		csvWriter.Flush()
+		return total, csvWriter.Error()
+	}
+
+	return total, nil
+}
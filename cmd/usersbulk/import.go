@@ -0,0 +1,469 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Vadim-Makhnev/grpc/internal/data"
+	"github.com/Vadim-Makhnev/grpc/internal/validator"
+)
+
+const (
+	modeInsert = "insert"
+	modeUpsert = "upsert"
+)
+
+const checkpointInterval = 100
+
+type importOptions struct {
+	source     string
+	format     string
+	mode       string
+	dryRun     bool
+	reportPath string
+	statePath  string
+}
+
+type importRow struct {
+	number int
+	user   data.User
+	errors map[string]string
+}
+
+// setBirthdate parses a YYYY-MM-DD birthdate, recording an error for the row
+// if it is malformed.
+func (row *importRow) setBirthdate(value string) {
+	birthdate, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		if row.errors == nil {
+			row.errors = map[string]string{}
+		}
+		row.errors["birthdate"] = "must be a date in YYYY-MM-DD format"
+		return
+	}
+	row.user.Birthdate = birthdate
+}
+
+type rowReader interface {
+	next() (*importRow, error)
+}
+
+type importState struct {
+	Source string `json:"source"`
+	Row    int    `json:"row"`
+}
+
+type importSummary struct {
+	dryRun   bool
+	skipped  int
+	valid    int
+	created  int
+	updated  int
+	rejected int
+}
+
+func (s importSummary) String() string {
+	if s.dryRun {
+		return fmt.Sprintf("dry run: %d valid, %d rejected", s.valid, s.rejected)
+	}
+
+	msg := fmt.Sprintf("%d created, %d updated, %d rejected", s.created, s.updated, s.rejected)
+	if s.skipped > 0 {
+		msg += fmt.Sprintf(" (resumed after row %d)", s.skipped)
+	}
+
+	return msg
+}
+
+func importUsers(ctx context.Context, users data.UserStorage, opts importOptions) (importSummary, error) {
+	summary := importSummary{dryRun: opts.dryRun}
+
+	in, err := os.Open(opts.source)
+	if err != nil {
+		return summary, err
+	}
+	defer in.Close()
+
+	var rows rowReader
+
+	switch opts.format {
+	case formatCSV:
+		rows, err = newCSVRowReader(in)
+		if err != nil {
+			return summary, err
+		}
+	default:
+		rows = newJSONLRowReader(in)
+	}
+
+	resumeAfter := 0
+	if !opts.dryRun {
+		resumeAfter, err = readImportState(opts.statePath, opts.source)
+		if err != nil {
+			return summary, err
+		}
+	}
+
+	reportFlags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if resumeAfter > 0 {
+		reportFlags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+
+	reportFile, err := os.OpenFile(opts.reportPath, reportFlags, 0o644)
+	if err != nil {
+		return summary, err
+	}
+	defer reportFile.Close()
+
+	report := csv.NewWriter(reportFile)
+	if resumeAfter == 0 {
+		report.Write([]string{"row", "email", "status", "errors"})
+	}
+
+	lastRow := resumeAfter
+	summary.skipped = resumeAfter
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return summary, checkpoint(report, opts, lastRow, err)
+		}
+
+		row, err := rows.next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return summary, checkpoint(report, opts, lastRow, err)
+		}
+
+		if row.number <= resumeAfter {
+			continue
+		}
+
+		status, err := importRowStatus(ctx, users, opts, row)
+		if err != nil {
+			// The row is imported again on resume, so it isn't reported.
+			return summary, checkpoint(report, opts, lastRow, err)
+		}
+
+		switch status {
+		case "valid":
+			summary.valid++
+		case "created":
+			summary.created++
+		case "updated":
+			summary.updated++
+		default:
+			summary.rejected++
+		}
+
+		report.Write([]string{strconv.Itoa(row.number), row.user.Email, status, formatRowErrors(row.errors)})
+
+		lastRow = row.number
+
+		if !opts.dryRun && lastRow%checkpointInterval == 0 {
+			if err := checkpoint(report, opts, lastRow, nil); err != nil {
+				return summary, err
+			}
+		}
+	}
+
+	report.Flush()
+	if err := report.Error(); err != nil {
+		return summary, err
+	}
+
+	if !opts.dryRun {
+		if err := os.Remove(opts.statePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return summary, err
+		}
+	}
+
+	return summary, nil
+}
+
+// importRowStatus imports a row and returns its status for the report. It
+// only returns an error when the row failed for a reason that has nothing to
+// do with the row itself, so that the import stops and the row is tried
+// again on resume.
+func importRowStatus(ctx context.Context, users data.UserStorage, opts importOptions, row *importRow) (string, error) {
+	if len(row.errors) == 0 {
+		v := validator.New()
+		data.ValidateUser(v, &row.user)
+		row.errors = v.Errors
+	}
+
+	if len(row.errors) > 0 {
+		return "invalid", nil
+	}
+
+	if opts.dryRun {
+		return "valid", nil
+	}
+
+	switch opts.mode {
+	case modeUpsert:
+		inserted, err := users.UpsertUserByEmail(ctx, &row.user)
+		switch {
+		case isInterruption(ctx, err):
+			return "", err
+		case err != nil:
+			row.errors = map[string]string{"database": err.Error()}
+			return "failed", nil
+		case inserted:
+			return "created", nil
+		}
+		return "updated", nil
+	default:
+		err := users.CreateUser(ctx, &row.user)
+		switch {
+		case errors.Is(err, data.ErrDuplicateEmail):
+			row.errors = map[string]string{"email": "a user with this email address already exists"}
+			return "invalid", nil
+		case isInterruption(ctx, err):
+			return "", err
+		case err != nil:
+			row.errors = map[string]string{"database": err.Error()}
+			return "failed", nil
+		}
+		return "created", nil
+	}
+}
+
+// isInterruption reports whether err stopped a write because the import was
+// cancelled or the database was unavailable, rather than because of the row.
+func isInterruption(ctx context.Context, err error) bool {
+	if err == nil {
+		return false
+	}
+
+	return ctx.Err() != nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, data.ErrCircuitOpen) || data.IsTransient(err)
+}
+
+func checkpoint(report *csv.Writer, opts importOptions, row int, cause error) error {
+	report.Flush()
+	if err := report.Error(); err != nil {
+		return errors.Join(cause, err)
+	}
+
+	if opts.dryRun || row == 0 {
+		return cause
+	}
+
+	js, err := json.Marshal(importState{Source: opts.source, Row: row})
+	if err != nil {
+		return errors.Join(cause, err)
+	}
+
+	if err := os.WriteFile(opts.statePath, js, 0o644); err != nil {
+		return errors.Join(cause, err)
+	}
+
+	if cause != nil {
+		return fmt.Errorf("import stopped after row %d, rerun the same command to resume: %w", row, cause)
+	}
+
+	return nil
+}
+
+func readImportState(path, source string) (int, error) {
+	js, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	var state importState
+	if err := json.Unmarshal(js, &state); err != nil {
+		return 0, fmt.Errorf("invalid checkpoint file %s: %w", path, err)
+	}
+
+	if state.Source != source {
+		return 0, fmt.Errorf("checkpoint file %s belongs to %s, not %s", path, state.Source, source)
+	}
+
+	return state.Row, nil
+}
+
+func formatRowErrors(errs map[string]string) string {
+	keys := make([]string, 0, len(errs))
+	for key := range errs {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	msgs := make([]string, len(keys))
+	for i, key := range keys {
+		msgs[i] = key + ": " + errs[key]
+	}
+
+	return strings.Join(msgs, "; ")
+}
+
+type csvRowReader struct {
+	r       *csv.Reader
+	columns map[string]int
+	number  int
+}
+
+func newCSVRowReader(r io.Reader) (*csvRowReader, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("unable to read CSV header: %w", err)
+	}
+
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	for _, required := range []string{"name", "email"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("CSV header must contain a %q column", required)
+		}
+	}
+
+	_, hasAge := columns["age"]
+	_, hasBirthdate := columns["birthdate"]
+	if !hasAge && !hasBirthdate {
+		return nil, fmt.Errorf(`CSV header must contain a "birthdate" or "age" column`)
+	}
+
+	return &csvRowReader{r: cr, columns: columns}, nil
+}
+
+func (c *csvRowReader) next() (*importRow, error) {
+	record, err := c.r.Read()
+	if err != nil {
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			c.number++
+			return &importRow{number: c.number, errors: map[string]string{"row": parseErr.Err.Error()}}, nil
+		}
+		return nil, err
+	}
+
+	c.number++
+
+	field := func(name string) string {
+		i, ok := c.columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	row := &importRow{
+		number: c.number,
+		user: data.User{
+			Name:        field("name"),
+			DisplayName: field("display_name"),
+			Email:       field("email"),
+			Phone:       field("phone"),
+			Locale:      field("locale"),
+			TimeZone:    field("time_zone"),
+		},
+	}
+
+	if age := field("age"); age != "" {
+		n, err := strconv.ParseInt(age, 10, 32)
+		if err != nil {
+			row.errors = map[string]string{"age": "must be an integer"}
+		}
+		row.user.Age = int32(n)
+	}
+
+	if birthdate := field("birthdate"); birthdate != "" {
+		row.setBirthdate(birthdate)
+	}
+
+	return row, nil
+}
+
+type jsonlRowReader struct {
+	s      *bufio.Scanner
+	number int
+}
+
+func newJSONLRowReader(r io.Reader) *jsonlRowReader {
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 64*1024), 1_048_576)
+
+	return &jsonlRowReader{s: s}
+}
+
+func (j *jsonlRowReader) next() (*importRow, error) {
+	for j.s.Scan() {
+		line := strings.TrimSpace(j.s.Text())
+		if line == "" {
+			continue
+		}
+
+		j.number++
+
+		var input struct {
+			Name        string            `json:"name"`
+			DisplayName string            `json:"display_name"`
+			Email       string            `json:"email"`
+			Phone       string            `json:"phone"`
+			Locale      string            `json:"locale"`
+			TimeZone    string            `json:"time_zone"`
+			Birthdate   string            `json:"birthdate"`
+			Age         int32             `json:"age"`
+			Attributes  map[string]string `json:"attributes"`
+		}
+
+		row := &importRow{number: j.number}
+
+		if err := json.Unmarshal([]byte(line), &input); err != nil {
+			row.errors = map[string]string{"row": "contains badly-formed JSON"}
+
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &typeErr) && typeErr.Field != "" {
+				row.errors = map[string]string{typeErr.Field: "has an invalid type"}
+			}
+
+			return row, nil
+		}
+
+		row.user = data.User{
+			Name:        input.Name,
+			DisplayName: input.DisplayName,
+			Email:       input.Email,
+			Phone:       input.Phone,
+			Locale:      input.Locale,
+			TimeZone:    input.TimeZone,
+			Age:         input.Age,
+			Attributes:  data.Attributes(nil).Merge(input.Attributes),
+		}
+
+		if input.Birthdate != "" {
+			row.setBirthdate(input.Birthdate)
+		}
+
+		return row, nil
+	}
+
+	if err := j.s.Err(); err != nil {
+		return nil, err
+	}
+
+	return nil, io.EOF
+}
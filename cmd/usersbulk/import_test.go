@@ -0,0 +1,303 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Vadim-Makhnev/grpc/internal/data"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryStorage struct {
+	data.UserStorage
+	users []*data.User
+}
+
+func (m *memoryStorage) CreateUser(ctx context.Context, user *data.User) error {
+	for _, existing := range m.users {
+		if existing.Email == user.Email {
+			return data.ErrDuplicateEmail
+		}
+	}
+
+	user.ID = int64(len(m.users) + 1)
+	user.Version = 1
+	stored := *user
+	m.users = append(m.users, &stored)
+
+	return nil
+}
+
+func (m *memoryStorage) UpsertUserByEmail(ctx context.Context, user *data.User) (bool, error) {
+	for _, existing := range m.users {
+		if existing.Email == user.Email {
+			existing.Name = user.Name
+			existing.Age = user.Age
+			existing.Birthdate = user.Birthdate
+			existing.Version++
+			return false, nil
+		}
+	}
+
+	return true, m.CreateUser(ctx, user)
+}
+
+func (m *memoryStorage) GetAll(ctx context.Context, filters data.Filters) ([]*data.User, data.MetaData, error) {
+	start := (filters.Page - 1) * filters.PageSize
+	if start >= len(m.users) {
+		return []*data.User{}, data.MetaData{}, nil
+	}
+
+	end := min(start+filters.PageSize, len(m.users))
+
+	return m.users[start:end], data.MetaData{}, nil
+}
+
+func writeInput(t *testing.T, name, contents string) importOptions {
+	dir := t.TempDir()
+	source := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(source, []byte(contents), 0o600))
+
+	format, err := detectFormat("", source)
+	require.NoError(t, err)
+
+	return importOptions{
+		source:     source,
+		format:     format,
+		mode:       modeInsert,
+		reportPath: source + ".report.csv",
+		statePath:  source + ".state",
+	}
+}
+
+func readReport(t *testing.T, path string) [][]string {
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	records, err := csv.NewReader(f).ReadAll()
+	require.NoError(t, err)
+
+	return records
+}
+
+const testCSV = `name,email,age
+Andrew,andrew@google.com,31
+,missing-name@google.com,20
+John,not-an-email,abc
+Jane,andrew@google.com,25
+`
+
+func TestImport_CSV(t *testing.T) {
+	opts := writeInput(t, "users.csv", testCSV)
+	storage := &memoryStorage{}
+
+	summary, err := importUsers(context.Background(), storage, opts)
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, summary.created)
+	assert.Equal(t, 3, summary.rejected)
+	assert.Len(t, storage.users, 1)
+
+	assert.Equal(t, [][]string{
+		{"row", "email", "status", "errors"},
+		{"1", "andrew@google.com", "created", ""},
+		{"2", "missing-name@google.com", "invalid", "name: must be provided"},
+		{"3", "not-an-email", "invalid", "age: must be an integer"},
+		{"4", "andrew@google.com", "invalid", "email: a user with this email address already exists"},
+	}, readReport(t, opts.reportPath))
+
+	assert.NoFileExists(t, opts.statePath)
+}
+
+func TestImport_JSONL_DryRun(t *testing.T) {
+	opts := writeInput(t, "users.jsonl", `{"name":"Andrew","email":"andrew@google.com","age":31}
+{"name":"John","email":"john@gmail.com","age":"old"}
+
+{"name":"Jane","email":"jane.com","age":25}
+`)
+	opts.dryRun = true
+
+	summary, err := importUsers(context.Background(), nil, opts)
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, summary.valid)
+	assert.Equal(t, 2, summary.rejected)
+
+	assert.Equal(t, [][]string{
+		{"row", "email", "status", "errors"},
+		{"1", "andrew@google.com", "valid", ""},
+		{"2", "", "invalid", "age: has an invalid type"},
+		{"3", "jane.com", "invalid", "email: must be a valid email address"},
+	}, readReport(t, opts.reportPath))
+}
+
+func TestImport_Upsert(t *testing.T) {
+	opts := writeInput(t, "users.csv", "email,name,age\nandrew@google.com,Andrew,31\nandrew@google.com,Andrew Smith,32\n")
+	opts.mode = modeUpsert
+	storage := &memoryStorage{}
+
+	summary, err := importUsers(context.Background(), storage, opts)
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, summary.created)
+	assert.Equal(t, 1, summary.updated)
+	require.Len(t, storage.users, 1)
+	assert.Equal(t, "Andrew Smith", storage.users[0].Name)
+	assert.Equal(t, int32(2), storage.users[0].Version)
+}
+
+func TestImport_Resume(t *testing.T) {
+	opts := writeInput(t, "users.csv", testCSV)
+	storage := &memoryStorage{}
+
+	require.NoError(t, os.WriteFile(opts.reportPath, []byte("row,email,status,errors\n1,andrew@google.com,created,\n"), 0o644))
+	require.NoError(t, os.WriteFile(opts.statePath, []byte(`{"source":"`+opts.source+`","row":1}`), 0o644))
+
+	summary, err := importUsers(context.Background(), storage, opts)
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, summary.skipped)
+	assert.Equal(t, 1, summary.created)
+	assert.Len(t, readReport(t, opts.reportPath), 5)
+	assert.Equal(t, "Jane", storage.users[0].Name)
+	assert.NoFileExists(t, opts.statePath)
+}
+
+func TestImport_CanceledWritesCheckpoint(t *testing.T) {
+	opts := writeInput(t, "users.csv", testCSV)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := importUsers(ctx, &memoryStorage{}, opts)
+
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+// interruptingStorage cancels the import while it writes the user with the
+// given email.
+type interruptingStorage struct {
+	*memoryStorage
+	email  string
+	cancel context.CancelFunc
+}
+
+func (s *interruptingStorage) CreateUser(ctx context.Context, user *data.User) error {
+	if user.Email == s.email {
+		s.cancel()
+		return ctx.Err()
+	}
+
+	return s.memoryStorage.CreateUser(ctx, user)
+}
+
+func TestImport_CanceledRowIsResumed(t *testing.T) {
+	opts := writeInput(t, "users.csv", "name,email,age\nAndrew,andrew@google.com,31\nJohn,john@gmail.com,21\nJane,jane@gmail.com,25\n")
+	storage := &memoryStorage{}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, err := importUsers(ctx, &interruptingStorage{memoryStorage: storage, email: "john@gmail.com", cancel: cancel}, opts)
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Len(t, storage.users, 1)
+
+	state, err := os.ReadFile(opts.statePath)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"source":"`+opts.source+`","row":1}`, string(state))
+
+	summary, err := importUsers(context.Background(), storage, opts)
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, summary.skipped)
+	assert.Equal(t, 2, summary.created)
+	assert.Len(t, storage.users, 3)
+
+	assert.Equal(t, [][]string{
+		{"row", "email", "status", "errors"},
+		{"1", "andrew@google.com", "created", ""},
+		{"2", "john@gmail.com", "created", ""},
+		{"3", "jane@gmail.com", "created", ""},
+	}, readReport(t, opts.reportPath))
+}
+
+func TestImport_MissingColumn(t *testing.T) {
+	opts := writeInput(t, "users.csv", "name,email\nAndrew,andrew@google.com\n")
+
+	_, err := importUsers(context.Background(), &memoryStorage{}, opts)
+
+	assert.ErrorContains(t, err, `CSV header must contain a "birthdate" or "age" column`)
+}
+
+func TestImport_Profile(t *testing.T) {
+	opts := writeInput(t, "users.csv", "name,email,birthdate,phone,time_zone\n"+
+		"Andrew,andrew@google.com,1994-03-15,+1 (415) 555-0123,America/Los_Angeles\n"+
+		"John,john@gmail.com,15.03.1994,,\n")
+	storage := &memoryStorage{}
+
+	summary, err := importUsers(context.Background(), storage, opts)
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, summary.created)
+	assert.Equal(t, 1, summary.rejected)
+
+	require.Len(t, storage.users, 1)
+	assert.Equal(t, "1994-03-15", storage.users[0].Birthdate.Format(time.DateOnly))
+	assert.Equal(t, "+14155550123", storage.users[0].Phone)
+	assert.Equal(t, "America/Los_Angeles", storage.users[0].TimeZone)
+
+	assert.Equal(t, []string{"2", "john@gmail.com", "invalid", "birthdate: must be a date in YYYY-MM-DD format"}, readReport(t, opts.reportPath)[2])
+}
+
+func TestExport(t *testing.T) {
+	storage := &memoryStorage{}
+	for i := 0; i < exportPageSize+2; i++ {
+		require.NoError(t, storage.CreateUser(context.Background(), &data.User{
+			Name:  "User",
+			Email: fmt.Sprintf("user%d@example.com", i),
+			Age:   30,
+		}))
+	}
+
+	t.Run("csv with selected columns", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		n, err := exportUsers(context.Background(), storage, &buf, formatCSV, []string{"id", "name"})
+
+		require.NoError(t, err)
+		assert.Equal(t, exportPageSize+2, n)
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		assert.Equal(t, "id,name", lines[0])
+		assert.Equal(t, "1,User", lines[1])
+		assert.Len(t, lines, exportPageSize+3)
+	})
+
+	t.Run("jsonl", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		_, err := exportUsers(context.Background(), storage, &buf, formatJSONL, []string{"id", "age"})
+
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(buf.String(), `{"age":30,"id":1}`+"\n"))
+	})
+}
+
+func TestParseColumns(t *testing.T) {
+	cols, err := parseColumns("email, name")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"email", "name"}, cols)
+
+	_, err = parseColumns("email,password")
+	assert.ErrorContains(t, err, `unknown column "password"`)
+}
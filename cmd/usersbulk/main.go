@@ -0,0 +1,378 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Vadim-Makhnev/grpc/internal/data"
+	"github.com/Vadim-Makhnev/grpc/internal/encryption"
+	_ "github.com/lib/pq"
+)
+
+const (
+	formatCSV   = "csv"
+	formatJSONL = "jsonl"
+)
+
+const usage = `usage: usersbulk <command> [flags]
+
+Commands:
+  import     load users from a CSV or JSONL file
+  export     write users to a CSV or JSONL file
+  reencrypt  encrypt the names and emails of a tenant's users with the current key
+
+Run "usersbulk <command> -h" for the flags of a command.
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	var err error
+
+	switch args[0] {
+	case "import":
+		err = runImport(ctx, args[1:], stdout, stderr)
+	case "export":
+		err = runExport(ctx, args[1:], stdout, stderr)
+	case "reencrypt":
+		err = runReencrypt(ctx, args[1:], stdout, stderr)
+	default:
+		fmt.Fprintf(stderr, "unknown command %q\n\n%s", args[0], usage)
+		return 2
+	}
+
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, flag.ErrHelp):
+		return 0
+	case errors.Is(err, errUsage):
+		return 2
+	default:
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 1
+	}
+}
+
+var errUsage = errors.New("invalid usage")
+
+func runImport(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("usersbulk import", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	dsn := fs.String("db-dsn", os.Getenv("GRPC_DB_DSN"), "PostgreSQL DSN")
+	rls := fs.Bool("db-row-level-security", false, "Set app.tenant_id for PostgreSQL row-level security policies")
+	keyFile := fs.String("encryption-key-file", os.Getenv("GRPC_ENCRYPTION_KEY_FILE"), "Key file to encrypt names and emails with, as used by the server")
+	tenant := fs.String("tenant", "default", "Tenant the imported users belong to")
+	format := fs.String("format", "", "Input format (csv|jsonl), detected from the file extension by default")
+
+	var opts importOptions
+	fs.StringVar(&opts.mode, "mode", modeInsert, "Write mode (insert|upsert); upsert updates existing users matched by email")
+	fs.BoolVar(&opts.dryRun, "dry-run", false, "Validate every row and write the report without touching the database")
+	fs.StringVar(&opts.reportPath, "report", "", "Path of the per-row CSV report (default <input>.report.csv)")
+	fs.StringVar(&opts.statePath, "state", "", "Path of the checkpoint file used to resume an interrupted import (default <input>.state)")
+
+	if err := fs.Parse(args); err != nil {
+		return usageError(err)
+	}
+
+	if fs.NArg() != 1 {
+		fmt.Fprintln(stderr, "usage: usersbulk import [flags] <file>")
+		return errUsage
+	}
+
+	opts.source = fs.Arg(0)
+
+	if !data.TenantRX.MatchString(*tenant) {
+		fmt.Fprintf(stderr, "invalid tenant %q\n", *tenant)
+		return errUsage
+	}
+
+	ctx = data.ContextWithTenant(ctx, *tenant)
+
+	if opts.mode != modeInsert && opts.mode != modeUpsert {
+		fmt.Fprintf(stderr, "invalid mode %q: must be insert or upsert\n", opts.mode)
+		return errUsage
+	}
+
+	var err error
+	if opts.format, err = detectFormat(*format, opts.source); err != nil {
+		fmt.Fprintln(stderr, err)
+		return errUsage
+	}
+
+	if opts.reportPath == "" {
+		opts.reportPath = opts.source + ".report.csv"
+	}
+
+	if opts.statePath == "" {
+		opts.statePath = opts.source + ".state"
+	}
+
+	var users data.UserStorage
+
+	if !opts.dryRun {
+		db, err := openDB(*dsn)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		cipher, err := loadCipher(*keyFile)
+		if err != nil {
+			return err
+		}
+
+		users = data.NewModels(db, *rls, cipher).Users
+	}
+
+	summary, err := importUsers(ctx, users, opts)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(stdout, summary)
+
+	return nil
+}
+
+func runExport(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("usersbulk export", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	dsn := fs.String("db-dsn", os.Getenv("GRPC_DB_DSN"), "PostgreSQL DSN")
+	rls := fs.Bool("db-row-level-security", false, "Set app.tenant_id for PostgreSQL row-level security policies")
+	keyFile := fs.String("encryption-key-file", os.Getenv("GRPC_ENCRYPTION_KEY_FILE"), "Key file to decrypt names and emails with, as used by the server")
+	tenant := fs.String("tenant", "default", "Tenant whose users are exported")
+	format := fs.String("format", "", "Output format (csv|jsonl), detected from the file extension by default")
+	output := fs.String("o", "-", "Output file, - for stdout")
+	columns := fs.String("columns", strings.Join(exportColumns, ","), "Comma-separated list of columns to export")
+
+	if err := fs.Parse(args); err != nil {
+		return usageError(err)
+	}
+
+	if fs.NArg() > 0 {
+		fmt.Fprintln(stderr, "usage: usersbulk export [flags]")
+		return errUsage
+	}
+
+	if !data.TenantRX.MatchString(*tenant) {
+		fmt.Fprintf(stderr, "invalid tenant %q\n", *tenant)
+		return errUsage
+	}
+
+	ctx = data.ContextWithTenant(ctx, *tenant)
+
+	outputFormat, err := detectFormat(*format, *output)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return errUsage
+	}
+
+	cols, err := parseColumns(*columns)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return errUsage
+	}
+
+	cipher, err := loadCipher(*keyFile)
+	if err != nil {
+		return err
+	}
+
+	db, err := openDB(*dsn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	w := stdout
+	if *output != "-" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	n, err := exportUsers(ctx, data.NewModels(db, *rls, cipher).Users, w, outputFormat, cols)
+	if err != nil {
+		return err
+	}
+
+	if *output != "-" {
+		fmt.Fprintf(stdout, "exported %d users to %s\n", n, *output)
+	}
+
+	return nil
+}
+
+func usageError(err error) error {
+	if errors.Is(err, flag.ErrHelp) {
+		return err
+	}
+	return errUsage
+}
+
+func detectFormat(format, path string) (string, error) {
+	if format == "" {
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".csv":
+			format = formatCSV
+		case ".jsonl", ".ndjson":
+			format = formatJSONL
+		case "":
+			if path == "-" {
+				format = formatCSV
+			}
+		}
+	}
+
+	if format != formatCSV && format != formatJSONL {
+		return "", fmt.Errorf("unable to determine format of %q: use -format csv or -format jsonl", path)
+	}
+
+	return format, nil
+}
+
+// runReencrypt is the per-tenant counterpart of the server's re-encryption
+// job, for databases with row-level security where the job can't see other
+// tenants. It can run next to the server and be interrupted at any time.
+func runReencrypt(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("usersbulk reencrypt", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	dsn := fs.String("db-dsn", os.Getenv("GRPC_DB_DSN"), "PostgreSQL DSN")
+	rls := fs.Bool("db-row-level-security", false, "Set app.tenant_id for PostgreSQL row-level security policies")
+	keyFile := fs.String("encryption-key-file", os.Getenv("GRPC_ENCRYPTION_KEY_FILE"), "Key file with the current key, as used by the server")
+	tenant := fs.String("tenant", "default", "Tenant whose users are re-encrypted")
+	batchSize := fs.Int("batch-size", 100, "Users re-encrypted per transaction")
+
+	if err := fs.Parse(args); err != nil {
+		return usageError(err)
+	}
+
+	if fs.NArg() > 0 {
+		fmt.Fprintln(stderr, "usage: usersbulk reencrypt [flags]")
+		return errUsage
+	}
+
+	if !data.TenantRX.MatchString(*tenant) {
+		fmt.Fprintf(stderr, "invalid tenant %q\n", *tenant)
+		return errUsage
+	}
+
+	if *keyFile == "" {
+		fmt.Fprintln(stderr, "-encryption-key-file is required")
+		return errUsage
+	}
+
+	if *batchSize < 1 {
+		fmt.Fprintln(stderr, "-batch-size must be greater than zero")
+		return errUsage
+	}
+
+	ctx = data.ContextWithTenant(ctx, *tenant)
+
+	cipher, err := loadCipher(*keyFile)
+	if err != nil {
+		return err
+	}
+
+	db, err := openDB(*dsn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	users := data.UserModel{DB: db, RowLevelSecurity: *rls, Cipher: cipher}
+
+	// Every plaintext user gets an email hash first, so that the unique
+	// index on email_hash covers the tenant before the first batch is
+	// re-encrypted.
+	for {
+		n, err := users.BackfillEmailHashes(ctx, *batchSize)
+		if err != nil {
+			return err
+		}
+
+		if n == 0 {
+			break
+		}
+	}
+
+	total := 0
+	for {
+		n, err := users.ReencryptUsers(ctx, *batchSize)
+		if err != nil {
+			return err
+		}
+
+		if n == 0 {
+			break
+		}
+
+		total += n
+	}
+
+	fmt.Fprintf(stdout, "re-encrypted %d users of tenant %s with key version %d\n", total, *tenant, cipher.CurrentKeyVersion())
+
+	return nil
+}
+
+// loadCipher returns nil for an empty path, which keeps names and emails in
+// plaintext.
+func loadCipher(path string) (*encryption.Cipher, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	keys, err := encryption.LoadKeyFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return encryption.New(keys), nil
+}
+
+func openDB(dsn string) (*sql.DB, error) {
+	if dsn == "" {
+		return nil, errors.New("a database DSN is required: use -db-dsn or GRPC_DB_DSN")
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
@@ -0,0 +1,388 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Vadim-Makhnev/grpc/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+var errUsage = errors.New("invalid usage")
+
+func (c *cli) commandFlags(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet("usersctl "+name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	return fs
+}
+
+// attributesFlag collects repeated -attr key=value flags.
+type attributesFlag map[string]string
+
+func (a attributesFlag) String() string {
+	pairs := make([]string, 0, len(a))
+	for key, value := range a {
+		pairs = append(pairs, key+"="+value)
+	}
+	slices.Sort(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func (a attributesFlag) Set(value string) error {
+	key, value, ok := strings.Cut(value, "=")
+	if !ok || key == "" {
+		return errors.New("must be in key=value format")
+	}
+	a[key] = value
+	return nil
+}
+
+// listFlag collects repeated flags, each of which may hold a comma-separated list.
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlag) Set(value string) error {
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*l = append(*l, item)
+		}
+	}
+	return nil
+}
+
+func (c *cli) parse(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+
+	if fs.NArg() > 0 {
+		fmt.Fprintf(c.stderr, "unexpected arguments: %v\n", fs.Args())
+		return errUsage
+	}
+
+	return nil
+}
+
+func (c *cli) parseID(fs *flag.FlagSet, args []string) (int64, error) {
+	var id int64
+	fs.Int64Var(&id, "id", 0, "User id")
+
+	if len(args) > 0 && args[0] != "" && args[0][0] != '-' {
+		parsed, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil {
+			fmt.Fprintf(c.stderr, "invalid id %q\n", args[0])
+			return 0, errUsage
+		}
+		id = parsed
+		args = args[1:]
+	}
+
+	if err := c.parse(fs, args); err != nil {
+		return 0, err
+	}
+
+	if id == 0 {
+		fmt.Fprintln(c.stderr, "an id is required")
+		return 0, errUsage
+	}
+
+	return id, nil
+}
+
+func (c *cli) create(ctx context.Context, client proto.UserServiceClient, args []string) error {
+	fs := c.commandFlags("create")
+
+	var req proto.CreateUserRequest
+	fs.StringVar(&req.Name, "name", "", "User name")
+	fs.StringVar(&req.Email, "email", "", "User email")
+	fs.StringVar(&req.Birthdate, "birthdate", "", "User birthdate, YYYY-MM-DD")
+	fs.StringVar(&req.DisplayName, "display-name", "", "User display name")
+	fs.StringVar(&req.Phone, "phone", "", "User phone number, e.g. +14155550123")
+	fs.StringVar(&req.Locale, "locale", "", "User locale, e.g. en-US")
+	fs.StringVar(&req.TimeZone, "time-zone", "", "User time zone, e.g. Europe/Moscow")
+	age := fs.Int("age", 0, "User age, used when -birthdate is not given")
+	attrs := attributesFlag{}
+	fs.Var(attrs, "attr", "User attribute as key=value, may be repeated")
+
+	if err := c.parse(fs, args); err != nil {
+		return err
+	}
+
+	req.Age = int32(*age)
+	req.Attributes = attrs
+
+	user, err := client.CreateUser(ctx, &req)
+	if err != nil {
+		return err
+	}
+
+	return c.printUsers(user)
+}
+
+func (c *cli) get(ctx context.Context, client proto.UserServiceClient, args []string) error {
+	id, err := c.parseID(c.commandFlags("get"), args)
+	if err != nil {
+		return err
+	}
+
+	user, err := client.GetUser(ctx, &proto.GetUserRequest{Id: id})
+	if err != nil {
+		return err
+	}
+
+	return c.printUsers(user)
+}
+
+func (c *cli) lookup(ctx context.Context, client proto.UserServiceClient, args []string) error {
+	fs := c.commandFlags("lookup")
+	email := fs.String("email", "", "User email")
+	id := fs.Int64("id", 0, "User id")
+
+	if err := c.parse(fs, args); err != nil {
+		return err
+	}
+
+	var req proto.LookupUserRequest
+
+	switch {
+	case *email != "" && *id != 0:
+		fmt.Fprintln(c.stderr, "only one of -email or -id may be given")
+		return errUsage
+	case *email != "":
+		req.Key = &proto.LookupUserRequest_Email{Email: *email}
+	case *id != 0:
+		req.Key = &proto.LookupUserRequest_Id{Id: *id}
+	default:
+		fmt.Fprintln(c.stderr, "one of -email or -id is required")
+		return errUsage
+	}
+
+	user, err := client.LookupUser(ctx, &req)
+	if err != nil {
+		return err
+	}
+
+	return c.printUsers(user)
+}
+
+func (c *cli) list(ctx context.Context, client proto.UserServiceClient, args []string) error {
+	fs := c.commandFlags("list")
+
+	page := fs.Int("page", 1, "Page number")
+	pageSize := fs.Int("page-size", 20, "Page size")
+	sort := fs.String("sort", "id", "Sort field, prefix with - for descending order")
+	attrs := attributesFlag{}
+	fs.Var(attrs, "attr", "Only users with this attribute value, as key=value, may be repeated")
+	var hasAttrs listFlag
+	fs.Var(&hasAttrs, "has-attr", "Only users with this attribute key, may be repeated")
+
+	if err := c.parse(fs, args); err != nil {
+		return err
+	}
+
+	resp, err := client.ListUsers(ctx, &proto.ListUsersRequest{
+		Page:          int32(*page),
+		PageSize:      int32(*pageSize),
+		Sort:          *sort,
+		Attributes:    attrs,
+		HasAttributes: hasAttrs,
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.printList(resp)
+}
+
+func (c *cli) search(ctx context.Context, client proto.UserServiceClient, args []string) error {
+	fs := c.commandFlags("search")
+
+	query := fs.String("query", "", "Words to search for in names and emails")
+	page := fs.Int("page", 1, "Page number")
+	pageSize := fs.Int("page-size", 20, "Page size")
+
+	if err := c.parse(fs, args); err != nil {
+		return err
+	}
+
+	if *query == "" {
+		fmt.Fprintln(c.stderr, "-query is required")
+		return errUsage
+	}
+
+	resp, err := client.SearchUsers(ctx, &proto.SearchUsersRequest{
+		Query:    *query,
+		Page:     int32(*page),
+		PageSize: int32(*pageSize),
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.printMatches(resp)
+}
+
+func (c *cli) stats(ctx context.Context, client proto.UserServiceClient, args []string) error {
+	fs := c.commandFlags("stats")
+
+	from := fs.String("from", "", "Only users created on or after this date, YYYY-MM-DD")
+	to := fs.String("to", "", "Only users created before this date, YYYY-MM-DD")
+	interval := fs.String("interval", "day", "Signup series interval (day|week|month)")
+	topDomains := fs.Int("top-domains", 10, "Number of email domains to report")
+	attrs := attributesFlag{}
+	fs.Var(attrs, "attr", "Only users with this attribute value, as key=value, may be repeated")
+
+	if err := c.parse(fs, args); err != nil {
+		return err
+	}
+
+	req := &proto.GetUserStatsRequest{
+		Attributes: attrs,
+		Interval:   *interval,
+		TopDomains: int32(*topDomains),
+	}
+
+	for _, date := range []struct {
+		flag  string
+		value string
+		dst   **timestamppb.Timestamp
+	}{
+		{"from", *from, &req.CreatedFrom},
+		{"to", *to, &req.CreatedTo},
+	} {
+		if date.value == "" {
+			continue
+		}
+
+		t, err := time.Parse(time.DateOnly, date.value)
+		if err != nil {
+			fmt.Fprintf(c.stderr, "-%s must be a date in YYYY-MM-DD format\n", date.flag)
+			return errUsage
+		}
+
+		*date.dst = timestamppb.New(t)
+	}
+
+	resp, err := client.GetUserStats(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	return c.printStats(resp, *interval)
+}
+
+func (c *cli) update(ctx context.Context, client proto.UserServiceClient, args []string) error {
+	fs := c.commandFlags("update")
+
+	name := fs.String("name", "", "New user name")
+	email := fs.String("email", "", "New user email")
+	age := fs.Int("age", 0, "New user age, used when -birthdate is not given")
+	birthdate := fs.String("birthdate", "", "New user birthdate, YYYY-MM-DD")
+	displayName := fs.String("display-name", "", "New user display name")
+	phone := fs.String("phone", "", "New user phone number")
+	locale := fs.String("locale", "", "New user locale")
+	timeZone := fs.String("time-zone", "", "New user time zone")
+	attrs := attributesFlag{}
+	fs.Var(attrs, "attr", "Attribute to set as key=value, or remove as key=, may be repeated")
+
+	id, err := c.parseID(fs, args)
+	if err != nil {
+		return err
+	}
+
+	req := &proto.UpdateUserRequest{Id: id}
+	fields := 0
+
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name != "id" {
+			fields++
+		}
+
+		switch f.Name {
+		case "name":
+			req.Name = wrapperspb.String(*name)
+		case "email":
+			req.Email = wrapperspb.String(*email)
+		case "age":
+			req.Age = wrapperspb.Int32(int32(*age))
+		case "birthdate":
+			req.Birthdate = wrapperspb.String(*birthdate)
+		case "display-name":
+			req.DisplayName = wrapperspb.String(*displayName)
+		case "phone":
+			req.Phone = wrapperspb.String(*phone)
+		case "locale":
+			req.Locale = wrapperspb.String(*locale)
+		case "time-zone":
+			req.TimeZone = wrapperspb.String(*timeZone)
+		case "attr":
+			req.Attributes = attrs
+		}
+	})
+
+	if fields == 0 {
+		fmt.Fprintln(c.stderr, "at least one field flag, such as -name, -email or -birthdate, is required")
+		return errUsage
+	}
+
+	user, err := client.UpdateUser(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	return c.printUsers(user)
+}
+
+func (c *cli) delete(ctx context.Context, client proto.UserServiceClient, args []string) error {
+	id, err := c.parseID(c.commandFlags("delete"), args)
+	if err != nil {
+		return err
+	}
+
+	user, err := client.DeleteUser(ctx, &proto.DeleteUserRequest{Id: id})
+	if err != nil {
+		return err
+	}
+
+	return c.printUsers(user)
+}
+
+// export always prints JSON: the export is a document to hand over, not a
+// table to read.
+func (c *cli) export(ctx context.Context, client proto.UserServiceClient, args []string) error {
+	id, err := c.parseID(c.commandFlags("export"), args)
+	if err != nil {
+		return err
+	}
+
+	export, err := client.ExportUserData(ctx, &proto.ExportUserDataRequest{Id: id})
+	if err != nil {
+		return err
+	}
+
+	return c.printJSON(export)
+}
+
+func (c *cli) erase(ctx context.Context, client proto.UserServiceClient, args []string) error {
+	id, err := c.parseID(c.commandFlags("erase"), args)
+	if err != nil {
+		return err
+	}
+
+	user, err := client.EraseUser(ctx, &proto.EraseUserRequest{Id: id})
+	if err != nil {
+		return err
+	}
+
+	return c.printUsers(user)
+}
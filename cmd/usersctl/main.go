@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/Vadim-Makhnev/grpc/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+)
+
+const usage = `usage: usersctl [global flags] <command> [command flags]
+
+Commands:
+  create   create a user
+  get      get a user by id
+  lookup   find a user by email or id
+  list     list users
+  search   search users by name or email, tolerating typos
+  stats    show aggregate statistics about users
+  update   partially update a user
+  delete   delete a user
+  export   export all data held about a user as JSON
+  erase    irreversibly anonymize the personal data of a user
+
+Global flags:
+%s
+Exit codes:
+  0        success
+  1        local error (connection, encoding, ...)
+  2        invalid usage
+  10+code  the RPC failed with gRPC status code "code" (e.g. 13 InvalidArgument, 15 NotFound, 20 Aborted)
+`
+
+type cli struct {
+	stdout   io.Writer
+	stderr   io.Writer
+	dialOpts []grpc.DialOption
+
+	addr    string
+	output  string
+	timeout time.Duration
+	tenant  string
+	tls     struct {
+		enabled  bool
+		caFile   string
+		certFile string
+		keyFile  string
+	}
+}
+
+func main() {
+	c := &cli{stdout: os.Stdout, stderr: os.Stderr}
+	os.Exit(c.run(os.Args[1:]))
+}
+
+func (c *cli) run(args []string) int {
+	fs := flag.NewFlagSet("usersctl", flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+
+	fs.StringVar(&c.addr, "addr", "localhost:4000", "UserService address")
+	fs.StringVar(&c.output, "o", "table", "Output format (table|json)")
+	fs.DurationVar(&c.timeout, "timeout", 10*time.Second, "RPC timeout")
+	fs.StringVar(&c.tenant, "tenant", os.Getenv("USERSCTL_TENANT"), "Tenant sent as x-tenant-id (server default if empty)")
+	fs.BoolVar(&c.tls.enabled, "tls", false, "Connect using TLS")
+	fs.StringVar(&c.tls.caFile, "cacert", "", "CA bundle used to verify the server certificate")
+	fs.StringVar(&c.tls.certFile, "cert", "", "Client certificate for mutual TLS")
+	fs.StringVar(&c.tls.keyFile, "key", "", "Client private key for mutual TLS")
+
+	fs.Usage = func() {
+		var defaults bytes.Buffer
+		fs.SetOutput(&defaults)
+		fs.PrintDefaults()
+		fs.SetOutput(c.stderr)
+		fmt.Fprintf(c.stderr, usage, defaults.String())
+	}
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+
+	if c.output != "table" && c.output != "json" {
+		fmt.Fprintf(c.stderr, "invalid output format %q: must be table or json\n", c.output)
+		return exitUsage
+	}
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	commands := map[string]func(context.Context, proto.UserServiceClient, []string) error{
+		"create": c.create,
+		"get":    c.get,
+		"lookup": c.lookup,
+		"search": c.search,
+		"stats":  c.stats,
+		"list":   c.list,
+		"update": c.update,
+		"delete": c.delete,
+		"export": c.export,
+		"erase":  c.erase,
+	}
+
+	command, ok := commands[fs.Arg(0)]
+	if !ok {
+		fmt.Fprintf(c.stderr, "unknown command %q\n", fs.Arg(0))
+		fs.Usage()
+		return exitUsage
+	}
+
+	conn, err := c.dial()
+	if err != nil {
+		fmt.Fprintf(c.stderr, "error: %v\n", err)
+		return exitError
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	if c.tenant != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "x-tenant-id", c.tenant)
+	}
+
+	err = command(ctx, proto.NewUserServiceClient(conn), fs.Args()[1:])
+
+	return c.exitCode(err)
+}
+
+func (c *cli) dial() (*grpc.ClientConn, error) {
+	creds := insecure.NewCredentials()
+
+	if c.tls.enabled {
+		tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+		if c.tls.caFile != "" {
+			pem, err := os.ReadFile(c.tls.caFile)
+			if err != nil {
+				return nil, err
+			}
+
+			roots := x509.NewCertPool()
+			if !roots.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("no certificates found in %s", c.tls.caFile)
+			}
+			tlsConfig.RootCAs = roots
+		}
+
+		if c.tls.certFile != "" || c.tls.keyFile != "" {
+			cert, err := tls.LoadX509KeyPair(c.tls.certFile, c.tls.keyFile)
+			if err != nil {
+				return nil, err
+			}
+			tlsConfig.Certificates = []tls.Certificate{cert}
+		}
+
+		creds = credentials.NewTLS(tlsConfig)
+	}
+
+	opts := append([]grpc.DialOption{grpc.WithTransportCredentials(creds)}, c.dialOpts...)
+
+	return grpc.NewClient(c.addr, opts...)
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"testing"
+
+	"github.com/Vadim-Makhnev/grpc/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type fakeUserService struct {
+	proto.UnimplementedUserServiceServer
+	lastUpdate *proto.UpdateUserRequest
+}
+
+func (s *fakeUserService) CreateUser(ctx context.Context, req *proto.CreateUserRequest) (*proto.UserResponse, error) {
+	st, _ := status.New(codes.InvalidArgument, "invalid request").WithDetails(
+		&errdetails.BadRequest_FieldViolation{Field: "email", Description: "must be a valid email address"},
+		&errdetails.BadRequest_FieldViolation{Field: "age", Description: "must be greater than 0"},
+	)
+	return nil, st.Err()
+}
+
+func (s *fakeUserService) GetUser(ctx context.Context, req *proto.GetUserRequest) (*proto.UserResponse, error) {
+	if req.Id != 1 {
+		return nil, status.Error(codes.NotFound, "user not found")
+	}
+	return &proto.UserResponse{Id: 1, Name: "Andrew", Email: "andrew@google.com", Age: 31, Version: 1}, nil
+}
+
+func (s *fakeUserService) LookupUser(ctx context.Context, req *proto.LookupUserRequest) (*proto.UserResponse, error) {
+	if req.GetEmail() != "andrew@google.com" {
+		return nil, status.Error(codes.NotFound, "user not found")
+	}
+	return &proto.UserResponse{Id: 1, Name: "Andrew", Email: "andrew@google.com", Age: 31, Version: 1}, nil
+}
+
+func (s *fakeUserService) DeleteUser(ctx context.Context, req *proto.DeleteUserRequest) (*proto.UserResponse, error) {
+	st, _ := status.New(codes.Internal, "the server encountered a problem").WithDetails(
+		&errdetails.ErrorInfo{Reason: "INTERNAL", Metadata: map[string]string{"reference": "ABC123"}},
+		&errdetails.RequestInfo{RequestId: "req-1"},
+	)
+	return nil, st.Err()
+}
+
+func (s *fakeUserService) UpdateUser(ctx context.Context, req *proto.UpdateUserRequest) (*proto.UserResponse, error) {
+	s.lastUpdate = req
+	return &proto.UserResponse{Id: req.Id, Name: "Andrew", Email: req.GetEmail().GetValue(), Age: 31, Version: 2}, nil
+}
+
+func (s *fakeUserService) ExportUserData(ctx context.Context, req *proto.ExportUserDataRequest) (*proto.UserDataExport, error) {
+	user, err := s.GetUser(ctx, &proto.GetUserRequest{Id: req.Id})
+	if err != nil {
+		return nil, err
+	}
+	return &proto.UserDataExport{TenantId: "acme", User: user}, nil
+}
+
+func newTestCLI(t *testing.T) (*cli, *fakeUserService, *bytes.Buffer, *bytes.Buffer) {
+	service := &fakeUserService{}
+
+	grpcServer := grpc.NewServer()
+	proto.RegisterUserServiceServer(grpcServer, service)
+
+	lis := bufconn.Listen(1024 * 1024)
+	go grpcServer.Serve(lis)
+	t.Cleanup(grpcServer.Stop)
+
+	var stdout, stderr bytes.Buffer
+
+	c := &cli{
+		stdout: &stdout,
+		stderr: &stderr,
+		dialOpts: []grpc.DialOption{
+			grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+				return lis.DialContext(ctx)
+			}),
+		},
+	}
+
+	return c, service, &stdout, &stderr
+}
+
+func TestCLI_Get_Table(t *testing.T) {
+	c, _, stdout, _ := newTestCLI(t)
+
+	code := c.run([]string{"-addr", "passthrough:///bufnet", "get", "1"})
+
+	assert.Equal(t, exitOK, code)
+	assert.Contains(t, stdout.String(), "ID  NAME    EMAIL              AGE  VERSION")
+	assert.Contains(t, stdout.String(), "1   Andrew  andrew@google.com  31   1")
+}
+
+func TestCLI_Get_JSON(t *testing.T) {
+	c, _, stdout, _ := newTestCLI(t)
+
+	code := c.run([]string{"-addr", "passthrough:///bufnet", "-o", "json", "get", "-id", "1"})
+
+	assert.Equal(t, exitOK, code)
+
+	var user map[string]any
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &user))
+	assert.Equal(t, "andrew@google.com", user["email"])
+}
+
+func TestCLI_Get_NotFound(t *testing.T) {
+	c, _, _, stderr := newTestCLI(t)
+
+	code := c.run([]string{"-addr", "passthrough:///bufnet", "get", "2"})
+
+	assert.Equal(t, exitStatusBase+int(codes.NotFound), code)
+	assert.Contains(t, stderr.String(), "error: NotFound: user not found")
+}
+
+func TestCLI_Export_JSON(t *testing.T) {
+	c, _, stdout, _ := newTestCLI(t)
+
+	code := c.run([]string{"-addr", "passthrough:///bufnet", "export", "1"})
+
+	assert.Equal(t, exitOK, code)
+
+	var export struct {
+		TenantID string         `json:"tenant_id"`
+		User     map[string]any `json:"user"`
+	}
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &export))
+	assert.Equal(t, "acme", export.TenantID)
+	assert.Equal(t, "andrew@google.com", export.User["email"])
+}
+
+func TestCLI_Lookup_Email(t *testing.T) {
+	c, _, stdout, _ := newTestCLI(t)
+
+	code := c.run([]string{"-addr", "passthrough:///bufnet", "lookup", "-email", "andrew@google.com"})
+
+	assert.Equal(t, exitOK, code)
+	assert.Contains(t, stdout.String(), "1   Andrew  andrew@google.com  31   1")
+}
+
+func TestCLI_Create_FieldViolations(t *testing.T) {
+	c, _, _, stderr := newTestCLI(t)
+
+	code := c.run([]string{"-addr", "passthrough:///bufnet", "create", "-name", "Andrew", "-email", "bad"})
+
+	assert.Equal(t, exitStatusBase+int(codes.InvalidArgument), code)
+	assert.Equal(t, "error: InvalidArgument: invalid request\n"+
+		"  age:    must be greater than 0\n"+
+		"  email:  must be a valid email address\n", stderr.String())
+}
+
+func TestCLI_Delete_InternalReference(t *testing.T) {
+	c, _, _, stderr := newTestCLI(t)
+
+	code := c.run([]string{"-addr", "passthrough:///bufnet", "delete", "1"})
+
+	assert.Equal(t, exitStatusBase+int(codes.Internal), code)
+	assert.Equal(t, "error: Internal: the server encountered a problem\n"+
+		"  reference: ABC123\n"+
+		"  request id: req-1\n", stderr.String())
+}
+
+func TestCLI_Update_Partial(t *testing.T) {
+	c, service, _, _ := newTestCLI(t)
+
+	code := c.run([]string{"-addr", "passthrough:///bufnet", "update", "1", "-email", "new@example.com"})
+
+	assert.Equal(t, exitOK, code)
+	require.NotNil(t, service.lastUpdate)
+	assert.Equal(t, int64(1), service.lastUpdate.Id)
+	assert.Equal(t, "new@example.com", service.lastUpdate.Email.GetValue())
+	assert.Nil(t, service.lastUpdate.Name)
+	assert.Nil(t, service.lastUpdate.Age)
+}
+
+func TestCLI_Update_Attributes(t *testing.T) {
+	c, service, _, _ := newTestCLI(t)
+
+	code := c.run([]string{"-addr", "passthrough:///bufnet", "update", "1", "-attr", "department=sales", "-attr", "team="})
+
+	assert.Equal(t, exitOK, code)
+	require.NotNil(t, service.lastUpdate)
+	assert.Equal(t, map[string]string{"department": "sales", "team": ""}, service.lastUpdate.Attributes)
+	assert.Nil(t, service.lastUpdate.Email)
+}
+
+func TestCLI_Usage(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{name: "no command", args: nil},
+		{name: "unknown command", args: []string{"frobnicate"}},
+		{name: "update without fields", args: []string{"update", "1"}},
+		{name: "get without id", args: []string{"get"}},
+		{name: "lookup without key", args: []string{"lookup"}},
+		{name: "lookup with both keys", args: []string{"lookup", "-id", "1", "-email", "andrew@google.com"}},
+		{name: "invalid output", args: []string{"-o", "yaml", "get", "1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _, _, _ := newTestCLI(t)
+			assert.Equal(t, exitUsage, c.run(append([]string{"-addr", "passthrough:///bufnet"}, tt.args...)))
+		})
+	}
+}
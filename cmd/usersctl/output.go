@@ -0,0 +1,175 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Vadim-Makhnev/grpc/proto"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	protobuf "google.golang.org/protobuf/proto"
+)
+
+const exitStatusBase = 10
+
+var jsonMarshaler = protojson.MarshalOptions{Multiline: true, UseProtoNames: true, EmitUnpopulated: true}
+
+func (c *cli) printJSON(msg protobuf.Message) error {
+	js, err := jsonMarshaler.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(c.stdout, string(js))
+	return err
+}
+
+func (c *cli) printUsers(users ...*proto.UserResponse) error {
+	if c.output == "json" && len(users) == 1 {
+		return c.printJSON(users[0])
+	}
+
+	tw := tabwriter.NewWriter(c.stdout, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "ID\tNAME\tEMAIL\tAGE\tVERSION")
+	for _, user := range users {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%d\t%d\n", user.Id, user.Name, user.Email, user.Age, user.Version)
+	}
+
+	return tw.Flush()
+}
+
+func (c *cli) printList(resp *proto.ListUsersResponse) error {
+	if c.output == "json" {
+		return c.printJSON(resp)
+	}
+
+	if err := c.printUsers(resp.Users...); err != nil {
+		return err
+	}
+
+	if md := resp.Metadata; md != nil && md.TotalRecords > 0 {
+		fmt.Fprintf(c.stdout, "\npage %d, page size %d, %d total records\n", md.Page, md.PageSize, md.TotalRecords)
+	}
+
+	return nil
+}
+
+func (c *cli) printMatches(resp *proto.SearchUsersResponse) error {
+	if c.output == "json" {
+		return c.printJSON(resp)
+	}
+
+	tw := tabwriter.NewWriter(c.stdout, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "ID\tNAME\tEMAIL\tSCORE")
+	for _, match := range resp.Matches {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%.3f\n", match.User.Id, match.User.Name, match.User.Email, match.Score)
+	}
+
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if md := resp.Metadata; md != nil && md.TotalRecords > 0 {
+		fmt.Fprintf(c.stdout, "\npage %d, page size %d, %d total records\n", md.Page, md.PageSize, md.TotalRecords)
+	}
+
+	return nil
+}
+
+func (c *cli) printStats(resp *proto.UserStatsResponse, interval string) error {
+	if c.output == "json" {
+		return c.printJSON(resp)
+	}
+
+	fmt.Fprintf(c.stdout, "total users: %d\n", resp.Total)
+
+	tw := tabwriter.NewWriter(c.stdout, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "\nAGE\tUSERS")
+	for _, bucket := range resp.AgeBuckets {
+		fmt.Fprintf(tw, "%s\t%d\n", bucket.Range, bucket.Count)
+	}
+
+	fmt.Fprintln(tw, "\nDOMAIN\tUSERS")
+	for _, domain := range resp.TopDomains {
+		fmt.Fprintf(tw, "%s\t%d\n", domain.Domain, domain.Count)
+	}
+
+	fmt.Fprintf(tw, "\n%s\tSIGNUPS\n", strings.ToUpper(interval))
+	for _, signup := range resp.Signups {
+		fmt.Fprintf(tw, "%s\t%d\n", signup.PeriodStart.AsTime().Format(time.DateOnly), signup.Count)
+	}
+
+	return tw.Flush()
+}
+
+func (c *cli) exitCode(err error) int {
+	if err == nil {
+		return exitOK
+	}
+
+	if errors.Is(err, errUsage) {
+		return exitUsage
+	}
+
+	st, ok := status.FromError(err)
+	if !ok {
+		fmt.Fprintf(c.stderr, "error: %v\n", err)
+		return exitError
+	}
+
+	c.printStatus(st)
+
+	return exitStatusBase + int(st.Code())
+}
+
+func (c *cli) printStatus(st *status.Status) {
+	fmt.Fprintf(c.stderr, "error: %s: %s\n", st.Code(), st.Message())
+
+	var (
+		violations []*errdetails.BadRequest_FieldViolation
+		references []string
+	)
+
+	for _, detail := range st.Details() {
+		switch detail := detail.(type) {
+		case *errdetails.BadRequest:
+			violations = append(violations, detail.FieldViolations...)
+		case *errdetails.BadRequest_FieldViolation:
+			violations = append(violations, detail)
+		case *errdetails.ErrorInfo:
+			if ref := detail.Metadata["reference"]; ref != "" {
+				references = append(references, "reference: "+ref)
+			}
+		case *errdetails.RequestInfo:
+			references = append(references, "request id: "+detail.RequestId)
+		}
+	}
+
+	defer func() {
+		for _, ref := range references {
+			fmt.Fprintf(c.stderr, "  %s\n", ref)
+		}
+	}()
+
+	if len(violations) == 0 {
+		return
+	}
+
+	slices.SortFunc(violations, func(a, b *errdetails.BadRequest_FieldViolation) int {
+		return strings.Compare(a.Field, b.Field)
+	})
+
+	tw := tabwriter.NewWriter(c.stderr, 0, 0, 2, ' ', 0)
+	for _, violation := range violations {
+		fmt.Fprintf(tw, "  %s:\t%s\n", violation.Field, violation.Description)
+	}
+	tw.Flush()
+}
@@ -0,0 +1,128 @@
+package data
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"regexp"
+
+	"github.com/Vadim-Makhnev/grpc/internal/i18n"
+	"github.com/Vadim-Makhnev/grpc/internal/validator"
+)
+
+// Limits on the attributes of a single user.
+const (
+	attributesMaxKeys       = 32
+	attributeKeyMaxLength   = 63
+	attributeValueMaxLength = 256
+)
+
+// AttributeKeyRX matches the keys allowed in Attributes.
+var AttributeKeyRX = regexp.MustCompile(`^[a-z][a-z0-9_.-]*$`)
+
+// Attributes are free-form key/value pairs that clients attach to a user, such
+// as a department or cost center. They are stored in the attributes JSONB
+// column.
+type Attributes map[string]string
+
+// Value encodes a as a JSON object. It returns a string rather than []byte,
+// which lib/pq would send as bytea.
+func (a Attributes) Value() (driver.Value, error) {
+	if a == nil {
+		return "{}", nil
+	}
+
+	js, err := json.Marshal(map[string]string(a))
+	if err != nil {
+		return nil, err
+	}
+
+	return string(js), nil
+}
+
+func (a *Attributes) Scan(src any) error {
+	var js []byte
+
+	switch src := src.(type) {
+	case nil:
+		*a = nil
+		return nil
+	case []byte:
+		js = src
+	case string:
+		js = []byte(src)
+	default:
+		return fmt.Errorf("data: cannot scan %T into Attributes", src)
+	}
+
+	return json.Unmarshal(js, (*map[string]string)(a))
+}
+
+// Merge returns a copy of a with patch applied key by key: an empty value
+// removes the key, any other value sets it. Keys missing from patch are kept.
+func (a Attributes) Merge(patch map[string]string) Attributes {
+	merged := maps.Clone(a)
+	if merged == nil {
+		merged = Attributes{}
+	}
+
+	for key, value := range patch {
+		if value == "" {
+			delete(merged, key)
+			continue
+		}
+		merged[key] = value
+	}
+
+	return merged
+}
+
+// Contains reports whether a has every key of want with the same value.
+func (a Attributes) Contains(want map[string]string) bool {
+	for key, value := range want {
+		if got, ok := a[key]; !ok || got != value {
+			return false
+		}
+	}
+	return true
+}
+
+// HasAll reports whether a has every one of keys, whatever the values.
+func (a Attributes) HasAll(keys []string) bool {
+	for _, key := range keys {
+		if _, ok := a[key]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func attributeKey() validator.Rule[string] {
+	return func(value string) (i18n.Message, bool) {
+		if !validator.Matches(value, AttributeKeyRX) {
+			return i18n.New(i18n.ReasonInvalidKey), false
+		}
+		return i18n.Message{}, true
+	}
+}
+
+// validateAttributes records problems with attrs under key, and problems with
+// a single attribute under "key.<name>".
+func validateAttributes(v *validator.Validator, key string, attrs map[string]string) {
+	validator.Field(v, key, attrs, validator.MaxEntries[map[string]string](attributesMaxKeys))
+
+	scope := v.Scope(key)
+
+	for name, value := range attrs {
+		validator.Field(scope, name, name,
+			attributeKey(),
+			validator.MaxRunes(attributeKeyMaxLength),
+		)
+		validator.Field(scope, name, value,
+			validator.MaxRunes(attributeValueMaxLength),
+			validator.ValidUTF8(),
+			validator.NoControlChars(),
+		)
+	}
+}
@@ -0,0 +1,320 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+)
+
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+type CircuitOpenError struct {
+	RetryAfter time.Duration
+}
+
+func (e *CircuitOpenError) Error() string {
+	return ErrCircuitOpen.Error()
+}
+
+func (e *CircuitOpenError) Is(target error) bool {
+	return target == ErrCircuitOpen
+}
+
+type BreakerState int
+
+const (
+	BreakerClosed BreakerState = iota
+	BreakerOpen
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+type BreakerConfig struct {
+	Window            time.Duration
+	MinRequests       int
+	FailureRatio      float64
+	SlowCallDuration  time.Duration
+	SlowCallRatio     float64
+	OpenTimeout       time.Duration
+	HalfOpenMaxProbes int
+	OnStateChange     func(from, to BreakerState)
+}
+
+type CircuitBreaker struct {
+	cfg BreakerConfig
+	now func() time.Time
+
+	mu          sync.Mutex
+	state       BreakerState
+	windowStart time.Time
+	total       int
+	failures    int
+	slow        int
+	openedAt    time.Time
+	probes      int
+	successes   int
+}
+
+func NewCircuitBreaker(cfg BreakerConfig) *CircuitBreaker {
+	return &CircuitBreaker{
+		cfg: cfg,
+		now: time.Now,
+	}
+}
+
+func (b *CircuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.state
+}
+
+func (b *CircuitBreaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+
+	switch b.state {
+	case BreakerOpen:
+		if elapsed := now.Sub(b.openedAt); elapsed < b.cfg.OpenTimeout {
+			return &CircuitOpenError{RetryAfter: b.cfg.OpenTimeout - elapsed}
+		}
+
+		b.setState(BreakerHalfOpen)
+		b.probes = 0
+		b.successes = 0
+
+		fallthrough
+	case BreakerHalfOpen:
+		if b.probes >= b.cfg.HalfOpenMaxProbes {
+			return &CircuitOpenError{RetryAfter: b.cfg.OpenTimeout}
+		}
+
+		b.probes++
+	default:
+		if now.Sub(b.windowStart) >= b.cfg.Window {
+			b.resetWindow(now)
+		}
+	}
+
+	return nil
+}
+
+func (b *CircuitBreaker) done(elapsed time.Duration, failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	slow := b.cfg.SlowCallDuration > 0 && elapsed >= b.cfg.SlowCallDuration
+
+	switch b.state {
+	case BreakerHalfOpen:
+		if failed || slow {
+			b.trip()
+			return
+		}
+
+		b.successes++
+		if b.successes >= b.cfg.HalfOpenMaxProbes {
+			b.setState(BreakerClosed)
+			b.resetWindow(b.now())
+		}
+	case BreakerClosed:
+		b.total++
+		if failed {
+			b.failures++
+		}
+		if slow {
+			b.slow++
+		}
+
+		if b.total < b.cfg.MinRequests {
+			return
+		}
+
+		failureRatio := float64(b.failures) / float64(b.total)
+		slowRatio := float64(b.slow) / float64(b.total)
+
+		if (b.cfg.FailureRatio > 0 && failureRatio >= b.cfg.FailureRatio) ||
+			(b.cfg.SlowCallRatio > 0 && slowRatio >= b.cfg.SlowCallRatio) {
+			b.trip()
+		}
+	}
+}
+
+func (b *CircuitBreaker) trip() {
+	b.setState(BreakerOpen)
+	b.openedAt = b.now()
+}
+
+func (b *CircuitBreaker) resetWindow(now time.Time) {
+	b.windowStart = now
+	b.total = 0
+	b.failures = 0
+	b.slow = 0
+}
+
+func (b *CircuitBreaker) setState(state BreakerState) {
+	if b.state == state {
+		return
+	}
+
+	from := b.state
+	b.state = state
+
+	if b.cfg.OnStateChange != nil {
+		b.cfg.OnStateChange(from, state)
+	}
+}
+
+func isBreakerFailure(err error) bool {
+	switch {
+	case err == nil,
+		errors.Is(err, ErrRecordNotFound),
+		errors.Is(err, ErrEditConflict),
+		errors.Is(err, ErrInvalidArgument),
+		errors.Is(err, ErrDuplicateEmail),
+		errors.Is(err, ErrMissingTenant),
+		errors.Is(err, context.Canceled):
+		return false
+	default:
+		return true
+	}
+}
+
+// isTxFailure is stricter than isBreakerFailure because a transaction also
+// returns whatever error the caller's function produced.
+func isTxFailure(err error) bool {
+	return IsTransient(err) || errors.Is(err, context.DeadlineExceeded)
+}
+
+type BreakingUserStorage struct {
+	UserStorage
+
+	breaker *CircuitBreaker
+}
+
+func NewBreakingUserStorage(next UserStorage, breaker *CircuitBreaker) *BreakingUserStorage {
+	return &BreakingUserStorage{
+		UserStorage: next,
+		breaker:     breaker,
+	}
+}
+
+func (s *BreakingUserStorage) CreateUser(ctx context.Context, user *User) error {
+	_, err := guard(s.breaker, func() (struct{}, error) {
+		return struct{}{}, s.UserStorage.CreateUser(ctx, user)
+	})
+	return err
+}
+
+func (s *BreakingUserStorage) GetUser(ctx context.Context, id int64) (*User, error) {
+	return guard(s.breaker, func() (*User, error) {
+		return s.UserStorage.GetUser(ctx, id)
+	})
+}
+
+func (s *BreakingUserStorage) GetUserByEmail(ctx context.Context, email string) (*User, error) {
+	return guard(s.breaker, func() (*User, error) {
+		return s.UserStorage.GetUserByEmail(ctx, email)
+	})
+}
+
+func (s *BreakingUserStorage) GetAll(ctx context.Context, filters Filters) ([]*User, MetaData, error) {
+	var metadata MetaData
+
+	users, err := guard(s.breaker, func() ([]*User, error) {
+		users, md, err := s.UserStorage.GetAll(ctx, filters)
+		metadata = md
+		return users, err
+	})
+
+	return users, metadata, err
+}
+
+func (s *BreakingUserStorage) SearchUsers(ctx context.Context, query string, filters Filters) ([]*UserMatch, MetaData, error) {
+	var metadata MetaData
+
+	matches, err := guard(s.breaker, func() ([]*UserMatch, error) {
+		matches, md, err := s.UserStorage.SearchUsers(ctx, query, filters)
+		metadata = md
+		return matches, err
+	})
+
+	return matches, metadata, err
+}
+
+func (s *BreakingUserStorage) GetUserStats(ctx context.Context, filters StatsFilters) (*UserStats, error) {
+	return guard(s.breaker, func() (*UserStats, error) {
+		return s.UserStorage.GetUserStats(ctx, filters)
+	})
+}
+
+func (s *BreakingUserStorage) DeleteUserById(ctx context.Context, id int64) (*User, error) {
+	return guard(s.breaker, func() (*User, error) {
+		return s.UserStorage.DeleteUserById(ctx, id)
+	})
+}
+
+func (s *BreakingUserStorage) EraseUser(ctx context.Context, id int64) (*User, error) {
+	return guard(s.breaker, func() (*User, error) {
+		return s.UserStorage.EraseUser(ctx, id)
+	})
+}
+
+func (s *BreakingUserStorage) UpdateUser(ctx context.Context, user *User) error {
+	_, err := guard(s.breaker, func() (struct{}, error) {
+		return struct{}{}, s.UserStorage.UpdateUser(ctx, user)
+	})
+	return err
+}
+
+func (s *BreakingUserStorage) UpsertUserByEmail(ctx context.Context, user *User) (bool, error) {
+	return guard(s.breaker, func() (bool, error) {
+		return s.UserStorage.UpsertUserByEmail(ctx, user)
+	})
+}
+
+func (s *BreakingUserStorage) GetUserForUpdate(ctx context.Context, id int64) (*User, error) {
+	return guard(s.breaker, func() (*User, error) {
+		return s.UserStorage.GetUserForUpdate(ctx, id)
+	})
+}
+
+func (s *BreakingUserStorage) RunInTx(ctx context.Context, opts *sql.TxOptions, fn func(UserStorage) error) error {
+	_, err := guardWith(s.breaker, isTxFailure, func() (struct{}, error) {
+		return struct{}{}, s.UserStorage.RunInTx(ctx, opts, fn)
+	})
+	return err
+}
+
+func guard[T any](b *CircuitBreaker, fn func() (T, error)) (T, error) {
+	return guardWith(b, isBreakerFailure, fn)
+}
+
+func guardWith[T any](b *CircuitBreaker, isFailure func(error) bool, fn func() (T, error)) (T, error) {
+	if err := b.allow(); err != nil {
+		var zero T
+		return zero, err
+	}
+
+	start := b.now()
+	result, err := fn()
+	b.done(b.now().Sub(start), isFailure(err))
+
+	return result, err
+}
@@ -0,0 +1,170 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	return c.now
+}
+
+func (c *testClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func newTestBreaker(transitions *[]string) (*CircuitBreaker, *testClock) {
+	clock := &testClock{now: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+
+	b := NewCircuitBreaker(BreakerConfig{
+		Window:            10 * time.Second,
+		MinRequests:       4,
+		FailureRatio:      0.5,
+		SlowCallDuration:  time.Second,
+		SlowCallRatio:     0.75,
+		OpenTimeout:       5 * time.Second,
+		HalfOpenMaxProbes: 2,
+		OnStateChange: func(from, to BreakerState) {
+			if transitions != nil {
+				*transitions = append(*transitions, from.String()+"->"+to.String())
+			}
+		},
+	})
+	b.now = clock.Now
+
+	return b, clock
+}
+
+func TestCircuitBreaker_OpensOnFailureRatio(t *testing.T) {
+	var transitions []string
+
+	b, clock := newTestBreaker(&transitions)
+	dbErr := errors.New("connection refused")
+
+	storage := &flakyStorage{errs: []error{dbErr, nil, dbErr, nil}}
+	s := NewBreakingUserStorage(storage, b)
+
+	for range 4 {
+		s.GetUser(context.Background(), 1)
+	}
+
+	require.Equal(t, BreakerOpen, b.State())
+	assert.Equal(t, []string{"closed->open"}, transitions)
+
+	clock.Advance(2 * time.Second)
+
+	_, err := s.GetUser(context.Background(), 1)
+	require.ErrorIs(t, err, ErrCircuitOpen)
+	assert.Equal(t, 4, storage.calls, "open breaker must not reach storage")
+
+	var openErr *CircuitOpenError
+	require.ErrorAs(t, err, &openErr)
+	assert.Equal(t, 3*time.Second, openErr.RetryAfter)
+}
+
+func TestCircuitBreaker_IgnoresDomainErrors(t *testing.T) {
+	b, _ := newTestBreaker(nil)
+
+	storage := &flakyStorage{errs: []error{ErrRecordNotFound, ErrRecordNotFound, ErrEditConflict, context.Canceled}}
+	s := NewBreakingUserStorage(storage, b)
+
+	for range 4 {
+		s.GetUser(context.Background(), 1)
+	}
+
+	assert.Equal(t, BreakerClosed, b.State())
+}
+
+func TestCircuitBreaker_MinRequests(t *testing.T) {
+	b, _ := newTestBreaker(nil)
+
+	for range 3 {
+		require.NoError(t, b.allow())
+		b.done(0, true)
+	}
+
+	assert.Equal(t, BreakerClosed, b.State())
+}
+
+func TestCircuitBreaker_WindowResets(t *testing.T) {
+	b, clock := newTestBreaker(nil)
+
+	for range 3 {
+		require.NoError(t, b.allow())
+		b.done(0, true)
+	}
+
+	clock.Advance(10 * time.Second)
+
+	require.NoError(t, b.allow())
+	b.done(0, true)
+
+	assert.Equal(t, BreakerClosed, b.State())
+}
+
+func TestCircuitBreaker_OpensOnSlowCalls(t *testing.T) {
+	b, _ := newTestBreaker(nil)
+
+	for _, elapsed := range []time.Duration{2 * time.Second, 3 * time.Second, 10 * time.Millisecond, time.Second} {
+		require.NoError(t, b.allow())
+		b.done(elapsed, false)
+	}
+
+	assert.Equal(t, BreakerOpen, b.State())
+}
+
+func TestCircuitBreaker_HalfOpen(t *testing.T) {
+	trip := func(b *CircuitBreaker) {
+		for range 4 {
+			require.NoError(t, b.allow())
+			b.done(0, true)
+		}
+		require.Equal(t, BreakerOpen, b.State())
+	}
+
+	t.Run("closes after successful probes", func(t *testing.T) {
+		var transitions []string
+
+		b, clock := newTestBreaker(&transitions)
+		trip(b)
+
+		clock.Advance(5 * time.Second)
+
+		require.NoError(t, b.allow())
+		require.NoError(t, b.allow())
+		assert.ErrorIs(t, b.allow(), ErrCircuitOpen, "probes beyond the limit must be rejected")
+
+		b.done(0, false)
+		assert.Equal(t, BreakerHalfOpen, b.State())
+		b.done(0, false)
+
+		assert.Equal(t, BreakerClosed, b.State())
+		assert.Equal(t, []string{"closed->open", "open->half-open", "half-open->closed"}, transitions)
+	})
+
+	t.Run("reopens on failed probe", func(t *testing.T) {
+		b, clock := newTestBreaker(nil)
+		trip(b)
+
+		clock.Advance(5 * time.Second)
+
+		require.NoError(t, b.allow())
+		b.done(0, true)
+
+		assert.Equal(t, BreakerOpen, b.State())
+
+		err := b.allow()
+		var openErr *CircuitOpenError
+		require.ErrorAs(t, err, &openErr)
+		assert.Equal(t, 5*time.Second, openErr.RetryAfter)
+	})
+}
@@ -0,0 +1,301 @@
+package data
+
+import (
+	"container/list"
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+type CacheStats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+}
+
+type CachedUserStorage struct {
+	UserStorage
+
+	size int
+	ttl  time.Duration
+	now  func() time.Time
+
+	mu         sync.Mutex
+	entries    map[cacheKey]*list.Element
+	versions   map[userKey]int32
+	lru        *list.List
+	generation uint64
+
+	group singleflight.Group
+
+	hits      atomic.Uint64
+	misses    atomic.Uint64
+	evictions atomic.Uint64
+}
+
+type userKey struct {
+	tenant string
+	id     int64
+}
+
+// cacheKey identifies one version of a user. versions maps every cached user
+// to the only version kept for it, the newest one read, so a read that
+// returns an older version than the cache already holds is never stored.
+//
+// Writes through this storage invalidate the user. Writes that bypass it,
+// such as those of another replica, are only picked up once the entry
+// expires, so the TTL bounds how stale a cached user can get.
+type cacheKey struct {
+	userKey
+	version int32
+}
+
+type cacheEntry struct {
+	key     cacheKey
+	user    User
+	expires time.Time
+}
+
+func NewCachedUserStorage(next UserStorage, size int, ttl time.Duration) *CachedUserStorage {
+	return &CachedUserStorage{
+		UserStorage: next,
+		size:        size,
+		ttl:         ttl,
+		now:         time.Now,
+		entries:     make(map[cacheKey]*list.Element),
+		versions:    make(map[userKey]int32),
+		lru:         list.New(),
+	}
+}
+
+func (c *CachedUserStorage) Stats() CacheStats {
+	return CacheStats{
+		Hits:      c.hits.Load(),
+		Misses:    c.misses.Load(),
+		Evictions: c.evictions.Load(),
+	}
+}
+
+func (c *CachedUserStorage) GetUser(ctx context.Context, id int64) (*User, error) {
+	tenant, ok := TenantFromContext(ctx)
+	if !ok {
+		return c.UserStorage.GetUser(ctx, id)
+	}
+
+	key := userKey{tenant: tenant, id: id}
+
+	if user, ok := c.get(key); ok {
+		c.hits.Add(1)
+		cacheLookups.WithLabelValues("hit").Inc()
+		return user, nil
+	}
+
+	c.misses.Add(1)
+	cacheLookups.WithLabelValues("miss").Inc()
+
+	user, err := sharedLoad(ctx, &c.group, tenant+"/"+strconv.FormatInt(id, 10), func(ctx context.Context) (User, error) {
+		c.mu.Lock()
+		generation := c.generation
+		c.mu.Unlock()
+
+		user, err := c.UserStorage.GetUser(ctx, id)
+		if err != nil {
+			return User{}, err
+		}
+
+		c.put(key, user, generation)
+
+		return *user, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// sharedLoad runs load once for all concurrent callers with the same key, but
+// each caller stops waiting for it as soon as its own ctx is done. The caller
+// that starts the load giving up doesn't cancel it for the others, while its
+// deadline still applies, so the layers below don't retry past it. A caller
+// with a later deadline loads on its own if the shared load runs out of time.
+func sharedLoad[T any](ctx context.Context, group *singleflight.Group, key string, load func(context.Context) (T, error)) (T, error) {
+	ch := group.DoChan(key, func() (any, error) {
+		loadCtx := context.WithoutCancel(ctx)
+		if deadline, ok := ctx.Deadline(); ok {
+			var cancel context.CancelFunc
+			loadCtx, cancel = context.WithDeadline(loadCtx, deadline)
+			defer cancel()
+		}
+
+		return load(loadCtx)
+	})
+
+	select {
+	case res := <-ch:
+		switch {
+		case errors.Is(res.Err, context.DeadlineExceeded) && ctx.Err() == nil:
+			return load(ctx)
+		case res.Err != nil:
+			var zero T
+			return zero, res.Err
+		}
+		return res.Val.(T), nil
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+}
+
+func (c *CachedUserStorage) UpdateUser(ctx context.Context, user *User) error {
+	defer c.invalidate(ctx, user.ID)
+	return c.UserStorage.UpdateUser(ctx, user)
+}
+
+func (c *CachedUserStorage) DeleteUserById(ctx context.Context, id int64) (*User, error) {
+	defer c.invalidate(ctx, id)
+	return c.UserStorage.DeleteUserById(ctx, id)
+}
+
+func (c *CachedUserStorage) EraseUser(ctx context.Context, id int64) (*User, error) {
+	defer c.invalidate(ctx, id)
+	return c.UserStorage.EraseUser(ctx, id)
+}
+
+func (c *CachedUserStorage) UpsertUserByEmail(ctx context.Context, user *User) (bool, error) {
+	inserted, err := c.UserStorage.UpsertUserByEmail(ctx, user)
+	if err == nil && !inserted {
+		c.invalidate(ctx, user.ID)
+	}
+	return inserted, err
+}
+
+// RunInTx bypasses the cache for everything done inside the transaction and
+// drops the users it wrote once the transaction has finished.
+func (c *CachedUserStorage) RunInTx(ctx context.Context, opts *sql.TxOptions, fn func(UserStorage) error) error {
+	var written []userKey
+
+	defer func() {
+		for _, key := range written {
+			c.invalidateKey(key)
+		}
+	}()
+
+	return c.UserStorage.RunInTx(ctx, opts, func(tx UserStorage) error {
+		return fn(&txWriteTracker{UserStorage: tx, written: &written})
+	})
+}
+
+func (c *CachedUserStorage) get(key userKey) (*User, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	version, ok := c.versions[key]
+	if !ok {
+		return nil, false
+	}
+
+	elem := c.entries[cacheKey{userKey: key, version: version}]
+
+	entry := elem.Value.(*cacheEntry)
+	if c.now().After(entry.expires) {
+		c.remove(elem)
+		return nil, false
+	}
+
+	c.lru.MoveToFront(elem)
+
+	user := entry.user
+	return &user, true
+}
+
+func (c *CachedUserStorage) put(key userKey, user *User, generation uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if generation != c.generation {
+		return
+	}
+
+	if version, ok := c.versions[key]; ok {
+		if version > user.Version {
+			return
+		}
+
+		c.remove(c.entries[cacheKey{userKey: key, version: version}])
+	}
+
+	entryKey := cacheKey{userKey: key, version: user.Version}
+	c.entries[entryKey] = c.lru.PushFront(&cacheEntry{key: entryKey, user: *user, expires: c.now().Add(c.ttl)})
+	c.versions[key] = user.Version
+
+	for c.lru.Len() > c.size {
+		c.remove(c.lru.Back())
+		c.evictions.Add(1)
+	}
+}
+
+func (c *CachedUserStorage) remove(elem *list.Element) {
+	key := elem.Value.(*cacheEntry).key
+
+	c.lru.Remove(elem)
+	delete(c.entries, key)
+	delete(c.versions, key.userKey)
+}
+
+func (c *CachedUserStorage) invalidate(ctx context.Context, id int64) {
+	tenant, _ := TenantFromContext(ctx)
+	c.invalidateKey(userKey{tenant: tenant, id: id})
+}
+
+func (c *CachedUserStorage) invalidateKey(key userKey) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+
+	if version, ok := c.versions[key]; ok {
+		c.remove(c.entries[cacheKey{userKey: key, version: version}])
+	}
+}
+
+type txWriteTracker struct {
+	UserStorage
+
+	written *[]userKey
+}
+
+func (t *txWriteTracker) record(ctx context.Context, id int64) {
+	tenant, _ := TenantFromContext(ctx)
+	*t.written = append(*t.written, userKey{tenant: tenant, id: id})
+}
+
+func (t *txWriteTracker) UpdateUser(ctx context.Context, user *User) error {
+	t.record(ctx, user.ID)
+	return t.UserStorage.UpdateUser(ctx, user)
+}
+
+func (t *txWriteTracker) DeleteUserById(ctx context.Context, id int64) (*User, error) {
+	t.record(ctx, id)
+	return t.UserStorage.DeleteUserById(ctx, id)
+}
+
+func (t *txWriteTracker) UpsertUserByEmail(ctx context.Context, user *User) (bool, error) {
+	inserted, err := t.UserStorage.UpsertUserByEmail(ctx, user)
+	if err == nil && !inserted {
+		t.record(ctx, user.ID)
+	}
+	return inserted, err
+}
+
+func (t *txWriteTracker) RunInTx(ctx context.Context, opts *sql.TxOptions, fn func(UserStorage) error) error {
+	return t.UserStorage.RunInTx(ctx, opts, func(tx UserStorage) error {
+		return fn(&txWriteTracker{UserStorage: tx, written: t.written})
+	})
+}
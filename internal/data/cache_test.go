@@ -0,0 +1,340 @@
+package data
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type countingStorage struct {
+	UserStorage
+
+	mu      sync.Mutex
+	users   map[int64]User
+	calls   atomic.Int32
+	release chan struct{}
+}
+
+func newCountingStorage() *countingStorage {
+	return &countingStorage{
+		users: map[int64]User{
+			1: {ID: 1, Name: "Andrew", Email: "andrew@google.com", Age: 31, Version: 1},
+			2: {ID: 2, Name: "John", Email: "john@gmail.com", Age: 21, Version: 1},
+			3: {ID: 3, Name: "Jane", Email: "jane@gmail.com", Age: 25, Version: 1},
+		},
+	}
+}
+
+func (s *countingStorage) GetUser(ctx context.Context, id int64) (*User, error) {
+	s.calls.Add(1)
+
+	if s.release != nil {
+		<-s.release
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[id]
+	if !ok {
+		return nil, ErrRecordNotFound
+	}
+
+	return &user, nil
+}
+
+func (s *countingStorage) UpdateUser(ctx context.Context, user *User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user.Version++
+	s.users[user.ID] = *user
+
+	return nil
+}
+
+func (s *countingStorage) DeleteUserById(ctx context.Context, id int64) (*User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user := s.users[id]
+	delete(s.users, id)
+
+	return &user, nil
+}
+
+func TestCachedUserStorage_ReadThrough(t *testing.T) {
+	backend := newCountingStorage()
+	cache := NewCachedUserStorage(backend, 10, time.Minute)
+	ctx := testTenantContext()
+
+	user, err := cache.GetUser(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "Andrew", user.Name)
+
+	user.Name = "Mutated"
+
+	user, err = cache.GetUser(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "Andrew", user.Name, "cached users must be copies")
+
+	assert.Equal(t, int32(1), backend.calls.Load())
+	assert.Equal(t, CacheStats{Hits: 1, Misses: 1}, cache.Stats())
+
+	_, err = cache.GetUser(ctx, 42)
+	assert.ErrorIs(t, err, ErrRecordNotFound)
+}
+
+func TestCachedUserStorage_Invalidation(t *testing.T) {
+	backend := newCountingStorage()
+	cache := NewCachedUserStorage(backend, 10, time.Minute)
+	ctx := testTenantContext()
+
+	user, err := cache.GetUser(ctx, 1)
+	require.NoError(t, err)
+
+	user.Name = "Andrew Smith"
+	require.NoError(t, cache.UpdateUser(ctx, user))
+
+	user, err = cache.GetUser(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "Andrew Smith", user.Name)
+	assert.Equal(t, int32(2), user.Version)
+
+	_, err = cache.DeleteUserById(ctx, 1)
+	require.NoError(t, err)
+
+	_, err = cache.GetUser(ctx, 1)
+	assert.ErrorIs(t, err, ErrRecordNotFound)
+	assert.Equal(t, int32(3), backend.calls.Load())
+}
+
+func TestCachedUserStorage_TTL(t *testing.T) {
+	backend := newCountingStorage()
+	cache := NewCachedUserStorage(backend, 10, time.Minute)
+	ctx := testTenantContext()
+
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	_, err := cache.GetUser(ctx, 1)
+	require.NoError(t, err)
+
+	now = now.Add(30 * time.Second)
+	_, err = cache.GetUser(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), backend.calls.Load())
+
+	now = now.Add(time.Minute)
+	_, err = cache.GetUser(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), backend.calls.Load())
+}
+
+func TestCachedUserStorage_LRUEviction(t *testing.T) {
+	backend := newCountingStorage()
+	cache := NewCachedUserStorage(backend, 2, time.Minute)
+	ctx := testTenantContext()
+
+	for _, id := range []int64{1, 2, 1, 3} {
+		_, err := cache.GetUser(ctx, id)
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, uint64(1), cache.Stats().Evictions)
+
+	_, err := cache.GetUser(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, int32(3), backend.calls.Load(), "user 1 was recently used and must stay cached")
+
+	_, err = cache.GetUser(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, int32(4), backend.calls.Load(), "user 2 was least recently used and must be evicted")
+}
+
+func TestCachedUserStorage_Stampede(t *testing.T) {
+	backend := newCountingStorage()
+	backend.release = make(chan struct{})
+	cache := NewCachedUserStorage(backend, 10, time.Minute)
+
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			user, err := cache.GetUser(testTenantContext(), 1)
+			assert.NoError(t, err)
+			assert.Equal(t, int64(1), user.ID)
+		}()
+	}
+
+	require.Eventually(t, func() bool { return backend.calls.Load() == 1 }, time.Second, time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+	close(backend.release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), backend.calls.Load())
+}
+
+func TestCachedUserStorage_StaleFillDiscarded(t *testing.T) {
+	backend := newCountingStorage()
+	backend.release = make(chan struct{})
+	cache := NewCachedUserStorage(backend, 10, time.Minute)
+	ctx := testTenantContext()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, err := cache.GetUser(ctx, 1)
+		assert.NoError(t, err)
+	}()
+
+	require.Eventually(t, func() bool { return backend.calls.Load() == 1 }, time.Second, time.Millisecond)
+
+	cache.invalidate(ctx, 1)
+	close(backend.release)
+	<-done
+
+	backend.release = nil
+	_, err := cache.GetUser(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), backend.calls.Load(), "a fill that raced with an invalidation must not be cached")
+}
+
+func TestCachedUserStorage_Transaction(t *testing.T) {
+	cache := NewCachedUserStorage(seedMemoryStorage(t), 10, time.Minute)
+	models := Models{Users: cache}
+	ctx := testTenantContext()
+
+	_, err := cache.GetUser(ctx, 1)
+	require.NoError(t, err)
+
+	err = models.WithTx(ctx, func(tx Models) error {
+		user, err := tx.Users.GetUserForUpdate(ctx, 1)
+		if err != nil {
+			return err
+		}
+
+		user.Name = "Andrew Smith"
+		return tx.Users.UpdateUser(ctx, user)
+	})
+	require.NoError(t, err)
+
+	user, err := cache.GetUser(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "Andrew Smith", user.Name, "users written in a transaction must be invalidated")
+}
+
+func TestCachedUserStorage_TenantIsolation(t *testing.T) {
+	cache := NewCachedUserStorage(seedMemoryStorage(t), 10, time.Minute)
+
+	_, err := cache.GetUser(testTenantContext(), 1)
+	require.NoError(t, err)
+
+	_, err = cache.GetUser(ContextWithTenant(context.Background(), "globex"), 1)
+	assert.ErrorIs(t, err, ErrRecordNotFound, "cached users must not leak across tenants")
+}
+
+func TestCachedUserStorage_KeyedByVersion(t *testing.T) {
+	cache := NewCachedUserStorage(newCountingStorage(), 10, time.Minute)
+	key := userKey{tenant: "acme", id: 1}
+
+	cache.put(key, &User{ID: 1, Name: "Andrew Smith", Version: 2}, 0)
+	cache.put(key, &User{ID: 1, Name: "Andrew", Version: 1}, 0)
+
+	user, ok := cache.get(key)
+	require.True(t, ok)
+	assert.Equal(t, int32(2), user.Version, "an older version must never replace a newer one")
+
+	cache.put(key, &User{ID: 1, Name: "Andrew Jones", Version: 3}, 0)
+
+	user, ok = cache.get(key)
+	require.True(t, ok)
+	assert.Equal(t, "Andrew Jones", user.Name)
+	assert.Len(t, cache.entries, 1, "only the newest version is kept")
+}
+
+func TestCachedUserStorage_OtherReplicaWrites(t *testing.T) {
+	backend := newCountingStorage()
+	replica := NewCachedUserStorage(backend, 10, time.Minute)
+	other := NewCachedUserStorage(backend, 10, time.Minute)
+	ctx := testTenantContext()
+
+	now := time.Now()
+	replica.now = func() time.Time { return now }
+
+	_, err := replica.GetUser(ctx, 1)
+	require.NoError(t, err)
+
+	user, err := other.GetUser(ctx, 1)
+	require.NoError(t, err)
+	user.Name = "Andrew Smith"
+	require.NoError(t, other.UpdateUser(ctx, user))
+
+	user, err = replica.GetUser(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), user.Version, "writes through another replica are not seen before the TTL")
+
+	now = now.Add(time.Minute + time.Second)
+
+	user, err = replica.GetUser(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), user.Version)
+	assert.Equal(t, "Andrew Smith", user.Name)
+}
+
+type deadlineStorage struct {
+	*countingStorage
+	deadline time.Time
+}
+
+func (s *deadlineStorage) GetUser(ctx context.Context, id int64) (*User, error) {
+	s.deadline, _ = ctx.Deadline()
+	return s.countingStorage.GetUser(ctx, id)
+}
+
+func TestCachedUserStorage_KeepsDeadline(t *testing.T) {
+	backend := &deadlineStorage{countingStorage: newCountingStorage()}
+	cache := NewCachedUserStorage(backend, 10, time.Minute)
+
+	ctx, cancel := context.WithTimeout(testTenantContext(), time.Minute)
+	defer cancel()
+
+	_, err := cache.GetUser(ctx, 1)
+	require.NoError(t, err)
+
+	deadline, _ := ctx.Deadline()
+	assert.Equal(t, deadline, backend.deadline, "the layers below must stop retrying at the deadline of the request")
+}
+
+func TestCachedUserStorage_WaiterGivesUp(t *testing.T) {
+	backend := newCountingStorage()
+	backend.release = make(chan struct{})
+	cache := NewCachedUserStorage(backend, 10, time.Minute)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		user, err := cache.GetUser(testTenantContext(), 1)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), user.ID)
+	}()
+
+	require.Eventually(t, func() bool { return backend.calls.Load() == 1 }, time.Second, time.Millisecond)
+
+	ctx, cancel := context.WithCancel(testTenantContext())
+	cancel()
+
+	_, err := cache.GetUser(ctx, 1)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	close(backend.release)
+	<-done
+
+	assert.Equal(t, int32(1), backend.calls.Load())
+}
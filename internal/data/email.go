@@ -0,0 +1,36 @@
+package data
+
+import (
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// NormalizeEmail trims surrounding whitespace, lowercases the domain and
+// converts a unicode domain to its ASCII (punycode) form. The local part is
+// left untouched because its case is significant to some mail servers;
+// uniqueness is enforced case-insensitively instead. Addresses that can't be
+// split or whose domain isn't valid IDNA are returned trimmed so validation
+// reports them.
+func NormalizeEmail(email string) string {
+	email = strings.TrimSpace(email)
+
+	at := strings.LastIndex(email, "@")
+	if at <= 0 || at == len(email)-1 {
+		return email
+	}
+
+	local, domain := email[:at], strings.ToLower(email[at+1:])
+
+	if ascii, err := idna.Lookup.ToASCII(domain); err == nil {
+		domain = ascii
+	}
+
+	return local + "@" + domain
+}
+
+// emailKey is the form used to compare emails for uniqueness. It matches the
+// lower(email) expression of the users_tenant_id_lower_email_key index.
+func emailKey(email string) string {
+	return strings.ToLower(email)
+}
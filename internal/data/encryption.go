@@ -0,0 +1,322 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var ErrNoEncryptionKeys = errors.New("data: user is encrypted but no encryption keys are configured")
+
+// storedColumns are the columns that hold the name and email of a user, in
+// the order of storedFields.args.
+const storedColumns = `name, email, name_encrypted, email_encrypted, email_hash, email_domain, encryption_key_version`
+
+// storedFields are the values of storedColumns. Without a Cipher only the
+// plaintext name and email are set; with one they are NULL and the encrypted
+// values, the blind index and the domain of the email and the key version are
+// set instead.
+type storedFields struct {
+	name           sql.NullString
+	email          sql.NullString
+	nameEncrypted  []byte
+	emailEncrypted []byte
+	emailHash      []byte
+	emailDomain    sql.NullString
+	keyVersion     sql.NullInt64
+}
+
+func (f storedFields) args() []any {
+	return []any{f.name, f.email, nullBytes(f.nameEncrypted), nullBytes(f.emailEncrypted), nullBytes(f.emailHash), f.emailDomain, f.keyVersion}
+}
+
+// nullBytes maps nil to NULL; lib/pq would send an empty bytea otherwise.
+func nullBytes(b []byte) any {
+	if b == nil {
+		return nil
+	}
+	return b
+}
+
+// fieldAAD binds an encrypted value to its tenant and column, so it can't be
+// moved to another user of another tenant or into the other column.
+func fieldAAD(tenant, column string) string {
+	return tenant + "/" + column
+}
+
+func (u UserModel) seal(ctx context.Context, tenant, name, email string) (storedFields, error) {
+	if u.Cipher == nil {
+		return storedFields{
+			name:  sql.NullString{String: name, Valid: true},
+			email: sql.NullString{String: email, Valid: true},
+		}, nil
+	}
+
+	nameEncrypted, err := u.Cipher.Encrypt(ctx, name, fieldAAD(tenant, "name"))
+	if err != nil {
+		return storedFields{}, err
+	}
+
+	emailEncrypted, err := u.Cipher.Encrypt(ctx, email, fieldAAD(tenant, "email"))
+	if err != nil {
+		return storedFields{}, err
+	}
+
+	emailHash, err := u.emailHash(ctx, email)
+	if err != nil {
+		return storedFields{}, err
+	}
+
+	// The domain stays readable so GetUserStats can still group by it.
+	domain, ok := emailDomain(email)
+
+	return storedFields{
+		nameEncrypted:  nameEncrypted,
+		emailEncrypted: emailEncrypted,
+		emailHash:      emailHash,
+		emailDomain:    sql.NullString{String: domain, Valid: ok},
+		keyVersion:     sql.NullInt64{Int64: int64(u.Cipher.CurrentKeyVersion()), Valid: true},
+	}, nil
+}
+
+// emailHash returns the blind index of email, or nil without a Cipher. Like
+// the unique index on plaintext emails, it ignores case.
+func (u UserModel) emailHash(ctx context.Context, email string) ([]byte, error) {
+	if u.Cipher == nil {
+		return nil, nil
+	}
+	return u.Cipher.BlindIndex(ctx, "email", strings.ToLower(email))
+}
+
+// claimEmail sets the email hash of a user that still has email in plaintext
+// and no hash yet, so the unique index on email_hash sees that user before
+// a user with the same email is written with a hash. Without it, a plaintext
+// and an encrypted user could share an email until BackfillEmailHashes or
+// ReencryptUsers reach the plaintext one. Setting a hash changes nothing
+// else, so it doesn't have to share a transaction with the write.
+func (u UserModel) claimEmail(ctx context.Context, db DBTX, tenant, email string, hash []byte) error {
+	if hash == nil {
+		return nil
+	}
+
+	query := `
+		UPDATE users
+		SET email_hash = $3
+		WHERE tenant_id = $1 AND email_hash IS NULL AND lower(email) = lower($2)`
+
+	_, err := db.ExecContext(ctx, query, tenant, email, hash)
+	return err
+}
+
+// open returns the plaintext of a column scanned as plain and encrypted.
+func (u UserModel) open(ctx context.Context, tenant, column string, plain sql.NullString, encrypted []byte) (string, error) {
+	if encrypted == nil {
+		return plain.String, nil
+	}
+
+	if u.Cipher == nil {
+		return "", ErrNoEncryptionKeys
+	}
+
+	return u.Cipher.Decrypt(ctx, encrypted, fieldAAD(tenant, column))
+}
+
+// ReencryptUsers rewrites the names and emails of up to batchSize users of
+// the tenant in ctx that are stored in plaintext or under an older key, using
+// the current key. It returns the number of users rewritten, zero once the
+// tenant is done. Rows locked by other transactions are skipped, and the
+// version is left alone, so it can run next to regular traffic.
+func (u UserModel) ReencryptUsers(ctx context.Context, batchSize int) (int, error) {
+	if u.Cipher == nil {
+		return 0, ErrNoEncryptionKeys
+	}
+
+	ctx, span := startQuery(ctx, "reencrypt_users")
+	defer span.end()
+
+	selectQuery := `
+		SELECT ` + userColumns + `
+		FROM users
+		WHERE tenant_id = $1 AND encryption_key_version IS DISTINCT FROM $2
+		ORDER BY id
+		LIMIT $3
+		FOR UPDATE SKIP LOCKED`
+
+	updateQuery := `
+		UPDATE users
+		SET (` + storedColumns + `) = ($3, $4, $5, $6, $7, $8, $9)
+		WHERE id = $1 AND tenant_id = $2`
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	n := 0
+
+	err := u.RunInTx(ctx, nil, func(s UserStorage) error {
+		tx := s.(UserModel)
+
+		tenant, _ := TenantFromContext(ctx)
+
+		var users []*User
+		err := queryRows(ctx, tx.DB, selectQuery, []any{tenant, u.Cipher.CurrentKeyVersion(), batchSize}, func(rows *sql.Rows) error {
+			user, err := tx.scanUser(ctx, rows)
+			if err != nil {
+				return err
+			}
+			users = append(users, user)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, user := range users {
+			fields, err := tx.seal(ctx, tenant, user.Name, user.Email)
+			if err != nil {
+				return err
+			}
+
+			if _, err := tx.DB.ExecContext(ctx, updateQuery, append([]any{user.ID, tenant}, fields.args()...)...); err != nil {
+				return err
+			}
+		}
+
+		n = len(users)
+
+		return nil
+	})
+	if err != nil {
+		span.fail(err)
+		return 0, err
+	}
+
+	span.rows(n)
+
+	return n, nil
+}
+
+// BackfillEmailHashes sets the email hash of up to batchSize users of the
+// tenant in ctx that are stored in plaintext and don't have one yet. It
+// returns the number of users updated, zero once the tenant is done. A
+// plaintext user whose email is already taken by an encrypted user fails
+// with ErrDuplicateEmail; the two have to be merged by hand.
+func (u UserModel) BackfillEmailHashes(ctx context.Context, batchSize int) (int, error) {
+	if u.Cipher == nil {
+		return 0, ErrNoEncryptionKeys
+	}
+
+	ctx, span := startQuery(ctx, "backfill_email_hashes")
+	defer span.end()
+
+	selectQuery := `
+		SELECT id, email
+		FROM users
+		WHERE tenant_id = $1 AND email_hash IS NULL AND email IS NOT NULL
+		ORDER BY id
+		LIMIT $2
+		FOR UPDATE SKIP LOCKED`
+
+	updateQuery := `
+		UPDATE users
+		SET email_hash = $3
+		WHERE id = $1 AND tenant_id = $2`
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	n := 0
+
+	err := u.RunInTx(ctx, nil, func(s UserStorage) error {
+		tx := s.(UserModel)
+
+		tenant, _ := TenantFromContext(ctx)
+
+		type legacyUser struct {
+			id    int64
+			email string
+		}
+
+		var users []legacyUser
+		err := queryRows(ctx, tx.DB, selectQuery, []any{tenant, batchSize}, func(rows *sql.Rows) error {
+			var user legacyUser
+			if err := rows.Scan(&user.id, &user.email); err != nil {
+				return err
+			}
+			users = append(users, user)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, user := range users {
+			hash, err := tx.emailHash(ctx, user.email)
+			if err != nil {
+				return err
+			}
+
+			if _, err := tx.DB.ExecContext(ctx, updateQuery, user.id, tenant, hash); err != nil {
+				if isDuplicateEmail(err) {
+					return fmt.Errorf("user %d: %w", user.id, ErrDuplicateEmail)
+				}
+				return err
+			}
+		}
+
+		n = len(users)
+
+		return nil
+	})
+	if err != nil {
+		span.fail(err)
+		return 0, err
+	}
+
+	span.rows(n)
+
+	return n, nil
+}
+
+// ReencryptionTenants returns the tenants with users that ReencryptUsers
+// still has to rewrite, which include every user BackfillEmailHashes has to
+// update. It looks across tenants, so with row-level security it
+// only sees the tenant set on the connection, if any.
+func (u UserModel) ReencryptionTenants(ctx context.Context) ([]string, error) {
+	if u.Cipher == nil {
+		return nil, ErrNoEncryptionKeys
+	}
+
+	ctx, span := startQuery(ctx, "reencryption_tenants")
+	defer span.end()
+
+	query := `
+		SELECT DISTINCT tenant_id
+		FROM users
+		WHERE encryption_key_version IS DISTINCT FROM $1
+		ORDER BY tenant_id`
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	var tenants []string
+
+	err := queryRows(ctx, u.DB, query, []any{u.Cipher.CurrentKeyVersion()}, func(rows *sql.Rows) error {
+		var tenant string
+		if err := rows.Scan(&tenant); err != nil {
+			return err
+		}
+		tenants = append(tenants, tenant)
+		return nil
+	})
+	if err != nil {
+		span.fail(err)
+		return nil, err
+	}
+
+	span.rows(len(tenants))
+
+	return tenants, nil
+}
@@ -0,0 +1,75 @@
+package data
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/Vadim-Makhnev/grpc/internal/encryption"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestCipher(t *testing.T) *encryption.Cipher {
+	t.Helper()
+
+	keys, err := encryption.NewLocalKeyProvider(1, map[uint32][]byte{1: bytes.Repeat([]byte{1}, encryption.KeySize)}, bytes.Repeat([]byte{2}, encryption.IndexKeyMinSize))
+	require.NoError(t, err)
+
+	return encryption.New(keys)
+}
+
+func TestUserModel_sealOpen(t *testing.T) {
+	ctx := context.Background()
+
+	plain := UserModel{}
+	encrypted := UserModel{Cipher: newTestCipher(t)}
+
+	fields, err := plain.seal(ctx, "acme", "Andrew", "andrew@google.com")
+	require.NoError(t, err)
+	assert.Equal(t, sql.NullString{String: "Andrew", Valid: true}, fields.name)
+	assert.Nil(t, fields.nameEncrypted)
+	assert.Nil(t, fields.emailHash)
+	assert.False(t, fields.emailDomain.Valid)
+
+	fields, err = encrypted.seal(ctx, "acme", "Andrew", "andrew@google.com")
+	require.NoError(t, err)
+	assert.False(t, fields.name.Valid)
+	assert.False(t, fields.email.Valid)
+	assert.Equal(t, sql.NullInt64{Int64: 1, Valid: true}, fields.keyVersion)
+	assert.Equal(t, sql.NullString{String: "google.com", Valid: true}, fields.emailDomain, "the domain stays groupable")
+
+	name, err := encrypted.open(ctx, "acme", "name", fields.name, fields.nameEncrypted)
+	require.NoError(t, err)
+	assert.Equal(t, "Andrew", name)
+
+	_, err = encrypted.open(ctx, "other", "name", fields.name, fields.nameEncrypted)
+	assert.ErrorIs(t, err, encryption.ErrMalformed, "values are bound to their tenant")
+
+	_, err = encrypted.open(ctx, "acme", "email", fields.name, fields.nameEncrypted)
+	assert.ErrorIs(t, err, encryption.ErrMalformed, "and to their column")
+
+	_, err = plain.open(ctx, "acme", "name", fields.name, fields.nameEncrypted)
+	assert.ErrorIs(t, err, ErrNoEncryptionKeys)
+
+	email, err := encrypted.open(ctx, "acme", "email", sql.NullString{String: "legacy@google.com", Valid: true}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "legacy@google.com", email, "plaintext rows still read")
+
+	hash, err := encrypted.emailHash(ctx, "ANDREW@google.com")
+	require.NoError(t, err)
+	assert.Equal(t, fields.emailHash, hash, "the email index ignores case")
+}
+
+func TestUserModel_CanSortBy(t *testing.T) {
+	sorts := []string{"id", "-id", "name", "email", "age"}
+
+	assert.Equal(t, sorts, SortSafelist(UserModel{}, sorts...))
+
+	encrypted := UserModel{Cipher: newTestCipher(t)}
+	assert.Equal(t, []string{"id", "-id", "age"}, SortSafelist(encrypted, sorts...))
+
+	_, _, err := encrypted.GetAll(testTenantContext(), Filters{Page: 1, PageSize: 20, Sort: "email", SortSafelist: sorts})
+	assert.ErrorIs(t, err, ErrInvalidArgument)
+}
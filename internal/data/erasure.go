@@ -0,0 +1,39 @@
+package data
+
+import (
+	"fmt"
+	"time"
+)
+
+// ErasedName replaces the name of an erased user.
+const ErasedName = "Erased user"
+
+// ErasedEmail returns the email that replaces the one of the erased user with
+// the given id. It is unique per id, so erased users never collide on the
+// email index, and the .invalid domain never resolves.
+func ErasedEmail(id int64) string {
+	return fmt.Sprintf("erased-%d@erased.invalid", id)
+}
+
+// Erased reports whether the personal data of the user has been erased.
+func (u *User) Erased() bool {
+	return !u.ErasedAt.IsZero()
+}
+
+// anonymize replaces the personal data of u the way EraseUser does in the
+// database. The birthdate is only coarsened to the start of its year, so age
+// statistics stay roughly right.
+func (u *User) anonymize(now time.Time) {
+	u.Name = ErasedName
+	u.DisplayName = ""
+	u.Email = ErasedEmail(u.ID)
+	u.Phone = ""
+	u.Locale = ""
+	u.TimeZone = ""
+	u.Attributes = Attributes{}
+	if !u.Birthdate.IsZero() {
+		u.Birthdate = time.Date(u.Birthdate.Year(), time.January, 1, 0, 0, 0, 0, time.UTC)
+		u.Age = AgeOn(u.Birthdate, DateOf(now))
+	}
+	u.ErasedAt = now
+}
@@ -4,6 +4,7 @@ import (
 	"math"
 	"strings"
 
+	"github.com/Vadim-Makhnev/grpc/internal/i18n"
 	"github.com/Vadim-Makhnev/grpc/internal/validator"
 )
 
@@ -12,6 +13,11 @@ type Filters struct {
 	PageSize     int
 	Sort         string
 	SortSafelist []string
+
+	// Attributes keeps users whose attributes include every one of these
+	// key/value pairs, HasAttributes those that have every one of these keys.
+	Attributes    map[string]string
+	HasAttributes []string
 }
 
 type MetaData struct {
@@ -36,6 +42,18 @@ func calculateMetadata(totalRecords, page, pageSize int) MetaData {
 	}
 }
 
+// SortSafelist returns the sorts whose column s can order users by, for
+// Filters.SortSafelist.
+func SortSafelist(s UserStorage, sorts ...string) []string {
+	safelist := []string{}
+	for _, sort := range sorts {
+		if s.CanSortBy(strings.TrimPrefix(sort, "-")) {
+			safelist = append(safelist, sort)
+		}
+	}
+	return safelist
+}
+
 func (f Filters) sortColumn() string {
 	for _, safeValue := range f.SortSafelist {
 		if f.Sort == safeValue {
@@ -54,6 +72,14 @@ func (f Filters) sortDirection() string {
 	return "ASC"
 }
 
+// hasAttributes never returns nil, which lib/pq would send as a NULL array.
+func (f Filters) hasAttributes() []string {
+	if f.HasAttributes == nil {
+		return []string{}
+	}
+	return f.HasAttributes
+}
+
 func (f Filters) limit() int {
 	return f.PageSize
 }
@@ -63,9 +89,25 @@ func (f Filters) offset() int {
 }
 
 func ValidateFilters(v *validator.Validator, f Filters) {
-	v.Check(f.Page > 0, "page", "must be greater than zero")
-	v.Check(f.Page <= 10_000_000, "page", "must be a maximum of 10 million")
-	v.Check(f.PageSize > 0, "page_size", "must be greater than zero")
-	v.Check(f.PageSize <= 100, "page_size", "must be a maximum of 100")
-	v.Check(validator.In(f.Sort, f.SortSafelist...), "sort", "invalid sort value")
+	validatePage(v, f)
+	validator.Field(v, "sort", f.Sort, validator.OneOf(f.SortSafelist...).Message(i18n.New(i18n.ReasonInvalidSort)))
+
+	validateAttributes(v, "attributes", f.Attributes)
+	for i, key := range f.HasAttributes {
+		validator.Field(v, validator.Index("has_attributes", i), key,
+			attributeKey(),
+			validator.MaxRunes(attributeKeyMaxLength),
+		)
+	}
+}
+
+func validatePage(v *validator.Validator, f Filters) {
+	validator.Field(v, "page", f.Page,
+		validator.GreaterThan(0).Message(i18n.New(i18n.ReasonNotAboveZero)),
+		validator.Max(10_000_000).Message(i18n.New(i18n.ReasonPageTooLarge)),
+	)
+	validator.Field(v, "page_size", f.PageSize,
+		validator.GreaterThan(0).Message(i18n.New(i18n.ReasonNotAboveZero)),
+		validator.Max(100),
+	)
 }
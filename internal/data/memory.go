@@ -0,0 +1,393 @@
+package data
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryUserStorage is a UserStorage kept entirely in process memory. It
+// mirrors the semantics of UserModel, including optimistic locking and
+// case-insensitive email uniqueness, and is meant for tests and local development.
+type MemoryUserStorage struct {
+	now func() time.Time
+
+	mu     sync.Mutex
+	users  map[int64]User
+	nextID int64
+}
+
+func NewMemoryUserStorage() *MemoryUserStorage {
+	return &MemoryUserStorage{
+		now:    time.Now,
+		users:  make(map[int64]User),
+		nextID: 1,
+	}
+}
+
+func (s *MemoryUserStorage) CreateUser(ctx context.Context, user *User) error {
+	tenant, ok := TenantFromContext(ctx)
+	if !ok {
+		return ErrMissingTenant
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.emailTakenLocked(tenant, user.Email, 0) {
+		return ErrDuplicateEmail
+	}
+
+	user.ID = s.nextID
+	user.TenantID = tenant
+	user.CreatedAt = s.now()
+	user.UpdatedAt = user.CreatedAt
+	user.Version = 1
+
+	s.users[user.ID] = *user
+	s.nextID++
+
+	return nil
+}
+
+func (s *MemoryUserStorage) UpsertUserByEmail(ctx context.Context, user *User) (bool, error) {
+	tenant, ok := TenantFromContext(ctx)
+	if !ok {
+		return false, ErrMissingTenant
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, existing := range s.users {
+		if existing.TenantID != tenant || emailKey(existing.Email) != emailKey(user.Email) {
+			continue
+		}
+
+		if existing.Erased() {
+			return false, ErrEditConflict
+		}
+
+		existing.Name = user.Name
+		existing.setProfile(user)
+		existing.Attributes = existing.Attributes.Merge(user.Attributes)
+		existing.UpdatedAt = s.now()
+		existing.Version++
+		s.users[id] = existing
+
+		*user = existing
+
+		return false, nil
+	}
+
+	user.ID = s.nextID
+	user.TenantID = tenant
+	user.CreatedAt = s.now()
+	user.UpdatedAt = user.CreatedAt
+	user.Version = 1
+
+	s.users[user.ID] = *user
+	s.nextID++
+
+	return true, nil
+}
+
+func (s *MemoryUserStorage) GetUser(ctx context.Context, id int64) (*User, error) {
+	if id < 1 {
+		return nil, ErrInvalidArgument
+	}
+
+	tenant, ok := TenantFromContext(ctx)
+	if !ok {
+		return nil, ErrMissingTenant
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[id]
+	if !ok || user.TenantID != tenant {
+		return nil, ErrRecordNotFound
+	}
+
+	return &user, nil
+}
+
+func (s *MemoryUserStorage) GetUserByEmail(ctx context.Context, email string) (*User, error) {
+	tenant, ok := TenantFromContext(ctx)
+	if !ok {
+		return nil, ErrMissingTenant
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, user := range s.users {
+		if user.TenantID == tenant && emailKey(user.Email) == emailKey(email) {
+			return &user, nil
+		}
+	}
+
+	return nil, ErrRecordNotFound
+}
+
+// GetUserForUpdate is the same as GetUser: transactions already hold the whole
+// storage exclusively, so there is no row lock to take.
+func (s *MemoryUserStorage) GetUserForUpdate(ctx context.Context, id int64) (*User, error) {
+	return s.GetUser(ctx, id)
+}
+
+func (s *MemoryUserStorage) CanSortBy(column string) bool {
+	return true
+}
+
+func (s *MemoryUserStorage) GetAll(ctx context.Context, filters Filters) ([]*User, MetaData, error) {
+	tenant, ok := TenantFromContext(ctx)
+	if !ok {
+		return nil, MetaData{}, ErrMissingTenant
+	}
+
+	var all []User
+
+	s.mu.Lock()
+	for _, user := range s.users {
+		if user.TenantID == tenant && user.Attributes.Contains(filters.Attributes) && user.Attributes.HasAll(filters.HasAttributes) {
+			all = append(all, user)
+		}
+	}
+	s.mu.Unlock()
+
+	column := filters.sortColumn()
+	desc := filters.sortDirection() == "DESC"
+
+	slices.SortFunc(all, func(a, b User) int {
+		var c int
+
+		switch column {
+		case "name":
+			c = strings.Compare(a.Name, b.Name)
+		case "email":
+			c = strings.Compare(a.Email, b.Email)
+		case "age":
+			c = cmp.Compare(a.Age, b.Age)
+		default:
+			c = cmp.Compare(a.ID, b.ID)
+		}
+
+		if desc {
+			c = -c
+		}
+
+		if c == 0 {
+			c = cmp.Compare(a.ID, b.ID)
+		}
+
+		return c
+	})
+
+	users := []*User{}
+
+	for i := filters.offset(); i < len(all) && len(users) < filters.limit(); i++ {
+		users = append(users, &all[i])
+	}
+
+	if len(users) == 0 {
+		return users, MetaData{}, nil
+	}
+
+	return users, calculateMetadata(len(all), filters.Page, filters.PageSize), nil
+}
+
+// SearchUsers approximates the full-text and trigram search of UserModel, see
+// matchUser.
+func (s *MemoryUserStorage) SearchUsers(ctx context.Context, query string, filters Filters) ([]*UserMatch, MetaData, error) {
+	tenant, ok := TenantFromContext(ctx)
+	if !ok {
+		return nil, MetaData{}, ErrMissingTenant
+	}
+
+	var all []*UserMatch
+
+	s.mu.Lock()
+	for _, user := range s.users {
+		if user.TenantID != tenant {
+			continue
+		}
+
+		if score, ok := matchUser(&user, query); ok {
+			all = append(all, &UserMatch{User: &user, Score: score})
+		}
+	}
+	s.mu.Unlock()
+
+	slices.SortFunc(all, func(a, b *UserMatch) int {
+		if c := cmp.Compare(b.Score, a.Score); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.User.ID, b.User.ID)
+	})
+
+	matches := []*UserMatch{}
+
+	for i := filters.offset(); i < len(all) && len(matches) < filters.limit(); i++ {
+		matches = append(matches, all[i])
+	}
+
+	if len(matches) == 0 {
+		return matches, MetaData{}, nil
+	}
+
+	return matches, calculateMetadata(len(all), filters.Page, filters.PageSize), nil
+}
+
+func (s *MemoryUserStorage) GetUserStats(ctx context.Context, filters StatsFilters) (*UserStats, error) {
+	tenant, ok := TenantFromContext(ctx)
+	if !ok {
+		return nil, ErrMissingTenant
+	}
+
+	var users []*User
+
+	s.mu.Lock()
+	for _, user := range s.users {
+		if user.TenantID == tenant && filters.matches(&user) {
+			users = append(users, &user)
+		}
+	}
+	s.mu.Unlock()
+
+	return computeStats(users, filters, s.now()), nil
+}
+
+func (s *MemoryUserStorage) DeleteUserById(ctx context.Context, id int64) (*User, error) {
+	if id < 1 {
+		return nil, ErrInvalidArgument
+	}
+
+	tenant, ok := TenantFromContext(ctx)
+	if !ok {
+		return nil, ErrMissingTenant
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[id]
+	if !ok || user.TenantID != tenant {
+		return nil, ErrRecordNotFound
+	}
+
+	delete(s.users, id)
+
+	return &user, nil
+}
+
+func (s *MemoryUserStorage) EraseUser(ctx context.Context, id int64) (*User, error) {
+	if id < 1 {
+		return nil, ErrInvalidArgument
+	}
+
+	tenant, ok := TenantFromContext(ctx)
+	if !ok {
+		return nil, ErrMissingTenant
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[id]
+	if !ok || user.TenantID != tenant {
+		return nil, ErrRecordNotFound
+	}
+
+	if user.Erased() {
+		return &user, nil
+	}
+
+	user.anonymize(s.now())
+	user.UpdatedAt = s.now()
+	user.Version++
+	s.users[id] = user
+
+	return &user, nil
+}
+
+func (s *MemoryUserStorage) UpdateUser(ctx context.Context, user *User) error {
+	tenant, ok := TenantFromContext(ctx)
+	if !ok {
+		return ErrMissingTenant
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.users[user.ID]
+	if !ok || existing.TenantID != tenant || existing.Version != user.Version || existing.Erased() {
+		return ErrEditConflict
+	}
+
+	if s.emailTakenLocked(tenant, user.Email, user.ID) {
+		return ErrDuplicateEmail
+	}
+
+	existing.Name = user.Name
+	existing.Email = user.Email
+	existing.setProfile(user)
+	existing.Attributes = maps.Clone(user.Attributes)
+	existing.UpdatedAt = s.now()
+	existing.Version++
+	s.users[user.ID] = existing
+
+	user.UpdatedAt = existing.UpdatedAt
+	user.Version = existing.Version
+
+	return nil
+}
+
+// RunInTx runs fn against a copy of the storage while holding the storage
+// lock, so transactions are always serializable regardless of opts. The copy
+// replaces the current contents only if fn succeeds.
+func (s *MemoryUserStorage) RunInTx(ctx context.Context, opts *sql.TxOptions, fn func(UserStorage) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx := &MemoryUserStorage{
+		now:    s.now,
+		users:  maps.Clone(s.users),
+		nextID: s.nextID,
+	}
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	s.users = tx.users
+	s.nextID = tx.nextID
+
+	return nil
+}
+
+// setProfile copies the profile fields that UpdateUser and UpsertUserByEmail
+// change from user.
+func (u *User) setProfile(user *User) {
+	u.DisplayName = user.DisplayName
+	u.Phone = user.Phone
+	u.Locale = user.Locale
+	u.TimeZone = user.TimeZone
+	u.Birthdate = user.Birthdate
+	u.Age = user.Age
+}
+
+func (s *MemoryUserStorage) emailTakenLocked(tenant, email string, exceptID int64) bool {
+	for id, user := range s.users {
+		if id != exceptID && user.TenantID == tenant && emailKey(user.Email) == emailKey(email) {
+			return true
+		}
+	}
+	return false
+}
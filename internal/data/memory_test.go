@@ -0,0 +1,351 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testTenantContext() context.Context {
+	return ContextWithTenant(context.Background(), "acme")
+}
+
+func seedMemoryStorage(t *testing.T) *MemoryUserStorage {
+	t.Helper()
+
+	s := NewMemoryUserStorage()
+
+	for _, user := range []*User{
+		{Name: "Andrew", Email: "andrew@google.com", Age: 31},
+		{Name: "John", Email: "john@gmail.com", Age: 21},
+		{Name: "Jane", Email: "jane@gmail.com", Age: 25},
+	} {
+		require.NoError(t, s.CreateUser(testTenantContext(), user))
+	}
+
+	return s
+}
+
+func TestMemoryUserStorage_CRUD(t *testing.T) {
+	s := seedMemoryStorage(t)
+	ctx := testTenantContext()
+
+	err := s.CreateUser(ctx, &User{Name: "Other", Email: "andrew@google.com", Age: 40})
+	assert.ErrorIs(t, err, ErrDuplicateEmail)
+
+	user, err := s.GetUser(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "Andrew", user.Name)
+	assert.Equal(t, int32(1), user.Version)
+
+	stale := *user
+
+	user.Age = 32
+	require.NoError(t, s.UpdateUser(ctx, user))
+	assert.Equal(t, int32(2), user.Version)
+
+	assert.ErrorIs(t, s.UpdateUser(ctx, &stale), ErrEditConflict)
+
+	user.Email = "john@gmail.com"
+	assert.ErrorIs(t, s.UpdateUser(ctx, user), ErrDuplicateEmail)
+
+	_, err = s.DeleteUserById(ctx, 2)
+	require.NoError(t, err)
+
+	_, err = s.GetUser(ctx, 2)
+	assert.ErrorIs(t, err, ErrRecordNotFound)
+
+	_, err = s.GetUser(ctx, 0)
+	assert.ErrorIs(t, err, ErrInvalidArgument)
+}
+
+func TestMemoryUserStorage_GetAll(t *testing.T) {
+	s := seedMemoryStorage(t)
+
+	users, metadata, err := s.GetAll(testTenantContext(), Filters{
+		Page:         1,
+		PageSize:     2,
+		Sort:         "-age",
+		SortSafelist: []string{"id", "-age"},
+	})
+	require.NoError(t, err)
+
+	require.Len(t, users, 2)
+	assert.Equal(t, "Andrew", users[0].Name)
+	assert.Equal(t, "Jane", users[1].Name)
+	assert.Equal(t, MetaData{CurrentPage: 1, PageSize: 2, FirstPage: 1, LastPage: 2, TotalRecords: 3}, metadata)
+}
+
+func TestModels_WithTx(t *testing.T) {
+	ctx := testTenantContext()
+
+	t.Run("commits", func(t *testing.T) {
+		models := Models{Users: seedMemoryStorage(t)}
+
+		err := models.WithTx(ctx, func(tx Models) error {
+			user, err := tx.Users.GetUserForUpdate(ctx, 1)
+			if err != nil {
+				return err
+			}
+
+			user.Age++
+			if err := tx.Users.UpdateUser(ctx, user); err != nil {
+				return err
+			}
+
+			return tx.Users.CreateUser(ctx, &User{Name: "Kate", Email: "kate@gmail.com", Age: 28})
+		})
+		require.NoError(t, err)
+
+		user, err := models.Users.GetUser(ctx, 1)
+		require.NoError(t, err)
+		assert.Equal(t, int32(32), user.Age)
+
+		_, err = models.Users.GetUser(ctx, 4)
+		assert.NoError(t, err)
+	})
+
+	t.Run("rolls back", func(t *testing.T) {
+		models := Models{Users: seedMemoryStorage(t)}
+		abort := errors.New("abort")
+
+		err := models.WithTx(ctx, func(tx Models) error {
+			if _, err := tx.Users.DeleteUserById(ctx, 1); err != nil {
+				return err
+			}
+
+			if err := tx.Users.CreateUser(ctx, &User{Name: "Kate", Email: "kate@gmail.com", Age: 28}); err != nil {
+				return err
+			}
+
+			return abort
+		})
+		require.ErrorIs(t, err, abort)
+
+		_, err = models.Users.GetUser(ctx, 1)
+		assert.NoError(t, err, "deleted user must be restored")
+
+		user := &User{Name: "Kate", Email: "kate@gmail.com", Age: 28}
+		require.NoError(t, models.Users.CreateUser(ctx, user))
+		assert.Equal(t, int64(4), user.ID, "ids allocated in a rolled back transaction must be released")
+	})
+}
+
+func TestMemoryUserStorage_TenantIsolation(t *testing.T) {
+	s := seedMemoryStorage(t)
+	other := ContextWithTenant(context.Background(), "globex")
+
+	_, err := s.GetUser(other, 1)
+	assert.ErrorIs(t, err, ErrRecordNotFound)
+
+	users, _, err := s.GetAll(other, Filters{Page: 1, PageSize: 10, Sort: "id", SortSafelist: []string{"id"}})
+	require.NoError(t, err)
+	assert.Empty(t, users)
+
+	user := &User{Name: "Andrew", Email: "andrew@google.com", Age: 31}
+	require.NoError(t, s.CreateUser(other, user), "emails are unique per tenant")
+	assert.Equal(t, "globex", user.TenantID)
+
+	_, err = s.DeleteUserById(other, 1)
+	assert.ErrorIs(t, err, ErrRecordNotFound)
+
+	_, err = s.GetUser(context.Background(), 1)
+	assert.ErrorIs(t, err, ErrMissingTenant)
+}
+
+func TestMemoryUserStorage_EmailCaseInsensitive(t *testing.T) {
+	s := seedMemoryStorage(t)
+	ctx := testTenantContext()
+
+	err := s.CreateUser(ctx, &User{Name: "Other", Email: "Andrew@Google.com", Age: 40})
+	assert.ErrorIs(t, err, ErrDuplicateEmail)
+
+	user := &User{Name: "Andrew Smith", Email: "ANDREW@google.com", Age: 32}
+	inserted, err := s.UpsertUserByEmail(ctx, user)
+	require.NoError(t, err)
+	assert.False(t, inserted)
+	assert.Equal(t, int64(1), user.ID)
+}
+
+func TestMemoryUserStorage_GetUserByEmail(t *testing.T) {
+	s := seedMemoryStorage(t)
+
+	user, err := s.GetUserByEmail(testTenantContext(), "ANDREW@google.com")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), user.ID)
+
+	_, err = s.GetUserByEmail(ContextWithTenant(context.Background(), "globex"), "andrew@google.com")
+	assert.ErrorIs(t, err, ErrRecordNotFound)
+}
+
+func TestMemoryUserStorage_GetAll_Attributes(t *testing.T) {
+	s := NewMemoryUserStorage()
+	ctx := testTenantContext()
+
+	for _, user := range []*User{
+		{Name: "Andrew", Email: "andrew@google.com", Attributes: Attributes{"department": "sales", "cost_center": "42"}},
+		{Name: "John", Email: "john@gmail.com", Attributes: Attributes{"department": "sales"}},
+		{Name: "Jane", Email: "jane@gmail.com", Attributes: Attributes{"department": "support", "cost_center": "7"}},
+	} {
+		require.NoError(t, s.CreateUser(ctx, user))
+	}
+
+	filters := Filters{Page: 1, PageSize: 10, Sort: "id", SortSafelist: []string{"id"}}
+
+	names := func(filters Filters) []string {
+		users, _, err := s.GetAll(ctx, filters)
+		require.NoError(t, err)
+
+		var names []string
+		for _, user := range users {
+			names = append(names, user.Name)
+		}
+		return names
+	}
+
+	filters.Attributes = map[string]string{"department": "sales"}
+	assert.Equal(t, []string{"Andrew", "John"}, names(filters))
+
+	filters.HasAttributes = []string{"cost_center"}
+	assert.Equal(t, []string{"Andrew"}, names(filters))
+
+	filters.Attributes = nil
+	assert.Equal(t, []string{"Andrew", "Jane"}, names(filters))
+}
+
+func TestMemoryUserStorage_SearchUsers(t *testing.T) {
+	s := NewMemoryUserStorage()
+	ctx := testTenantContext()
+
+	for _, user := range []*User{
+		{Name: "John Smith", Email: "john.smith@gmail.com"},
+		{Name: "Jane Smith", Email: "jane@gmail.com"},
+		{Name: "Andrew", Email: "andrew@google.com"},
+	} {
+		require.NoError(t, s.CreateUser(ctx, user))
+	}
+
+	filters := Filters{Page: 1, PageSize: 10}
+
+	names := func(query string) []string {
+		matches, _, err := s.SearchUsers(ctx, query, filters)
+		require.NoError(t, err)
+
+		var names []string
+		for _, match := range matches {
+			names = append(names, match.User.Name)
+		}
+		return names
+	}
+
+	assert.Equal(t, []string{"John Smith"}, names("jon smth"), "typos")
+	assert.Equal(t, []string{"John Smith", "Jane Smith"}, names("smith"), "equal scores are ordered by id")
+	assert.Equal(t, []string{"Andrew"}, names("ANDREW@google.com"))
+	assert.Empty(t, names("kate"))
+
+	matches, metadata, err := s.SearchUsers(ctx, "smith", Filters{Page: 2, PageSize: 1})
+	require.NoError(t, err)
+	require.Len(t, matches, 1)
+	assert.Equal(t, "Jane Smith", matches[0].User.Name)
+	assert.Equal(t, 2, metadata.TotalRecords)
+}
+
+func TestMemoryUserStorage_GetUserStats(t *testing.T) {
+	s := NewMemoryUserStorage()
+	ctx := testTenantContext()
+
+	now := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+
+	for _, user := range []*User{
+		{Name: "Andrew", Email: "andrew@google.com", Age: 31, Attributes: Attributes{"department": "sales"}},
+		{Name: "John", Email: "john@Gmail.com", Age: 17},
+		{Name: "Jane", Email: "jane@gmail.com", Age: 70, Attributes: Attributes{"department": "sales"}},
+	} {
+		user.Birthdate = BirthdateFromAge(user.Age, DateOf(now))
+		require.NoError(t, s.CreateUser(ctx, user))
+		now = now.AddDate(0, 0, 2)
+	}
+
+	stats, err := s.GetUserStats(ctx, StatsFilters{Interval: IntervalDay, TopDomains: 1})
+	require.NoError(t, err)
+
+	assert.Equal(t, 3, stats.Total)
+	assert.Equal(t, []AgeBucket{
+		{Range: "0-17", Count: 1},
+		{Range: "18-24", Count: 0},
+		{Range: "25-34", Count: 1},
+		{Range: "35-44", Count: 0},
+		{Range: "45-54", Count: 0},
+		{Range: "55-64", Count: 0},
+		{Range: "65+", Count: 1},
+	}, stats.AgeBuckets)
+	assert.Equal(t, []DomainCount{{Domain: "gmail.com", Count: 2}}, stats.TopDomains)
+
+	day := func(d int) time.Time { return time.Date(2025, 3, d, 0, 0, 0, 0, time.UTC) }
+	assert.Equal(t, []SignupCount{
+		{PeriodStart: day(10), Count: 1},
+		{PeriodStart: day(11), Count: 0},
+		{PeriodStart: day(12), Count: 1},
+		{PeriodStart: day(13), Count: 0},
+		{PeriodStart: day(14), Count: 1},
+	}, stats.Signups, "gaps are filled with zero counts")
+
+	stats, err = s.GetUserStats(ctx, StatsFilters{
+		CreatedFrom: day(11),
+		Attributes:  map[string]string{"department": "sales"},
+		Interval:    IntervalWeek,
+		TopDomains:  10,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, stats.Total)
+	assert.Equal(t, []SignupCount{{PeriodStart: day(10), Count: 1}}, stats.Signups, "weeks start on Monday")
+}
+
+func TestMemoryUserStorage_EraseUser(t *testing.T) {
+	s := seedMemoryStorage(t)
+	ctx := testTenantContext()
+
+	require.NoError(t, s.UpdateUser(ctx, &User{
+		ID: 1, Name: "Andrew", Email: "andrew@google.com", Phone: "+15551234567",
+		Birthdate: time.Date(1994, 7, 15, 0, 0, 0, 0, time.UTC), Attributes: Attributes{"department": "sales"}, Version: 1,
+	}))
+
+	user, err := s.EraseUser(ctx, 1)
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(1), user.ID)
+	assert.Equal(t, ErasedName, user.Name)
+	assert.Equal(t, ErasedEmail(1), user.Email)
+	assert.Empty(t, user.Phone)
+	assert.Empty(t, user.Attributes)
+	assert.Equal(t, time.Date(1994, 1, 1, 0, 0, 0, 0, time.UTC), user.Birthdate)
+	assert.True(t, user.Erased())
+	assert.Equal(t, int32(3), user.Version)
+
+	erasedAt := user.ErasedAt
+
+	user, err = s.EraseUser(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, erasedAt, user.ErasedAt, "erasing again keeps the first time")
+	assert.Equal(t, int32(3), user.Version, "erasing again changes nothing")
+
+	assert.ErrorIs(t, s.UpdateUser(ctx, &User{ID: 1, Name: "Andrew", Email: "andrew@google.com", Version: 3}), ErrEditConflict, "erased users are never updated")
+
+	_, err = s.UpsertUserByEmail(ctx, &User{Name: "Andrew", Email: ErasedEmail(1)})
+	assert.ErrorIs(t, err, ErrEditConflict, "not even through their erased email")
+
+	user, err = s.GetUser(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, ErasedName, user.Name)
+
+	require.NoError(t, s.CreateUser(ctx, &User{Name: "Andrew", Email: "andrew@google.com"}), "the email is free again")
+
+	_, err = s.EraseUser(ContextWithTenant(context.Background(), "other"), 2)
+	assert.ErrorIs(t, err, ErrRecordNotFound)
+}
@@ -0,0 +1,38 @@
+package data
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var queryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace: "users",
+	Subsystem: "db",
+	Name:      "query_duration_seconds",
+	Help:      "Duration of UserModel queries by query type.",
+	Buckets:   prometheus.DefBuckets,
+}, []string{"query"})
+
+var cacheLookups = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "users",
+	Subsystem: "cache",
+	Name:      "lookups_total",
+	Help:      "User cache lookups by result (hit or miss).",
+}, []string{"result"})
+
+var statsCacheLookups = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "users",
+	Subsystem: "stats_cache",
+	Name:      "lookups_total",
+	Help:      "User statistics cache lookups by result (hit or miss).",
+}, []string{"result"})
+
+var dbRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "users",
+	Subsystem: "db",
+	Name:      "retries_total",
+	Help:      "Retries of UserStorage calls after transient database errors, by query type.",
+}, []string{"query"})
+
+func MetricsCollectors() []prometheus.Collector {
+	return []prometheus.Collector{queryDuration, cacheLookups, statsCacheLookups, dbRetries}
+}
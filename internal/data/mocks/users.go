@@ -1,6 +1,12 @@
 package mocks
 
-import "github.com/Vadim-Makhnev/grpc/internal/data"
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/Vadim-Makhnev/grpc/internal/data"
+)
 
 type UserStorageMock struct{}
 
@@ -8,14 +14,14 @@ func NewUserStorageMock() UserStorageMock {
 	return UserStorageMock{}
 }
 
-func (s UserStorageMock) CreateUser(user *data.User) error {
+func (s UserStorageMock) CreateUser(ctx context.Context, user *data.User) error {
 	user.ID = 1
 	user.Version = 1
 
 	return nil
 }
 
-func (s UserStorageMock) GetUser(id int64) (*data.User, error) {
+func (s UserStorageMock) GetUser(ctx context.Context, id int64) (*data.User, error) {
 	if id == 1 {
 		return &data.User{
 			ID:      1,
@@ -38,7 +44,19 @@ func (s UserStorageMock) GetUser(id int64) (*data.User, error) {
 	}
 }
 
-func (s UserStorageMock) GetAll(filters data.Filters) ([]*data.User, data.MetaData, error) {
+func (s UserStorageMock) GetUserByEmail(ctx context.Context, email string) (*data.User, error) {
+	if email == "andrew@google.com" {
+		return s.GetUser(ctx, 1)
+	}
+
+	return nil, data.ErrRecordNotFound
+}
+
+func (s UserStorageMock) CanSortBy(column string) bool {
+	return true
+}
+
+func (s UserStorageMock) GetAll(ctx context.Context, filters data.Filters) ([]*data.User, data.MetaData, error) {
 	return []*data.User{
 		{
 			ID:      1,
@@ -57,7 +75,22 @@ func (s UserStorageMock) GetAll(filters data.Filters) ([]*data.User, data.MetaDa
 	}, data.MetaData{}, nil
 }
 
-func (s UserStorageMock) DeleteUserById(id int64) (*data.User, error) {
+func (s UserStorageMock) SearchUsers(ctx context.Context, query string, filters data.Filters) ([]*data.UserMatch, data.MetaData, error) {
+	user, _ := s.GetUser(ctx, 1)
+
+	return []*data.UserMatch{{User: user, Score: 1}}, data.MetaData{}, nil
+}
+
+func (s UserStorageMock) GetUserStats(ctx context.Context, filters data.StatsFilters) (*data.UserStats, error) {
+	return &data.UserStats{
+		Total:      1,
+		AgeBuckets: []data.AgeBucket{{Range: "25-34", Count: 1}},
+		TopDomains: []data.DomainCount{{Domain: "google.com", Count: 1}},
+		Signups:    []data.SignupCount{{PeriodStart: time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC), Count: 1}},
+	}, nil
+}
+
+func (s UserStorageMock) DeleteUserById(ctx context.Context, id int64) (*data.User, error) {
 	if id == 1 {
 		return &data.User{
 			ID:      1,
@@ -71,7 +104,22 @@ func (s UserStorageMock) DeleteUserById(id int64) (*data.User, error) {
 	}
 }
 
-func (s UserStorageMock) UpdateUser(user *data.User) error {
+func (s UserStorageMock) EraseUser(ctx context.Context, id int64) (*data.User, error) {
+	if id == 1 {
+		return &data.User{
+			ID:       1,
+			Name:     data.ErasedName,
+			Email:    data.ErasedEmail(1),
+			Age:      31,
+			ErasedAt: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+			Version:  2,
+		}, nil
+	} else {
+		return nil, data.ErrRecordNotFound
+	}
+}
+
+func (s UserStorageMock) UpdateUser(ctx context.Context, user *data.User) error {
 	if user.ID == 1 {
 		user.Version = user.Version + 1
 		return nil
@@ -79,3 +127,24 @@ func (s UserStorageMock) UpdateUser(user *data.User) error {
 		return data.ErrEditConflict
 	}
 }
+
+func (s UserStorageMock) UpsertUserByEmail(ctx context.Context, user *data.User) (bool, error) {
+	if user.Email == "andrew@google.com" {
+		user.ID = 1
+		user.Version = 2
+		return false, nil
+	}
+
+	user.ID = 2
+	user.Version = 1
+
+	return true, nil
+}
+
+func (s UserStorageMock) GetUserForUpdate(ctx context.Context, id int64) (*data.User, error) {
+	return s.GetUser(ctx, id)
+}
+
+func (s UserStorageMock) RunInTx(ctx context.Context, opts *sql.TxOptions, fn func(data.UserStorage) error) error {
+	return fn(s)
+}
@@ -1,32 +1,70 @@
 package data
 
 import (
+	"context"
 	"database/sql"
 	"errors"
+
+	"github.com/Vadim-Makhnev/grpc/internal/encryption"
 )
 
 var (
 	ErrRecordNotFound  = errors.New("record not found")
 	ErrEditConflict    = errors.New("edit conflict")
 	ErrInvalidArgument = errors.New("invalid argument")
+	ErrDuplicateEmail  = errors.New("duplicate email")
 )
 
 type UserStorage interface {
-	CreateUser(user *User) error
-	GetUser(id int64) (*User, error)
-	GetAll(filters Filters) ([]*User, MetaData, error)
-	DeleteUserById(id int64) (*User, error)
-	UpdateUser(user *User) error
+	CreateUser(ctx context.Context, user *User) error
+	GetUser(ctx context.Context, id int64) (*User, error)
+	GetUserByEmail(ctx context.Context, email string) (*User, error)
+	GetAll(ctx context.Context, filters Filters) ([]*User, MetaData, error)
+	// CanSortBy reports whether GetAll can order users by column.
+	CanSortBy(column string) bool
+	SearchUsers(ctx context.Context, query string, filters Filters) ([]*UserMatch, MetaData, error)
+	GetUserStats(ctx context.Context, filters StatsFilters) (*UserStats, error)
+	DeleteUserById(ctx context.Context, id int64) (*User, error)
+	EraseUser(ctx context.Context, id int64) (*User, error)
+	UpdateUser(ctx context.Context, user *User) error
+	UpsertUserByEmail(ctx context.Context, user *User) (bool, error)
+	GetUserForUpdate(ctx context.Context, id int64) (*User, error)
+	RunInTx(ctx context.Context, opts *sql.TxOptions, fn func(UserStorage) error) error
+}
+
+type DBTX interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
 }
 
 type Models struct {
-	Users UserStorage
+	Users       UserStorage
+	TxIsolation sql.IsolationLevel
 }
 
-func NewModels(db *sql.DB) Models {
+// NewModels returns the models backed by db. cipher may be nil, which keeps
+// names and emails in plaintext.
+func NewModels(db *sql.DB, rowLevelSecurity bool, cipher *encryption.Cipher) Models {
 	return Models{
 		Users: UserModel{
-			DB: db,
+			DB:               db,
+			RowLevelSecurity: rowLevelSecurity,
+			Cipher:           cipher,
 		},
 	}
 }
+
+// WithTx runs fn inside a transaction using the default isolation level. The
+// transaction is committed if fn returns nil and rolled back otherwise. fn may
+// be called more than once when the transaction is retried, so it must not
+// have side effects outside of tx.
+func (m Models) WithTx(ctx context.Context, fn func(tx Models) error) error {
+	return m.WithTxOptions(ctx, &sql.TxOptions{Isolation: m.TxIsolation}, fn)
+}
+
+func (m Models) WithTxOptions(ctx context.Context, opts *sql.TxOptions, fn func(tx Models) error) error {
+	return m.Users.RunInTx(ctx, opts, func(users UserStorage) error {
+		return fn(Models{Users: users, TxIsolation: m.TxIsolation})
+	})
+}
@@ -0,0 +1,131 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testPostgres connects to the migrated database in GRPC_TEST_DB_DSN and
+// returns a context with a tenant of its own, whose users are deleted when
+// the test ends. Tests that use it are skipped without the variable.
+func testPostgres(t *testing.T) (*sql.DB, context.Context) {
+	t.Helper()
+
+	dsn := os.Getenv("GRPC_TEST_DB_DSN")
+	if dsn == "" {
+		t.Skip("GRPC_TEST_DB_DSN is not set")
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	tenant := fmt.Sprintf("test-%d", time.Now().UnixNano())
+	t.Cleanup(func() {
+		_, err := db.Exec(`DELETE FROM users WHERE tenant_id = $1`, tenant)
+		assert.NoError(t, err)
+	})
+
+	return db, ContextWithTenant(context.Background(), tenant)
+}
+
+func TestUserModel_EmailUniqueAcrossEncryption(t *testing.T) {
+	db, ctx := testPostgres(t)
+
+	plain := UserModel{DB: db}
+	encrypted := UserModel{DB: db, Cipher: newTestCipher(t)}
+
+	// Users created before encryption was enabled have no email hash.
+	andrew := &User{Name: "Andrew", Email: "andrew@google.com"}
+	require.NoError(t, plain.CreateUser(ctx, andrew))
+	jane := &User{Name: "Jane", Email: "jane@gmail.com"}
+	require.NoError(t, plain.CreateUser(ctx, jane))
+	john := &User{Name: "John", Email: "john@gmail.com"}
+	require.NoError(t, plain.CreateUser(ctx, john))
+	jack := &User{Name: "Jack", Email: "jack@gmail.com"}
+	require.NoError(t, plain.CreateUser(ctx, jack))
+
+	err := encrypted.CreateUser(ctx, &User{Name: "Other", Email: "Andrew@google.com"})
+	assert.ErrorIs(t, err, ErrDuplicateEmail)
+
+	other := &User{Name: "Other", Email: "other@google.com"}
+	require.NoError(t, encrypted.CreateUser(ctx, other))
+
+	other.Email = "jane@gmail.com"
+	err = encrypted.UpdateUser(ctx, other)
+	assert.ErrorIs(t, err, ErrDuplicateEmail)
+
+	upserted := &User{Name: "John Smith", Email: "john@gmail.com"}
+	inserted, err := encrypted.UpsertUserByEmail(ctx, upserted)
+	require.NoError(t, err)
+	assert.False(t, inserted)
+	assert.Equal(t, john.ID, upserted.ID)
+
+	user, err := encrypted.GetUserByEmail(ctx, "john@gmail.com")
+	require.NoError(t, err)
+	assert.Equal(t, john.ID, user.ID)
+	assert.Equal(t, "John Smith", user.Name)
+
+	// The failed writes claimed the hashes of Andrew and Jane, which leaves
+	// Jack as the only user without one.
+	n, err := encrypted.BackfillEmailHashes(ctx, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	n, err = encrypted.BackfillEmailHashes(ctx, 10)
+	require.NoError(t, err)
+	assert.Zero(t, n)
+
+	err = encrypted.CreateUser(ctx, &User{Name: "Jack", Email: "jack@gmail.com"})
+	assert.ErrorIs(t, err, ErrDuplicateEmail)
+
+	n, err = encrypted.ReencryptUsers(ctx, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+
+	user, err = encrypted.GetUserByEmail(ctx, "andrew@google.com")
+	require.NoError(t, err)
+	assert.Equal(t, andrew.ID, user.ID)
+	assert.Equal(t, "Andrew", user.Name)
+}
+
+func TestUserModel_SearchUsersEncrypted(t *testing.T) {
+	db, ctx := testPostgres(t)
+
+	plain := UserModel{DB: db}
+	encrypted := UserModel{DB: db, Cipher: newTestCipher(t)}
+
+	legacy := &User{Name: "Andrew Smith", DisplayName: "Andy", Email: "andrew@google.com"}
+	require.NoError(t, plain.CreateUser(ctx, legacy))
+	john := &User{Name: "John Smith", DisplayName: "Johnny", Email: "john@gmail.com"}
+	require.NoError(t, encrypted.CreateUser(ctx, john))
+
+	search := func(query string) []int64 {
+		t.Helper()
+
+		matches, _, err := encrypted.SearchUsers(ctx, query, Filters{Page: 1, PageSize: 10})
+		require.NoError(t, err)
+
+		ids := []int64{}
+		for _, match := range matches {
+			ids = append(ids, match.User.ID)
+		}
+		return ids
+	}
+
+	// Names and emails aren't searched, not even those still in plaintext.
+	assert.Empty(t, search("Smith"))
+	assert.Empty(t, search("google"))
+
+	assert.Equal(t, []int64{legacy.ID}, search("Andy"))
+	assert.Equal(t, []int64{john.ID}, search("Jonny"))
+	assert.Equal(t, []int64{john.ID}, search("John@gmail.com"))
+	assert.Equal(t, []int64{legacy.ID}, search("andrew@google.com"))
+}
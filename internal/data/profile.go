@@ -0,0 +1,153 @@
+package data
+
+import (
+	"regexp"
+	"strings"
+	"time"
+	_ "time/tzdata" // time zones are validated against the embedded IANA database
+
+	"github.com/Vadim-Makhnev/grpc/internal/i18n"
+	"github.com/Vadim-Makhnev/grpc/internal/validator"
+	"golang.org/x/text/language"
+)
+
+// Limits of the profile columns added in migration 000004.
+const (
+	displayNameMaxLength = 50
+	localeMaxLength      = 35
+	timeZoneMaxLength    = 64
+
+	// maxAge bounds both the age accepted from old clients and how far in the
+	// past a birthdate may be.
+	maxAge = 150
+)
+
+// PhoneRX matches phone numbers in E.164 format.
+var PhoneRX = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+
+// NormalizePhone trims phone and strips the separators people commonly type,
+// so "+1 (415) 555-0123" is stored as "+14155550123".
+func NormalizePhone(phone string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '\t', '-', '.', '(', ')':
+			return -1
+		}
+		return r
+	}, phone)
+}
+
+// NormalizeLocale returns the canonical form of a BCP 47 language tag, e.g.
+// "en-us" becomes "en-US". Values that don't parse are returned trimmed so
+// that validation can reject them.
+func NormalizeLocale(locale string) string {
+	locale = strings.TrimSpace(locale)
+	if locale == "" {
+		return ""
+	}
+
+	tag, err := language.Parse(locale)
+	if err != nil {
+		return locale
+	}
+
+	return tag.String()
+}
+
+// Today returns the current UTC date at midnight.
+func Today() time.Time {
+	return DateOf(time.Now())
+}
+
+// DateOf drops the time of day from t, keeping its calendar date in UTC.
+func DateOf(t time.Time) time.Time {
+	y, m, d := t.UTC().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// AgeOn returns the number of full years between birthdate and on.
+func AgeOn(birthdate, on time.Time) int32 {
+	age := on.Year() - birthdate.Year()
+	if on.Month() < birthdate.Month() || on.Month() == birthdate.Month() && on.Day() < birthdate.Day() {
+		age--
+	}
+	return int32(max(age, 0))
+}
+
+// BirthdateFromAge approximates the birthdate of someone who is age years old
+// on the given day by placing it in the middle of that year of their life, the
+// same way migration 000004 backfills existing users.
+func BirthdateFromAge(age int32, on time.Time) time.Time {
+	return DateOf(on).AddDate(-int(age), -6, 0)
+}
+
+func validLocale() validator.Rule[string] {
+	return func(value string) (i18n.Message, bool) {
+		if value == "" {
+			return i18n.Message{}, true
+		}
+
+		if _, err := language.Parse(value); err != nil {
+			return i18n.New(i18n.ReasonInvalidLocale), false
+		}
+
+		return i18n.Message{}, true
+	}
+}
+
+func validTimeZone() validator.Rule[string] {
+	return func(value string) (i18n.Message, bool) {
+		if value == "" {
+			return i18n.Message{}, true
+		}
+
+		// "Local" would mean the server's zone, not something a client can rely on.
+		if _, err := time.LoadLocation(value); err != nil || value == "Local" {
+			return i18n.New(i18n.ReasonInvalidTimeZone), false
+		}
+
+		return i18n.Message{}, true
+	}
+}
+
+func validateProfile(v *validator.Validator, user *User) {
+	user.Phone = NormalizePhone(user.Phone)
+	user.Locale = NormalizeLocale(user.Locale)
+	user.TimeZone = strings.TrimSpace(user.TimeZone)
+
+	validator.Field(v, "display_name", user.DisplayName,
+		validator.MaxRunes(displayNameMaxLength),
+		validator.ValidUTF8(),
+		validator.NoControlChars(),
+	)
+	validator.Field(v, "phone", user.Phone, validator.Match(PhoneRX, i18n.New(i18n.ReasonInvalidPhone)))
+	validator.Field(v, "locale", user.Locale, validator.MaxRunes(localeMaxLength), validLocale())
+	validator.Field(v, "time_zone", user.TimeZone, validator.MaxRunes(timeZoneMaxLength), validTimeZone())
+
+	validateBirthdate(v, user, Today())
+}
+
+// validateBirthdate checks the birthdate and keeps Age in step with it. Clients
+// that only send an age get an approximate birthdate derived from it instead.
+func validateBirthdate(v *validator.Validator, user *User, today time.Time) {
+	if user.Birthdate.IsZero() {
+		validator.Field(v, "age", user.Age,
+			validator.GreaterThan[int32](0),
+			validator.Max[int32](maxAge),
+		)
+
+		if user.Age > 0 && user.Age <= maxAge {
+			user.Birthdate = BirthdateFromAge(user.Age, today)
+		}
+		return
+	}
+
+	user.Birthdate = DateOf(user.Birthdate)
+
+	validator.Field(v, "birthdate", user.Birthdate,
+		validator.NotAfter(today).Message(i18n.New(i18n.ReasonDateInFuture)),
+		validator.NotBefore(today.AddDate(-maxAge, 0, 0)).Message(i18n.New(i18n.ReasonDateTooFarInPast, maxAge)),
+	)
+
+	user.Age = AgeOn(user.Birthdate, today)
+}
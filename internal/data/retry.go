@@ -0,0 +1,225 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"syscall"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type RetryingUserStorage struct {
+	UserStorage
+
+	maxAttempts int
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+	sleep       func(context.Context, time.Duration) error
+}
+
+func NewRetryingUserStorage(next UserStorage, maxAttempts int, baseDelay, maxDelay time.Duration) *RetryingUserStorage {
+	return &RetryingUserStorage{
+		UserStorage: next,
+		maxAttempts: maxAttempts,
+		baseDelay:   baseDelay,
+		maxDelay:    maxDelay,
+		sleep:       sleepContext,
+	}
+}
+
+func (r *RetryingUserStorage) CreateUser(ctx context.Context, user *User) error {
+	_, err := retry(ctx, r, "create_user", false, func() (struct{}, error) {
+		return struct{}{}, r.UserStorage.CreateUser(ctx, user)
+	})
+	return err
+}
+
+func (r *RetryingUserStorage) GetUser(ctx context.Context, id int64) (*User, error) {
+	return retry(ctx, r, "get_user", true, func() (*User, error) {
+		return r.UserStorage.GetUser(ctx, id)
+	})
+}
+
+func (r *RetryingUserStorage) GetUserByEmail(ctx context.Context, email string) (*User, error) {
+	return retry(ctx, r, "get_user_by_email", true, func() (*User, error) {
+		return r.UserStorage.GetUserByEmail(ctx, email)
+	})
+}
+
+func (r *RetryingUserStorage) GetAll(ctx context.Context, filters Filters) ([]*User, MetaData, error) {
+	var metadata MetaData
+
+	users, err := retry(ctx, r, "get_all", true, func() ([]*User, error) {
+		users, md, err := r.UserStorage.GetAll(ctx, filters)
+		metadata = md
+		return users, err
+	})
+
+	return users, metadata, err
+}
+
+func (r *RetryingUserStorage) SearchUsers(ctx context.Context, query string, filters Filters) ([]*UserMatch, MetaData, error) {
+	var metadata MetaData
+
+	matches, err := retry(ctx, r, "search_users", true, func() ([]*UserMatch, error) {
+		matches, md, err := r.UserStorage.SearchUsers(ctx, query, filters)
+		metadata = md
+		return matches, err
+	})
+
+	return matches, metadata, err
+}
+
+func (r *RetryingUserStorage) GetUserStats(ctx context.Context, filters StatsFilters) (*UserStats, error) {
+	return retry(ctx, r, "get_user_stats", true, func() (*UserStats, error) {
+		return r.UserStorage.GetUserStats(ctx, filters)
+	})
+}
+
+func (r *RetryingUserStorage) DeleteUserById(ctx context.Context, id int64) (*User, error) {
+	return retry(ctx, r, "delete_user", false, func() (*User, error) {
+		return r.UserStorage.DeleteUserById(ctx, id)
+	})
+}
+
+// EraseUser is safe to retry: an erased user is returned unchanged.
+func (r *RetryingUserStorage) EraseUser(ctx context.Context, id int64) (*User, error) {
+	return retry(ctx, r, "erase_user", true, func() (*User, error) {
+		return r.UserStorage.EraseUser(ctx, id)
+	})
+}
+
+func (r *RetryingUserStorage) UpdateUser(ctx context.Context, user *User) error {
+	_, err := retry(ctx, r, "update_user", false, func() (struct{}, error) {
+		return struct{}{}, r.UserStorage.UpdateUser(ctx, user)
+	})
+	return err
+}
+
+func (r *RetryingUserStorage) UpsertUserByEmail(ctx context.Context, user *User) (bool, error) {
+	return retry(ctx, r, "upsert_user_by_email", false, func() (bool, error) {
+		return r.UserStorage.UpsertUserByEmail(ctx, user)
+	})
+}
+
+func (r *RetryingUserStorage) GetUserForUpdate(ctx context.Context, id int64) (*User, error) {
+	return retry(ctx, r, "get_user_for_update", true, func() (*User, error) {
+		return r.UserStorage.GetUserForUpdate(ctx, id)
+	})
+}
+
+// RunInTx retries the whole transaction rather than individual statements: once
+// a statement fails, PostgreSQL aborts the transaction and nothing inside it can
+// be retried on its own.
+func (r *RetryingUserStorage) RunInTx(ctx context.Context, opts *sql.TxOptions, fn func(UserStorage) error) error {
+	_, err := retry(ctx, r, "transaction", false, func() (struct{}, error) {
+		return struct{}{}, r.UserStorage.RunInTx(ctx, opts, fn)
+	})
+	return err
+}
+
+func retry[T any](ctx context.Context, r *RetryingUserStorage, query string, idempotent bool, fn func() (T, error)) (T, error) {
+	for attempt := 1; ; attempt++ {
+		result, err := fn()
+		if err == nil || attempt >= r.maxAttempts || !isRetryable(err, idempotent) {
+			return result, err
+		}
+
+		delay := r.backoff(attempt)
+
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= delay {
+			return result, err
+		}
+
+		dbRetries.WithLabelValues(query).Inc()
+
+		if sleepErr := r.sleep(ctx, delay); sleepErr != nil {
+			return result, err
+		}
+	}
+}
+
+func (r *RetryingUserStorage) backoff(attempt int) time.Duration {
+	ceiling := r.baseDelay << (attempt - 1)
+	if ceiling <= 0 || ceiling > r.maxDelay {
+		ceiling = r.maxDelay
+	}
+
+	if ceiling <= 0 {
+		return 0
+	}
+
+	return rand.N(ceiling) + 1
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+func isRetryable(err error, idempotent bool) bool {
+	if idempotent {
+		return IsTransient(err)
+	}
+	return isNotApplied(err)
+}
+
+func IsTransient(err error) bool {
+	if isNotApplied(err) {
+		return true
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		if pqErr.Code.Class() == "08" {
+			return true
+		}
+
+		switch pqErr.Code.Name() {
+		case "admin_shutdown", "crash_shutdown":
+			return true
+		}
+
+		return false
+	}
+
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) && !errors.Is(err, context.DeadlineExceeded)
+}
+
+func isNotApplied(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code.Name() {
+		case "serialization_failure", "deadlock_detected", "cannot_connect_now", "too_many_connections":
+			return true
+		}
+
+		switch pqErr.Code {
+		case "08001", "08004":
+			return true
+		}
+
+		return false
+	}
+
+	return errors.Is(err, driver.ErrBadConn) || errors.Is(err, syscall.ECONNREFUSED)
+}
@@ -0,0 +1,189 @@
+package data
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type flakyStorage struct {
+	UserStorage
+
+	errs  []error
+	calls int
+}
+
+func (s *flakyStorage) next() error {
+	s.calls++
+	if len(s.errs) == 0 {
+		return nil
+	}
+
+	err := s.errs[0]
+	s.errs = s.errs[1:]
+
+	return err
+}
+
+func (s *flakyStorage) GetUser(ctx context.Context, id int64) (*User, error) {
+	if err := s.next(); err != nil {
+		return nil, err
+	}
+	return &User{ID: id}, nil
+}
+
+func (s *flakyStorage) CreateUser(ctx context.Context, user *User) error {
+	return s.next()
+}
+
+func newTestRetrying(next UserStorage) (*RetryingUserStorage, *[]time.Duration) {
+	r := NewRetryingUserStorage(next, 3, 10*time.Millisecond, 40*time.Millisecond)
+
+	var delays []time.Duration
+	r.sleep = func(ctx context.Context, d time.Duration) error {
+		delays = append(delays, d)
+		return ctx.Err()
+	}
+
+	return r, &delays
+}
+
+func TestRetryingUserStorage_Classification(t *testing.T) {
+	connReset := &net.OpError{Op: "read", Net: "tcp", Err: os.NewSyscallError("read", syscall.ECONNRESET)}
+	serialization := &pq.Error{Code: "40001"}
+	deadlock := &pq.Error{Code: "40P01"}
+	adminShutdown := &pq.Error{Code: "57P01"}
+	uniqueViolation := &pq.Error{Code: "23505"}
+
+	tests := []struct {
+		name      string
+		read      bool
+		errs      []error
+		wantCalls int
+		wantErr   error
+	}{
+		{name: "read retried on connection reset", read: true, errs: []error{connReset, connReset}, wantCalls: 3},
+		{name: "read retried on admin shutdown", read: true, errs: []error{adminShutdown}, wantCalls: 2},
+		{name: "read gives up after max attempts", read: true, errs: []error{connReset, connReset, connReset}, wantCalls: 3, wantErr: connReset},
+		{name: "read not retried on not found", read: true, errs: []error{ErrRecordNotFound}, wantCalls: 1, wantErr: ErrRecordNotFound},
+		{name: "write retried on serialization failure", errs: []error{serialization}, wantCalls: 2},
+		{name: "write retried on deadlock", errs: []error{deadlock}, wantCalls: 2},
+		{name: "write not retried on connection reset", errs: []error{connReset}, wantCalls: 1, wantErr: connReset},
+		{name: "write not retried on admin shutdown", errs: []error{adminShutdown}, wantCalls: 1, wantErr: adminShutdown},
+		{name: "write not retried on constraint violation", errs: []error{uniqueViolation}, wantCalls: 1, wantErr: uniqueViolation},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := &flakyStorage{errs: tt.errs}
+			r, delays := newTestRetrying(backend)
+
+			var err error
+			if tt.read {
+				_, err = r.GetUser(context.Background(), 1)
+			} else {
+				err = r.CreateUser(context.Background(), &User{})
+			}
+
+			assert.Equal(t, tt.wantCalls, backend.calls)
+			assert.Len(t, *delays, tt.wantCalls-1)
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestRetryingUserStorage_Backoff(t *testing.T) {
+	backend := &flakyStorage{errs: []error{driver.ErrBadConn, driver.ErrBadConn}}
+	r, delays := newTestRetrying(backend)
+
+	_, err := r.GetUser(context.Background(), 1)
+	require.NoError(t, err)
+
+	require.Len(t, *delays, 2)
+	assert.LessOrEqual(t, (*delays)[0], 10*time.Millisecond)
+	assert.LessOrEqual(t, (*delays)[1], 20*time.Millisecond)
+
+	for attempt := 1; attempt < 10; attempt++ {
+		d := r.backoff(attempt)
+		assert.Greater(t, d, time.Duration(0))
+		assert.LessOrEqual(t, d, 40*time.Millisecond)
+	}
+}
+
+func TestRetryingUserStorage_RespectsDeadline(t *testing.T) {
+	backend := &flakyStorage{errs: []error{driver.ErrBadConn}}
+	r, delays := newTestRetrying(backend)
+	r.baseDelay = time.Second
+	r.maxDelay = time.Second
+
+	// The backoff is jittered down to a nanosecond, so only a deadline that
+	// has already passed is sure to be shorter.
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now())
+	defer cancel()
+
+	_, err := r.GetUser(ctx, 1)
+
+	assert.Error(t, err)
+	assert.Equal(t, 1, backend.calls)
+	assert.Empty(t, *delays)
+}
+
+func TestIsTransient(t *testing.T) {
+	assert.True(t, IsTransient(&pq.Error{Code: "08006"}))
+	assert.True(t, IsTransient(&pq.Error{Code: "57P03"}))
+	assert.True(t, IsTransient(errors.Join(errors.New("query failed"), syscall.ECONNREFUSED)))
+	assert.False(t, IsTransient(&pq.Error{Code: "22001"}))
+	assert.False(t, IsTransient(ErrEditConflict))
+	assert.False(t, IsTransient(context.DeadlineExceeded))
+}
+
+func TestRetryingUserStorage_Transaction(t *testing.T) {
+	backend := seedMemoryStorage(t)
+	r, delays := newTestRetrying(backend)
+	models := Models{Users: r}
+	ctx := testTenantContext()
+
+	calls := 0
+
+	err := models.WithTx(ctx, func(tx Models) error {
+		calls++
+
+		user, err := tx.Users.GetUserForUpdate(ctx, 1)
+		if err != nil {
+			return err
+		}
+
+		user.Age++
+		if err := tx.Users.UpdateUser(ctx, user); err != nil {
+			return err
+		}
+
+		if calls == 1 {
+			return &pq.Error{Code: "40001"}
+		}
+
+		return nil
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, calls, "the whole transaction must be retried")
+	assert.Len(t, *delays, 1)
+
+	user, err := backend.GetUser(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, int32(32), user.Age, "the failed attempt must have been rolled back")
+}
@@ -0,0 +1,107 @@
+package data
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/Vadim-Makhnev/grpc/internal/validator"
+)
+
+const searchQueryMaxLength = 100
+
+// similarityThreshold is the trigram similarity a field needs to match a query
+// with typos. It is the default of pg_trgm.similarity_threshold.
+const similarityThreshold = 0.3
+
+// UserMatch is a user found by SearchUsers with its relevance to the query.
+// Higher scores are better; they are only comparable within one search.
+type UserMatch struct {
+	User  *User
+	Score float64
+}
+
+func ValidateSearch(v *validator.Validator, query string, f Filters) {
+	validator.Field(v, "query", query,
+		validator.NotBlank(),
+		validator.MaxRunes(searchQueryMaxLength),
+		validator.ValidUTF8(),
+		validator.NoControlChars(),
+	)
+	validatePage(v, f)
+}
+
+// searchWords splits s into lower-case words, treating everything other than
+// letters and digits as a separator.
+func searchWords(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// trigrams returns the trigrams of s the way pg_trgm builds them: every word
+// is padded with two spaces in front and one behind.
+func trigrams(s string) map[string]struct{} {
+	set := make(map[string]struct{})
+
+	for _, word := range searchWords(s) {
+		runes := []rune("  " + word + " ")
+		for i := 0; i+3 <= len(runes); i++ {
+			set[string(runes[i:i+3])] = struct{}{}
+		}
+	}
+
+	return set
+}
+
+// similarity is the share of trigrams that a and b have in common, like
+// pg_trgm's similarity function.
+func similarity(a, b map[string]struct{}) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+
+	shared := 0
+	for t := range a {
+		if _, ok := b[t]; ok {
+			shared++
+		}
+	}
+
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
+
+// matchUser is the in-memory counterpart of the SearchUsers query. A user
+// matches when every query word is one of its words, which stands in for
+// full-text search, or when a field is similar enough to the query to allow
+// for typos. It reports the score and whether the user matched.
+func matchUser(user *User, query string) (float64, bool) {
+	fields := []string{user.Name, user.DisplayName, user.Email}
+
+	words := make(map[string]bool)
+	for _, field := range fields {
+		for _, word := range searchWords(field) {
+			words[word] = true
+		}
+	}
+
+	queryWords := searchWords(query)
+
+	fullText := len(queryWords) > 0
+	for _, word := range queryWords {
+		fullText = fullText && words[word]
+	}
+
+	queryTrigrams := trigrams(query)
+
+	best := 0.0
+	for _, field := range fields {
+		best = max(best, similarity(queryTrigrams, trigrams(field)))
+	}
+
+	score := best
+	if fullText {
+		score++
+	}
+
+	return score, fullText || best >= similarityThreshold
+}
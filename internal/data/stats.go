@@ -0,0 +1,338 @@
+package data
+
+import (
+	"cmp"
+	"container/list"
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Vadim-Makhnev/grpc/internal/i18n"
+	"github.com/Vadim-Makhnev/grpc/internal/validator"
+	"golang.org/x/sync/singleflight"
+)
+
+// Intervals of the signup time series, named after the date_trunc fields.
+const (
+	IntervalDay   = "day"
+	IntervalWeek  = "week"
+	IntervalMonth = "month"
+)
+
+const topDomainsMax = 100
+
+// ageBucketBounds are the lower bounds of every age bucket but the first,
+// which starts at zero.
+var ageBucketBounds = []int32{18, 25, 35, 45, 55, 65}
+
+type StatsFilters struct {
+	// CreatedFrom and CreatedTo limit the users to those created in
+	// [CreatedFrom, CreatedTo). Zero values leave that side open.
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+	Attributes  map[string]string
+
+	Interval   string
+	TopDomains int
+}
+
+// key identifies the filters in the stats cache.
+func (f StatsFilters) key() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "%d|%d|%s|%d", f.CreatedFrom.UnixNano(), f.CreatedTo.UnixNano(), f.Interval, f.TopDomains)
+	for _, key := range slices.Sorted(maps.Keys(f.Attributes)) {
+		fmt.Fprintf(&b, "|%q=%q", key, f.Attributes[key])
+	}
+
+	return b.String()
+}
+
+func (f StatsFilters) matches(user *User) bool {
+	return (f.CreatedFrom.IsZero() || !user.CreatedAt.Before(f.CreatedFrom)) &&
+		(f.CreatedTo.IsZero() || user.CreatedAt.Before(f.CreatedTo)) &&
+		user.Attributes.Contains(f.Attributes)
+}
+
+type UserStats struct {
+	Total       int
+	AgeBuckets  []AgeBucket
+	TopDomains  []DomainCount
+	Signups     []SignupCount
+	GeneratedAt time.Time
+}
+
+type AgeBucket struct {
+	// Range is a label such as "18-24" or "65+".
+	Range string
+	Count int
+}
+
+type DomainCount struct {
+	Domain string
+	Count  int
+}
+
+type SignupCount struct {
+	PeriodStart time.Time
+	Count       int
+}
+
+func ValidateStatsFilters(v *validator.Validator, f StatsFilters) {
+	validator.Field(v, "interval", f.Interval, validator.OneOf(IntervalDay, IntervalWeek, IntervalMonth))
+	validator.Field(v, "top_domains", f.TopDomains, validator.Between(1, topDomainsMax))
+
+	if !f.CreatedFrom.IsZero() && !f.CreatedTo.IsZero() {
+		if !f.CreatedTo.After(f.CreatedFrom) {
+			v.Add("created_to", i18n.New(i18n.ReasonNotAfter, "created_from"))
+		}
+	}
+
+	validateAttributes(v, "attributes", f.Attributes)
+}
+
+// newAgeBuckets returns the empty age buckets. counts[i] is added to the i-th
+// bucket, the way width_bucket numbers them.
+func newAgeBuckets(counts map[int]int) []AgeBucket {
+	buckets := make([]AgeBucket, len(ageBucketBounds)+1)
+
+	lower := int32(0)
+	for i, upper := range ageBucketBounds {
+		buckets[i] = AgeBucket{Range: fmt.Sprintf("%d-%d", lower, upper-1), Count: counts[i]}
+		lower = upper
+	}
+
+	buckets[len(ageBucketBounds)] = AgeBucket{Range: strconv.Itoa(int(lower)) + "+", Count: counts[len(ageBucketBounds)]}
+
+	return buckets
+}
+
+// ageBucket returns the index of the bucket that age falls into.
+func ageBucket(age int32) int {
+	i, found := slices.BinarySearch(ageBucketBounds, age)
+	if found {
+		i++
+	}
+	return i
+}
+
+// truncate returns the start of the interval t falls into, in UTC. Weeks start
+// on Monday, like date_trunc('week', ...).
+func truncate(t time.Time, interval string) time.Time {
+	day := DateOf(t)
+
+	switch interval {
+	case IntervalWeek:
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	case IntervalMonth:
+		return day.AddDate(0, 0, 1-day.Day())
+	default:
+		return day
+	}
+}
+
+func nextPeriod(t time.Time, interval string) time.Time {
+	switch interval {
+	case IntervalWeek:
+		return t.AddDate(0, 0, 7)
+	case IntervalMonth:
+		return t.AddDate(0, 1, 0)
+	default:
+		return t.AddDate(0, 0, 1)
+	}
+}
+
+// fillSignupGaps adds zero counts for the periods without signups between the
+// first and the last one, so the series has one point per period.
+func fillSignupGaps(signups []SignupCount, interval string) []SignupCount {
+	if len(signups) == 0 {
+		return []SignupCount{}
+	}
+
+	filled := []SignupCount{signups[0]}
+
+	for _, signup := range signups[1:] {
+		for period := nextPeriod(filled[len(filled)-1].PeriodStart, interval); period.Before(signup.PeriodStart); period = nextPeriod(period, interval) {
+			filled = append(filled, SignupCount{PeriodStart: period})
+		}
+		filled = append(filled, signup)
+	}
+
+	return filled
+}
+
+// emailDomain returns the domain of email in lower case, like the domain
+// query of UserModel.
+func emailDomain(email string) (string, bool) {
+	at := strings.LastIndexByte(email, '@')
+	if at < 0 {
+		return "", false
+	}
+	return strings.ToLower(email[at+1:]), true
+}
+
+// topDomains returns the n domains with the most users, the most common first
+// and ties in alphabetical order.
+func topDomains(domains map[string]int, n int) []DomainCount {
+	top := []DomainCount{}
+	for domain, count := range domains {
+		top = append(top, DomainCount{Domain: domain, Count: count})
+	}
+
+	slices.SortFunc(top, func(a, b DomainCount) int {
+		if c := cmp.Compare(b.Count, a.Count); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Domain, b.Domain)
+	})
+
+	return top[:min(len(top), n)]
+}
+
+// computeStats builds UserStats from users that already match the filters. It
+// is the in-memory counterpart of the aggregate queries of UserModel.
+func computeStats(users []*User, f StatsFilters, now time.Time) *UserStats {
+	ages := map[int]int{}
+	domains := map[string]int{}
+	signups := map[time.Time]int{}
+
+	for _, user := range users {
+		ages[ageBucket(AgeOn(user.Birthdate, DateOf(now)))]++
+
+		if domain, ok := emailDomain(user.Email); ok {
+			domains[domain]++
+		}
+
+		signups[truncate(user.CreatedAt, f.Interval)]++
+	}
+
+	stats := &UserStats{
+		Total:       len(users),
+		AgeBuckets:  newAgeBuckets(ages),
+		TopDomains:  topDomains(domains, f.TopDomains),
+		GeneratedAt: now,
+	}
+
+	var series []SignupCount
+	for _, period := range slices.SortedFunc(maps.Keys(signups), time.Time.Compare) {
+		series = append(series, SignupCount{PeriodStart: period, Count: signups[period]})
+	}
+
+	stats.Signups = fillSignupGaps(series, f.Interval)
+
+	return stats
+}
+
+// StatsCachingUserStorage keeps the results of GetUserStats for a fixed time.
+// Statistics are allowed to lag behind writes by up to the ttl, so nothing
+// invalidates them early. Clients choose the filters, and with them the
+// keys, so at most size entries are kept and the least recently used one is
+// evicted first.
+type StatsCachingUserStorage struct {
+	UserStorage
+
+	size int
+	ttl  time.Duration
+	now  func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+
+	group singleflight.Group
+}
+
+type statsEntry struct {
+	key     string
+	stats   UserStats
+	expires time.Time
+}
+
+func NewStatsCachingUserStorage(next UserStorage, size int, ttl time.Duration) *StatsCachingUserStorage {
+	return &StatsCachingUserStorage{
+		UserStorage: next,
+		size:        size,
+		ttl:         ttl,
+		now:         time.Now,
+		entries:     make(map[string]*list.Element),
+		lru:         list.New(),
+	}
+}
+
+func (s *StatsCachingUserStorage) GetUserStats(ctx context.Context, filters StatsFilters) (*UserStats, error) {
+	tenant, ok := TenantFromContext(ctx)
+	if !ok {
+		return s.UserStorage.GetUserStats(ctx, filters)
+	}
+
+	key := tenant + "/" + filters.key()
+
+	if stats, ok := s.get(key); ok {
+		statsCacheLookups.WithLabelValues("hit").Inc()
+		return stats, nil
+	}
+
+	statsCacheLookups.WithLabelValues("miss").Inc()
+
+	stats, err := sharedLoad(ctx, &s.group, key, func(ctx context.Context) (UserStats, error) {
+		stats, err := s.UserStorage.GetUserStats(ctx, filters)
+		if err != nil {
+			return UserStats{}, err
+		}
+
+		s.put(key, stats)
+
+		return *stats, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &stats, nil
+}
+
+func (s *StatsCachingUserStorage) get(key string) (*UserStats, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	elem, ok := s.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	entry := elem.Value.(*statsEntry)
+	if s.now().After(entry.expires) {
+		s.remove(elem)
+		return nil, false
+	}
+
+	s.lru.MoveToFront(elem)
+
+	stats := entry.stats
+	return &stats, true
+}
+
+func (s *StatsCachingUserStorage) put(key string, stats *UserStats) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if elem, ok := s.entries[key]; ok {
+		s.remove(elem)
+	}
+
+	s.entries[key] = s.lru.PushFront(&statsEntry{key: key, stats: *stats, expires: s.now().Add(s.ttl)})
+
+	for s.lru.Len() > s.size {
+		s.remove(s.lru.Back())
+	}
+}
+
+func (s *StatsCachingUserStorage) remove(elem *list.Element) {
+	s.lru.Remove(elem)
+	delete(s.entries, elem.Value.(*statsEntry).key)
+}
@@ -0,0 +1,137 @@
+package data
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Vadim-Makhnev/grpc/internal/validator"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type statsStorage struct {
+	UserStorage
+
+	calls   atomic.Int32
+	release chan struct{}
+}
+
+func (s *statsStorage) GetUserStats(ctx context.Context, filters StatsFilters) (*UserStats, error) {
+	if s.release != nil {
+		<-s.release
+	}
+	return &UserStats{Total: int(s.calls.Add(1))}, nil
+}
+
+func TestStatsCachingUserStorage(t *testing.T) {
+	backend := &statsStorage{}
+	cache := NewStatsCachingUserStorage(backend, 10, time.Minute)
+	ctx := testTenantContext()
+
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	filters := StatsFilters{Interval: IntervalDay, TopDomains: 10}
+
+	stats, err := cache.GetUserStats(ctx, filters)
+	require.NoError(t, err)
+	assert.Equal(t, 1, stats.Total)
+
+	now = now.Add(30 * time.Second)
+	stats, err = cache.GetUserStats(ctx, filters)
+	require.NoError(t, err)
+	assert.Equal(t, 1, stats.Total, "cached")
+
+	stats, err = cache.GetUserStats(ctx, StatsFilters{Interval: IntervalWeek, TopDomains: 10})
+	require.NoError(t, err)
+	assert.Equal(t, 2, stats.Total, "other filters are cached separately")
+
+	stats, err = cache.GetUserStats(ContextWithTenant(context.Background(), "other"), filters)
+	require.NoError(t, err)
+	assert.Equal(t, 3, stats.Total, "tenants are cached separately")
+
+	now = now.Add(time.Minute)
+	stats, err = cache.GetUserStats(ctx, filters)
+	require.NoError(t, err)
+	assert.Equal(t, 4, stats.Total, "expired")
+}
+
+func TestStatsCachingUserStorage_Eviction(t *testing.T) {
+	backend := &statsStorage{}
+	cache := NewStatsCachingUserStorage(backend, 2, time.Minute)
+	ctx := testTenantContext()
+
+	day := StatsFilters{Interval: IntervalDay, TopDomains: 10}
+	week := StatsFilters{Interval: IntervalWeek, TopDomains: 10}
+	month := StatsFilters{Interval: IntervalMonth, TopDomains: 10}
+
+	for _, filters := range []StatsFilters{day, week, day, month} {
+		_, err := cache.GetUserStats(ctx, filters)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, int32(3), backend.calls.Load())
+	assert.Len(t, cache.entries, 2)
+
+	stats, err := cache.GetUserStats(ctx, day)
+	require.NoError(t, err)
+	assert.Equal(t, 1, stats.Total, "recently used")
+
+	stats, err = cache.GetUserStats(ctx, week)
+	require.NoError(t, err)
+	assert.Equal(t, 4, stats.Total, "evicted")
+}
+
+func TestStatsCachingUserStorage_WaiterGivesUp(t *testing.T) {
+	backend := &statsStorage{release: make(chan struct{})}
+	cache := NewStatsCachingUserStorage(backend, 10, time.Minute)
+	filters := StatsFilters{Interval: IntervalDay, TopDomains: 10}
+
+	ctx, cancel := context.WithTimeout(testTenantContext(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := cache.GetUserStats(ctx, filters)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// The load goes on for the callers still waiting for it.
+	close(backend.release)
+	require.Eventually(t, func() bool {
+		_, ok := cache.get("acme/" + filters.key())
+		return ok
+	}, time.Second, time.Millisecond)
+
+	stats, err := cache.GetUserStats(testTenantContext(), filters)
+	require.NoError(t, err)
+	assert.Equal(t, 1, stats.Total)
+}
+
+func Test_ValidateStatsFilters(t *testing.T) {
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		filters StatsFilters
+		want    []string
+	}{
+		{"valid", StatsFilters{CreatedFrom: from, CreatedTo: from.AddDate(0, 1, 0), Interval: IntervalMonth, TopDomains: 10}, nil},
+		{"open range", StatsFilters{CreatedTo: from, Interval: IntervalDay, TopDomains: 1}, nil},
+		{"bad interval", StatsFilters{Interval: "hour", TopDomains: 10}, []string{"interval"}},
+		{"too many domains", StatsFilters{Interval: IntervalDay, TopDomains: 101}, []string{"top_domains"}},
+		{"empty range", StatsFilters{CreatedFrom: from, CreatedTo: from, Interval: IntervalDay, TopDomains: 10}, []string{"created_to"}},
+		{"bad attribute", StatsFilters{Interval: IntervalDay, TopDomains: 10, Attributes: map[string]string{"Dept": "x"}}, []string{"attributes.Dept"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validator.New()
+			ValidateStatsFilters(v, tt.filters)
+
+			var fields []string
+			for field := range v.Errors {
+				fields = append(fields, field)
+			}
+			assert.ElementsMatch(t, tt.want, fields)
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+
+	"github.com/Vadim-Makhnev/grpc/internal/i18n"
+	"github.com/Vadim-Makhnev/grpc/internal/validator"
+)
+
+var ErrMissingTenant = errors.New("missing tenant")
+
+var TenantRX = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,62}$`)
+
+type tenantContextKey struct{}
+
+func ContextWithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantContextKey{}, tenant)
+}
+
+func TenantFromContext(ctx context.Context) (string, bool) {
+	tenant, ok := ctx.Value(tenantContextKey{}).(string)
+	return tenant, ok && tenant != ""
+}
+
+func ValidateTenant(v *validator.Validator, key, tenant string) {
+	validator.Field(v, key, tenant,
+		validator.Required[string](),
+		validator.Match(TenantRX, i18n.New(i18n.ReasonInvalidTenant)),
+	)
+}
+
+// setTenant makes the tenant visible to the row-level security policies for
+// the rest of the transaction.
+func setTenant(ctx context.Context, tx *sql.Tx, tenant string) error {
+	_, err := tx.ExecContext(ctx, `SELECT set_config('app.tenant_id', $1, true)`, tenant)
+	return err
+}
@@ -0,0 +1,47 @@
+package data
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/Vadim-Makhnev/grpc/internal/data")
+
+type querySpan struct {
+	span  trace.Span
+	timer *prometheus.Timer
+}
+
+func startQuery(ctx context.Context, name string) (context.Context, *querySpan) {
+	ctx, span := tracer.Start(ctx, "UserModel."+name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNamePostgreSQL,
+			semconv.DBOperationName(name),
+		),
+	)
+
+	return ctx, &querySpan{
+		span:  span,
+		timer: prometheus.NewTimer(queryDuration.WithLabelValues(name)),
+	}
+}
+
+func (q *querySpan) rows(n int) {
+	q.span.SetAttributes(semconv.DBResponseReturnedRows(n))
+}
+
+func (q *querySpan) fail(err error) {
+	q.span.RecordError(err)
+	q.span.SetStatus(codes.Error, err.Error())
+}
+
+func (q *querySpan) end() {
+	q.timer.ObserveDuration()
+	q.span.End()
+}
@@ -0,0 +1,50 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+)
+
+func Test_startQuery(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(tp)
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+
+	parentCtx, parent := tp.Tracer("test").Start(context.Background(), "rpc")
+
+	_, span := startQuery(parentCtx, "get_all")
+	span.rows(3)
+	span.end()
+
+	_, span = startQuery(parentCtx, "update_user")
+	span.fail(errors.New("connection reset"))
+	span.end()
+
+	parent.End()
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 3)
+
+	getAll := spans[0]
+	assert.Equal(t, "UserModel.get_all", getAll.Name)
+	assert.Equal(t, parent.SpanContext().SpanID(), getAll.Parent.SpanID())
+	assert.Contains(t, getAll.Attributes, semconv.DBOperationName("get_all"))
+	assert.Contains(t, getAll.Attributes, semconv.DBResponseReturnedRows(3))
+
+	update := spans[1]
+	assert.Equal(t, "UserModel.update_user", update.Name)
+	assert.Equal(t, codes.Error, update.Status.Code)
+	assert.Equal(t, "connection reset", update.Status.Description)
+}
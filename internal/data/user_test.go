@@ -1,8 +1,12 @@
 package data
 
 import (
+	"fmt"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/Vadim-Makhnev/grpc/internal/i18n"
 	"github.com/Vadim-Makhnev/grpc/internal/validator"
 	"github.com/stretchr/testify/assert"
 )
@@ -66,6 +70,50 @@ func Test_ValidateUser(t *testing.T) {
 				"email": "must be provided",
 			},
 		},
+		{
+			name: "name too long",
+			user: &User{
+				Name:  strings.Repeat("Андрей", 10),
+				Email: "andrew@google.com",
+				Age:   31,
+			},
+			wantErrs: map[string]string{
+				"name": "must not be more than 50 characters long",
+			},
+		},
+		{
+			name: "blank name",
+			user: &User{
+				Name:  "   ",
+				Email: "andrew@google.com",
+				Age:   31,
+			},
+			wantErrs: map[string]string{
+				"name": "must not be blank",
+			},
+		},
+		{
+			name: "name with control characters",
+			user: &User{
+				Name:  "Andrew\r\nBcc: x",
+				Email: "andrew@google.com",
+				Age:   31,
+			},
+			wantErrs: map[string]string{
+				"name": "must not contain control characters",
+			},
+		},
+		{
+			name: "email too long",
+			user: &User{
+				Name:  "Andrew",
+				Email: strings.Repeat("a", 40) + "@google.com",
+				Age:   31,
+			},
+			wantErrs: map[string]string{
+				"email": "must not be more than 50 characters long",
+			},
+		},
 		{
 			name: "multiple errors",
 			user: &User{},
@@ -91,3 +139,178 @@ func Test_ValidateUser(t *testing.T) {
 		})
 	}
 }
+
+func Test_NormalizeEmail(t *testing.T) {
+	tests := []struct {
+		email string
+		want  string
+	}{
+		{email: "andrew@google.com", want: "andrew@google.com"},
+		{email: "  Bob@Example.COM\t", want: "Bob@example.com"},
+		{email: "info@Bücher.de", want: "info@xn--bcher-kva.de"},
+		{email: `"a@b"@Example.com`, want: `"a@b"@example.com`},
+		{email: "andrew.com", want: "andrew.com"},
+		{email: "andrew@", want: "andrew@"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.email, func(t *testing.T) {
+			assert.Equal(t, tt.want, NormalizeEmail(tt.email))
+		})
+	}
+}
+
+func Test_ValidateUser_NormalizesEmail(t *testing.T) {
+	user := &User{Name: "Andrew", Email: " Andrew@Bücher.DE ", Age: 31}
+
+	v := validator.New()
+	ValidateUser(v, user)
+
+	assert.True(t, v.Valid())
+	assert.Equal(t, "Andrew@xn--bcher-kva.de", user.Email)
+}
+
+func Test_ValidateUser_MultipleErrorsPerField(t *testing.T) {
+	user := &User{Name: "Andrew", Email: strings.Repeat("a", 50) + ".com", Age: 31}
+
+	v := validator.New()
+	ValidateUser(v, user)
+
+	assert.Equal(t, []i18n.Message{
+		i18n.New(i18n.ReasonTooLong, emailMaxLength),
+		i18n.New(i18n.ReasonInvalidEmail),
+	}, v.FieldErrors["email"])
+}
+
+func Test_ValidateFilters(t *testing.T) {
+	v := validator.New()
+	ValidateFilters(v, Filters{Page: 0, PageSize: 101, Sort: "password", SortSafelist: []string{"id", "-id"}})
+
+	assert.Equal(t, map[string]string{
+		"page":      "must be greater than zero",
+		"page_size": "must be a maximum of 100",
+		"sort":      "invalid sort value",
+	}, v.Errors)
+}
+
+func Test_ValidateUser_Profile(t *testing.T) {
+	user := &User{
+		Name:      "Andrew",
+		Email:     "andrew@google.com",
+		Phone:     "+1 (415) 555-0123",
+		Locale:    "en-us",
+		TimeZone:  "America/Los_Angeles",
+		Birthdate: time.Date(1994, time.March, 15, 0, 0, 0, 0, time.UTC),
+		Age:       99,
+	}
+
+	v := validator.New()
+	ValidateUser(v, user)
+
+	assert.True(t, v.Valid())
+	assert.Equal(t, "+14155550123", user.Phone)
+	assert.Equal(t, "en-US", user.Locale)
+	assert.Equal(t, AgeOn(user.Birthdate, Today()), user.Age, "age must follow the birthdate")
+
+	user = &User{
+		Name:      "Andrew",
+		Email:     "andrew@google.com",
+		Phone:     "4155550123",
+		Locale:    "not a locale",
+		TimeZone:  "Mars/Olympus_Mons",
+		Birthdate: Today().AddDate(0, 0, 1),
+	}
+
+	v = validator.New()
+	ValidateUser(v, user)
+
+	assert.Equal(t, map[string]string{
+		"phone":     "must be a phone number in international format, e.g. +14155550123",
+		"locale":    "must be a valid BCP 47 language tag, e.g. en-US",
+		"time_zone": "must be a valid IANA time zone, e.g. Europe/Moscow",
+		"birthdate": "must not be in the future",
+	}, v.Errors)
+}
+
+func Test_ValidateUser_BirthdateFromAge(t *testing.T) {
+	user := &User{Name: "Andrew", Email: "andrew@google.com", Age: 31}
+
+	v := validator.New()
+	ValidateUser(v, user)
+
+	assert.True(t, v.Valid())
+	assert.Equal(t, int32(31), AgeOn(user.Birthdate, Today()))
+}
+
+func Test_AgeOn(t *testing.T) {
+	birthdate := time.Date(1994, time.March, 15, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		on   time.Time
+		want int32
+	}{
+		{on: time.Date(2025, time.March, 14, 0, 0, 0, 0, time.UTC), want: 30},
+		{on: time.Date(2025, time.March, 15, 0, 0, 0, 0, time.UTC), want: 31},
+		{on: time.Date(2025, time.December, 31, 0, 0, 0, 0, time.UTC), want: 31},
+		{on: time.Date(1993, time.January, 1, 0, 0, 0, 0, time.UTC), want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.on.Format(time.DateOnly), func(t *testing.T) {
+			assert.Equal(t, tt.want, AgeOn(birthdate, tt.on))
+		})
+	}
+}
+
+func Test_Attributes_Merge(t *testing.T) {
+	attrs := Attributes{"department": "sales", "team": "core"}
+
+	merged := attrs.Merge(map[string]string{"team": "", "cost_center": "42"})
+
+	assert.Equal(t, Attributes{"department": "sales", "cost_center": "42"}, merged)
+	assert.Equal(t, Attributes{"department": "sales", "team": "core"}, attrs, "the original must not change")
+	assert.Equal(t, Attributes{}, Attributes(nil).Merge(map[string]string{"team": ""}))
+}
+
+func Test_ValidateUser_Attributes(t *testing.T) {
+	attrs := Attributes{
+		"department":  "sales",
+		"Cost Center": "42",
+		"note":        strings.Repeat("x", attributeValueMaxLength+1),
+	}
+
+	user := &User{Name: "Andrew", Email: "andrew@google.com", Age: 31, Attributes: attrs}
+
+	v := validator.New()
+	ValidateUser(v, user)
+
+	assert.Equal(t, map[string]string{
+		"attributes.Cost Center": "must be a key of lowercase letters, digits, '_', '.' or '-' starting with a letter",
+		"attributes.note":        "must not be more than 256 characters long",
+	}, v.Errors)
+
+	for i := range attributesMaxKeys {
+		attrs[fmt.Sprintf("key%d", i)] = "value"
+	}
+
+	v = validator.New()
+	ValidateUser(v, user)
+
+	assert.Equal(t, "must not have more than 32 entries", v.Errors["attributes"])
+}
+
+func Test_ValidateFilters_Attributes(t *testing.T) {
+	v := validator.New()
+	ValidateFilters(v, Filters{
+		Page:          1,
+		PageSize:      20,
+		Sort:          "id",
+		SortSafelist:  []string{"id"},
+		Attributes:    map[string]string{"department": "sales"},
+		HasAttributes: []string{"cost_center", "Team"},
+	})
+
+	assert.Equal(t, map[string]string{
+		"has_attributes[1]": "must be a key of lowercase letters, digits, '_', '.' or '-' starting with a letter",
+	}, v.Errors)
+}
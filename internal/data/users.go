@@ -5,191 +5,841 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/Vadim-Makhnev/grpc/internal/encryption"
+	"github.com/Vadim-Makhnev/grpc/internal/i18n"
 	"github.com/Vadim-Makhnev/grpc/internal/validator"
+	"github.com/lib/pq"
+)
+
+// Limits of the VARCHAR columns in the users table, counted in characters.
+const (
+	nameMaxLength  = 50
+	emailMaxLength = 50
 )
 
 type User struct {
-	ID        int64
-	Name      string
-	Email     string
+	ID          int64
+	TenantID    string
+	Name        string
+	DisplayName string
+	Email       string
+	Phone       string
+	Locale      string
+	TimeZone    string
+	Birthdate   time.Time
+	Attributes  Attributes
+	// Age is derived from Birthdate whenever a user is read or validated. On
+	// input it is only used when Birthdate is unset, for clients that predate
+	// birthdates.
 	Age       int32
 	CreatedAt time.Time
-	Version   int32
+	UpdatedAt time.Time
+	// ErasedAt is set once EraseUser has anonymized the user.
+	ErasedAt time.Time
+	Version  int32
+}
+
+// userColumns lists the columns scanned by scanUser, in order.
+const userColumns = `id, tenant_id, name, display_name, email, phone, locale, time_zone, birthdate, attributes, created_at, updated_at, erased_at, name_encrypted, email_encrypted, version`
+
+// scanUser scans userColumns, preceded by any extra destinations, into a new
+// User, decrypting the name and email if needed, and computes its age.
+func (u UserModel) scanUser(ctx context.Context, row interface{ Scan(...any) error }, extra ...any) (*User, error) {
+	var (
+		user           User
+		name, email    sql.NullString
+		nameEncrypted  []byte
+		emailEncrypted []byte
+		erasedAt       sql.NullTime
+	)
+
+	dest := append(extra,
+		&user.ID,
+		&user.TenantID,
+		&name,
+		&user.DisplayName,
+		&email,
+		&user.Phone,
+		&user.Locale,
+		&user.TimeZone,
+		&user.Birthdate,
+		&user.Attributes,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+		&erasedAt,
+		&nameEncrypted,
+		&emailEncrypted,
+		&user.Version,
+	)
+
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+
+	var err error
+
+	if user.Name, err = u.open(ctx, user.TenantID, "name", name, nameEncrypted); err != nil {
+		return nil, err
+	}
+
+	if user.Email, err = u.open(ctx, user.TenantID, "email", email, emailEncrypted); err != nil {
+		return nil, err
+	}
+
+	user.ErasedAt = erasedAt.Time
+	user.Birthdate = DateOf(user.Birthdate)
+	user.Age = AgeOn(user.Birthdate, Today())
+
+	return &user, nil
 }
 
 type UserModel struct {
-	DB *sql.DB
+	DB               DBTX
+	RowLevelSecurity bool
+	// Cipher encrypts names and emails at rest when set. Users stored in
+	// plaintext stay readable either way.
+	Cipher *encryption.Cipher
+}
+
+func (u UserModel) RunInTx(ctx context.Context, opts *sql.TxOptions, fn func(UserStorage) error) error {
+	db, ok := u.DB.(*sql.DB)
+	if !ok {
+		return fn(u)
+	}
+
+	tenant, ok := TenantFromContext(ctx)
+	if !ok {
+		return ErrMissingTenant
+	}
+
+	ctx, span := tracer.Start(ctx, "UserModel.transaction")
+	defer span.End()
+
+	tx, err := db.BeginTx(ctx, opts)
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	if u.RowLevelSecurity {
+		if err := setTenant(ctx, tx, tenant); err != nil {
+			tx.Rollback()
+			span.RecordError(err)
+			return err
+		}
+	}
+
+	if err := fn(UserModel{DB: tx, RowLevelSecurity: u.RowLevelSecurity, Cipher: u.Cipher}); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+			return errors.Join(err, rbErr)
+		}
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		span.RecordError(err)
+		return err
+	}
+
+	return nil
+}
+
+// withTenant calls fn with the tenant from ctx. With row-level security
+// enabled, a statement outside of RunInTx gets its own transaction so the
+// tenant setting can't leak to other users of the pooled connection.
+func (u UserModel) withTenant(ctx context.Context, fn func(db DBTX, tenant string) error) error {
+	tenant, ok := TenantFromContext(ctx)
+	if !ok {
+		return ErrMissingTenant
+	}
+
+	db, ok := u.DB.(*sql.DB)
+	if !u.RowLevelSecurity || !ok {
+		return fn(u.DB, tenant)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := setTenant(ctx, tx, tenant); err != nil {
+		return err
+	}
+
+	if err := fn(tx, tenant); err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
-func (u UserModel) CreateUser(user *User) error {
+func (u UserModel) CreateUser(ctx context.Context, user *User) error {
+	ctx, span := startQuery(ctx, "create_user")
+	defer span.end()
+
 	query := `
-		INSERT INTO users (name, email, age)
-		VALUES ($1, $2, $3)
-		RETURNING id, created_at, version
+		INSERT INTO users (tenant_id, display_name, phone, locale, time_zone, birthdate, attributes, ` + storedColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+		RETURNING id, created_at, updated_at, version
 		`
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
-	args := []any{user.Name, user.Email, user.Age}
+	err := u.withTenant(ctx, func(db DBTX, tenant string) error {
+		fields, err := u.seal(ctx, tenant, user.Name, user.Email)
+		if err != nil {
+			return err
+		}
+
+		if err := u.claimEmail(ctx, db, tenant, user.Email, fields.emailHash); err != nil {
+			return err
+		}
+
+		args := append([]any{tenant, user.DisplayName, user.Phone, user.Locale, user.TimeZone, user.Birthdate, user.Attributes}, fields.args()...)
+
+		err = db.QueryRowContext(ctx, query, args...).Scan(&user.ID, &user.CreatedAt, &user.UpdatedAt, &user.Version)
+		if err == nil {
+			user.TenantID = tenant
+		}
+		return err
+	})
+	if err != nil {
+		switch {
+		case isDuplicateEmail(err):
+			span.rows(0)
+			return ErrDuplicateEmail
+		default:
+			span.fail(err)
+			return err
+		}
+	}
+
+	span.rows(1)
 
-	return u.DB.QueryRowContext(ctx, query, args...).Scan(&user.ID, &user.CreatedAt, &user.Version)
+	return nil
 }
 
-func (u UserModel) GetUser(id int64) (*User, error) {
-	if id < 1 {
-		return nil, ErrInvalidArgument
+func (u UserModel) UpsertUserByEmail(ctx context.Context, user *User) (bool, error) {
+	ctx, span := startQuery(ctx, "upsert_user_by_email")
+	defer span.end()
+
+	// The email is matched through its blind index once it is encrypted.
+	conflict := "(tenant_id, lower(email))"
+	if u.Cipher != nil {
+		conflict = "(tenant_id, email_hash)"
+	}
+
+	query := `
+		INSERT INTO users (tenant_id, display_name, phone, locale, time_zone, birthdate, attributes, ` + storedColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+		ON CONFLICT ` + conflict + ` DO UPDATE
+		SET display_name = EXCLUDED.display_name, phone = EXCLUDED.phone,
+			locale = EXCLUDED.locale, time_zone = EXCLUDED.time_zone, birthdate = EXCLUDED.birthdate,
+			attributes = users.attributes || EXCLUDED.attributes,
+			(` + storedColumns + `) = (EXCLUDED.name, EXCLUDED.email, EXCLUDED.name_encrypted,
+				EXCLUDED.email_encrypted, EXCLUDED.email_hash, EXCLUDED.email_domain, EXCLUDED.encryption_key_version),
+			version = users.version + 1
+		WHERE users.erased_at IS NULL
+		RETURNING id, attributes, created_at, updated_at, version, (xmax = 0) AS inserted`
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var inserted bool
+
+	err := u.withTenant(ctx, func(db DBTX, tenant string) error {
+		fields, err := u.seal(ctx, tenant, user.Name, user.Email)
+		if err != nil {
+			return err
+		}
+
+		// A plaintext user with this email has to be found by the conflict
+		// target, and be updated instead of duplicated.
+		if err := u.claimEmail(ctx, db, tenant, user.Email, fields.emailHash); err != nil {
+			return err
+		}
+
+		args := append([]any{tenant, user.DisplayName, user.Phone, user.Locale, user.TimeZone, user.Birthdate, user.Attributes}, fields.args()...)
+
+		err = db.QueryRowContext(ctx, query, args...).Scan(&user.ID, &user.Attributes, &user.CreatedAt, &user.UpdatedAt, &user.Version, &inserted)
+		if err == nil {
+			user.TenantID = tenant
+		}
+		return err
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			// The email belongs to an erased user, which is never updated.
+			span.rows(0)
+			return false, ErrEditConflict
+		case isDuplicateEmail(err):
+			span.rows(0)
+			return false, ErrDuplicateEmail
+		default:
+			span.fail(err)
+			return false, err
+		}
 	}
 
+	span.rows(1)
+
+	return inserted, nil
+}
+
+func (u UserModel) GetUser(ctx context.Context, id int64) (*User, error) {
+	query := `
+		SELECT ` + userColumns + `
+		FROM users
+		WHERE id = $1 AND tenant_id = $2
+		`
+	return u.getUser(ctx, "get_user", query, id)
+}
+
+func (u UserModel) GetUserByEmail(ctx context.Context, email string) (*User, error) {
+	ctx, span := startQuery(ctx, "get_user_by_email")
+	defer span.end()
+
 	query := `
-		SELECT id, name, email, age, created_at, version
+		SELECT ` + userColumns + `
 		FROM users
-		WHERE id = $1
+		WHERE tenant_id = $1 AND (lower(email) = lower($2) OR email_hash = $3)
 		`
-	var user User
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	var user *User
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
-	err := u.DB.QueryRowContext(ctx, query, id).Scan(
-		&user.ID,
-		&user.Name,
-		&user.Email,
-		&user.Age,
-		&user.CreatedAt,
-		&user.Version,
-	)
+	err := u.withTenant(ctx, func(db DBTX, tenant string) error {
+		hash, err := u.emailHash(ctx, email)
+		if err != nil {
+			return err
+		}
+
+		user, err = u.scanUser(ctx, db.QueryRowContext(ctx, query, tenant, email, nullBytes(hash)))
+		return err
+	})
 
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
+			span.rows(0)
 			return nil, ErrRecordNotFound
 		default:
+			span.fail(err)
 			return nil, err
 		}
 	}
 
-	return &user, nil
+	span.rows(1)
+
+	return user, nil
+}
+
+func (u UserModel) GetUserForUpdate(ctx context.Context, id int64) (*User, error) {
+	query := `
+		SELECT ` + userColumns + `
+		FROM users
+		WHERE id = $1 AND tenant_id = $2
+		FOR UPDATE
+		`
+	return u.getUser(ctx, "get_user_for_update", query, id)
+}
+
+func (u UserModel) getUser(ctx context.Context, name, query string, id int64) (*User, error) {
+	if id < 1 {
+		return nil, ErrInvalidArgument
+	}
+
+	ctx, span := startQuery(ctx, name)
+	defer span.end()
+
+	var user *User
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	err := u.withTenant(ctx, func(db DBTX, tenant string) (err error) {
+		user, err = u.scanUser(ctx, db.QueryRowContext(ctx, query, id, tenant))
+		return err
+	})
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			span.rows(0)
+			return nil, ErrRecordNotFound
+		default:
+			span.fail(err)
+			return nil, err
+		}
+	}
+
+	span.rows(1)
 
+	return user, nil
 }
 
-func (u UserModel) GetAll(filters Filters) ([]*User, MetaData, error) {
+// CanSortBy reports false for name and email with a Cipher: the database
+// can't order encrypted values, nor a mix of encrypted and plaintext ones.
+func (u UserModel) CanSortBy(column string) bool {
+	return u.Cipher == nil || (column != "name" && column != "email")
+}
+
+func (u UserModel) GetAll(ctx context.Context, filters Filters) ([]*User, MetaData, error) {
+	column, direction := filters.sortColumn(), filters.sortDirection()
+
+	if !u.CanSortBy(column) {
+		return nil, MetaData{}, ErrInvalidArgument
+	}
+
+	ctx, span := startQuery(ctx, "get_all")
+	defer span.end()
+
+	// Age is not stored: the oldest users have the earliest birthdates.
+	if column == "age" {
+		column, direction = "birthdate", "DESC"
+		if filters.sortDirection() == "DESC" {
+			direction = "ASC"
+		}
+	}
+
 	query := fmt.Sprintf(`
-		SELECT count(*) OVER(), id, name, email, age, created_at, version
+		SELECT count(*) OVER(), `+userColumns+`
 		FROM users
+		WHERE tenant_id = $1 AND attributes @> $2::jsonb AND attributes ?& $3::text[]
 		ORDER BY %s %s, id ASC
-		LIMIT $1 OFFSET $2`, filters.sortColumn(), filters.sortDirection())
+		LIMIT $4 OFFSET $5`, column, direction)
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
-	args := []any{filters.limit(), filters.offset()}
+	totalRecords := 0
+
+	users := []*User{}
+
+	err := u.withTenant(ctx, func(db DBTX, tenant string) error {
+		args := []any{tenant, Attributes(filters.Attributes), pq.StringArray(filters.hasAttributes()), filters.limit(), filters.offset()}
+
+		rows, err := db.QueryContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
 
-	rows, err := u.DB.QueryContext(ctx, query, args...)
+		defer rows.Close()
+
+		_, scanSpan := tracer.Start(ctx, "UserModel.get_all.scan")
+		defer scanSpan.End()
+
+		for rows.Next() {
+			user, err := u.scanUser(ctx, rows, &totalRecords)
+			if err != nil {
+				return err
+			}
+
+			users = append(users, user)
+		}
+
+		return rows.Err()
+	})
 	if err != nil {
+		span.fail(err)
 		return nil, MetaData{}, err
 	}
 
-	defer rows.Close()
+	span.rows(len(users))
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return users, metadata, nil
+}
+
+// SearchUsers ranks the users matching query, combining full-text search over
+// names and email with trigram similarity to tolerate typos. With a Cipher it
+// only searches display names, for every user whether or not they are
+// encrypted yet, and finds a user by email only when the query is the exact
+// email, through the blind index.
+func (u UserModel) SearchUsers(ctx context.Context, query string, filters Filters) ([]*UserMatch, MetaData, error) {
+	ctx, span := startQuery(ctx, "search_users")
+	defer span.end()
+
+	stmt := `
+		WITH q AS (SELECT websearch_to_tsquery('simple', $2) AS tsq)
+		SELECT count(*) OVER(),
+			ts_rank(search_vector, q.tsq) + greatest(similarity(name, $2), similarity(display_name, $2), similarity(email, $2)) AS score,
+			` + userColumns + `
+		FROM users, q
+		WHERE tenant_id = $1
+			AND (search_vector @@ q.tsq OR name % $2 OR display_name % $2 OR email % $2)
+		ORDER BY score DESC, id ASC
+		LIMIT $3 OFFSET $4`
+
+	if u.Cipher != nil {
+		stmt = `
+		WITH q AS (SELECT websearch_to_tsquery('simple', $2) AS tsq)
+		SELECT count(*) OVER(),
+			ts_rank(to_tsvector('simple', display_name), q.tsq) + similarity(display_name, $2)
+				+ CASE WHEN email_hash = $5 THEN 1 ELSE 0 END AS score,
+			` + userColumns + `
+		FROM users, q
+		WHERE tenant_id = $1
+			AND (to_tsvector('simple', display_name) @@ q.tsq OR display_name % $2 OR email_hash = $5)
+		ORDER BY score DESC, id ASC
+		LIMIT $3 OFFSET $4`
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
 
 	totalRecords := 0
 
-	users := []*User{}
+	matches := []*UserMatch{}
 
-	for rows.Next() {
-		var user User
-
-		err := rows.Scan(
-			&totalRecords,
-			&user.ID,
-			&user.Name,
-			&user.Email,
-			&user.Age,
-			&user.CreatedAt,
-			&user.Version,
-		)
+	err := u.withTenant(ctx, func(db DBTX, tenant string) error {
+		args := []any{tenant, query, filters.limit(), filters.offset()}
+
+		if u.Cipher != nil {
+			hash, err := u.emailHash(ctx, strings.TrimSpace(query))
+			if err != nil {
+				return err
+			}
+			args = append(args, hash)
+		}
+
+		rows, err := db.QueryContext(ctx, stmt, args...)
 		if err != nil {
-			return nil, MetaData{}, err
+			return err
 		}
 
-		users = append(users, &user)
-	}
+		defer rows.Close()
+
+		for rows.Next() {
+			var score float64
+
+			user, err := u.scanUser(ctx, rows, &totalRecords, &score)
+			if err != nil {
+				return err
+			}
+
+			matches = append(matches, &UserMatch{User: user, Score: score})
+		}
 
-	if err := rows.Err(); err != nil {
+		return rows.Err()
+	})
+	if err != nil {
+		span.fail(err)
 		return nil, MetaData{}, err
 	}
 
+	span.rows(len(matches))
+
 	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
 
-	return users, metadata, err
+	return matches, metadata, nil
+}
+
+// GetUserStats aggregates the users matching filters in the database: the age
+// buckets, the top email domains and the signups per interval each take one
+// grouped query. Encrypted emails are grouped by the email_domain stored next
+// to them.
+func (u UserModel) GetUserStats(ctx context.Context, filters StatsFilters) (*UserStats, error) {
+	ctx, span := startQuery(ctx, "get_user_stats")
+	defer span.end()
+
+	where := `
+		WHERE tenant_id = $1
+			AND ($2::timestamptz IS NULL OR created_at >= $2)
+			AND ($3::timestamptz IS NULL OR created_at < $3)
+			AND attributes @> $4::jsonb`
+
+	ageQuery := `
+		SELECT width_bucket(date_part('year', age(CURRENT_DATE, birthdate)), $5::float8[]) AS bucket, count(*)
+		FROM users` + where + `
+		GROUP BY bucket`
+
+	domainQuery := `
+		SELECT coalesce(email_domain, lower(substring(email FROM '@([^@]*)$'))) AS domain, count(*)
+		FROM users` + where + `
+		GROUP BY domain
+		ORDER BY count(*) DESC, domain ASC
+		LIMIT $5`
+
+	signupQuery := `
+		SELECT date_trunc($5, created_at AT TIME ZONE 'UTC') AS period, count(*)
+		FROM users` + where + `
+		GROUP BY period
+		ORDER BY period ASC`
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	stats := &UserStats{GeneratedAt: time.Now()}
+
+	err := u.withTenant(ctx, func(db DBTX, tenant string) error {
+		args := []any{tenant, nullTime(filters.CreatedFrom), nullTime(filters.CreatedTo), Attributes(filters.Attributes)}
+
+		bounds := make(pq.Float64Array, len(ageBucketBounds))
+		for i, bound := range ageBucketBounds {
+			bounds[i] = float64(bound)
+		}
+
+		ages := map[int]int{}
+		err := queryRows(ctx, db, ageQuery, append(args, bounds), func(rows *sql.Rows) error {
+			var bucket, count int
+			if err := rows.Scan(&bucket, &count); err != nil {
+				return err
+			}
+			ages[bucket] = count
+			stats.Total += count
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		stats.AgeBuckets = newAgeBuckets(ages)
+
+		stats.TopDomains = []DomainCount{}
+		err = queryRows(ctx, db, domainQuery, append(args, filters.TopDomains), func(rows *sql.Rows) error {
+			var domain DomainCount
+			if err := rows.Scan(&domain.Domain, &domain.Count); err != nil {
+				return err
+			}
+			stats.TopDomains = append(stats.TopDomains, domain)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		var signups []SignupCount
+		err = queryRows(ctx, db, signupQuery, append(args, filters.Interval), func(rows *sql.Rows) error {
+			var signup SignupCount
+			if err := rows.Scan(&signup.PeriodStart, &signup.Count); err != nil {
+				return err
+			}
+			signup.PeriodStart = DateOf(signup.PeriodStart)
+			signups = append(signups, signup)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		stats.Signups = fillSignupGaps(signups, filters.Interval)
+
+		return nil
+	})
+	if err != nil {
+		span.fail(err)
+		return nil, err
+	}
+
+	span.rows(stats.Total)
+
+	return stats, nil
+}
+
+// queryRows runs query and calls scan for every row.
+func queryRows(ctx context.Context, db DBTX, query string, args []any, scan func(*sql.Rows) error) error {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// nullTime maps the zero time to NULL.
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
 }
 
-func (u UserModel) DeleteUserById(id int64) (*User, error) {
+func (u UserModel) DeleteUserById(ctx context.Context, id int64) (*User, error) {
 	if id < 1 {
 		return nil, ErrInvalidArgument
 	}
 
+	ctx, span := startQuery(ctx, "delete_user")
+	defer span.end()
+
 	query := `
 		DELETE FROM users
-		WHERE id = $1
-		RETURNING  id, name, email, age, created_at, version`
+		WHERE id = $1 AND tenant_id = $2
+		RETURNING ` + userColumns
 
-	var user User
+	var user *User
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
-	err := u.DB.QueryRowContext(ctx, query, id).Scan(
-		&user.ID,
-		&user.Name,
-		&user.Email,
-		&user.Age,
-		&user.CreatedAt,
-		&user.Version,
-	)
+	err := u.withTenant(ctx, func(db DBTX, tenant string) (err error) {
+		user, err = u.scanUser(ctx, db.QueryRowContext(ctx, query, id, tenant))
+		return err
+	})
 
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
+			span.rows(0)
 			return nil, ErrRecordNotFound
 		default:
+			span.fail(err)
 			return nil, err
 		}
 	}
 
-	return &user, nil
+	span.rows(1)
+
+	return user, nil
+}
+
+// EraseUser irreversibly replaces the personal data of a user, as described
+// for anonymize, and bumps its version. The row keeps its id, so it can still
+// be referred to. An erased user is returned unchanged.
+func (u UserModel) EraseUser(ctx context.Context, id int64) (*User, error) {
+	if id < 1 {
+		return nil, ErrInvalidArgument
+	}
+
+	ctx, span := startQuery(ctx, "erase_user")
+	defer span.end()
+
+	// The outer SELECT sees the rows as they were before the UPDATE, so it
+	// only returns users that had already been erased.
+	query := `
+		WITH erased AS (
+			UPDATE users
+			SET display_name = '', phone = '', locale = '', time_zone = '',
+				attributes = '{}', birthdate = date_trunc('year', birthdate)::date,
+				(` + storedColumns + `) = ($3, $4, $5, $6, $7, $8, $9),
+				erased_at = NOW(), version = version + 1
+			WHERE id = $1 AND tenant_id = $2 AND erased_at IS NULL
+			RETURNING ` + userColumns + `
+		)
+		SELECT ` + userColumns + ` FROM erased
+		UNION ALL
+		SELECT ` + userColumns + ` FROM users
+		WHERE id = $1 AND tenant_id = $2 AND erased_at IS NOT NULL`
+
+	var user *User
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	err := u.withTenant(ctx, func(db DBTX, tenant string) error {
+		fields, err := u.seal(ctx, tenant, ErasedName, ErasedEmail(id))
+		if err != nil {
+			return err
+		}
+
+		user, err = u.scanUser(ctx, db.QueryRowContext(ctx, query, append([]any{id, tenant}, fields.args()...)...))
+		return err
+	})
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			span.rows(0)
+			return nil, ErrRecordNotFound
+		default:
+			span.fail(err)
+			return nil, err
+		}
+	}
+
+	span.rows(1)
+
+	return user, nil
 }
 
-func (u UserModel) UpdateUser(user *User) error {
+func (u UserModel) UpdateUser(ctx context.Context, user *User) error {
+	ctx, span := startQuery(ctx, "update_user")
+	defer span.end()
+
 	query := `
 		UPDATE users
-		SET name = $1, email = $2, age = $3, version = version + 1
-		WHERE id = $4 AND version = $5
-		RETURNING version`
+		SET display_name = $4, phone = $5, locale = $6, time_zone = $7, birthdate = $8, attributes = $9,
+			(` + storedColumns + `) = ($10, $11, $12, $13, $14, $15, $16), version = version + 1
+		WHERE id = $1 AND version = $2 AND tenant_id = $3 AND erased_at IS NULL
+		RETURNING updated_at, version`
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
-	args := []any{user.Name, user.Email, user.Age, user.ID, user.Version}
+	err := u.withTenant(ctx, func(db DBTX, tenant string) error {
+		fields, err := u.seal(ctx, tenant, user.Name, user.Email)
+		if err != nil {
+			return err
+		}
+
+		if err := u.claimEmail(ctx, db, tenant, user.Email, fields.emailHash); err != nil {
+			return err
+		}
 
-	err := u.DB.QueryRowContext(ctx, query, args...).Scan(&user.Version)
+		args := append([]any{user.ID, user.Version, tenant, user.DisplayName, user.Phone, user.Locale, user.TimeZone, user.Birthdate, user.Attributes}, fields.args()...)
+
+		return db.QueryRowContext(ctx, query, args...).Scan(&user.UpdatedAt, &user.Version)
+	})
 
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
+			span.rows(0)
 			return ErrEditConflict
+		case isDuplicateEmail(err):
+			span.rows(0)
+			return ErrDuplicateEmail
 		default:
+			span.fail(err)
 			return err
 		}
 	}
 
+	span.rows(1)
+
 	return nil
 }
 
+func isDuplicateEmail(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505" &&
+		(pqErr.Constraint == "users_tenant_id_lower_email_key" || pqErr.Constraint == "users_tenant_id_email_hash_key")
+}
+
 func ValidateUser(v *validator.Validator, user *User) {
-	v.Check(user.Name != "", "name", "must be provided")
-	v.Check(user.Email != "", "email", "must be provided")
-	v.Check(user.Age > 0, "age", "must be greater than 0")
-	v.Check(validator.Matches(user.Email, validator.EmailRX), "email", "must be a valid email address")
+	user.Email = NormalizeEmail(user.Email)
+
+	validator.Field(v, "name", user.Name,
+		validator.NotBlank(),
+		validator.MaxRunes(nameMaxLength),
+		validator.ValidUTF8(),
+		validator.NoControlChars(),
+	)
+	validator.Field(v, "email", user.Email,
+		validator.NotBlank(),
+		validator.MaxRunes(emailMaxLength),
+		validator.Match(validator.EmailRX, i18n.New(i18n.ReasonInvalidEmail)),
+	)
+
+	validateProfile(v, user)
+	validateAttributes(v, "attributes", user.Attributes)
 }
@@ -0,0 +1,144 @@
+// Package encryption encrypts single field values with envelope encryption and
+// computes blind indexes, so encrypted values can still be looked up by
+// equality.
+package encryption
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+)
+
+var ErrMalformed = errors.New("encryption: malformed or tampered ciphertext")
+
+// formatV1 marks the layout produced by Encrypt:
+//
+//	format (1 byte) | key version (4 bytes) | wrapped key length (2 bytes) |
+//	wrapped data key | nonce | sealed value
+const formatV1 = 1
+
+const headerSize = 1 + 4 + 2
+
+// Cipher encrypts every value with a fresh AES-256-GCM data key, which it
+// stores wrapped by the current key of the KeyProvider next to the value.
+// Rotating the key encryption key therefore only needs the data keys to be
+// rewrapped, and the version in each value says which key unwraps it.
+type Cipher struct {
+	keys KeyProvider
+}
+
+func New(keys KeyProvider) *Cipher {
+	return &Cipher{keys: keys}
+}
+
+// CurrentKeyVersion returns the key version Encrypt uses.
+func (c *Cipher) CurrentKeyVersion() uint32 {
+	return c.keys.CurrentVersion()
+}
+
+// Encrypt seals plaintext. aad is authenticated but not stored: Decrypt needs
+// the same aad, which binds the value to where it is stored, such as its
+// tenant and column, so it can't be copied elsewhere unnoticed.
+func (c *Cipher) Encrypt(ctx context.Context, plaintext, aad string) ([]byte, error) {
+	dataKey := make([]byte, KeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, err
+	}
+
+	version := c.keys.CurrentVersion()
+
+	wrapped, err := c.keys.WrapKey(ctx, version, dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, 0, headerSize+len(wrapped)+aead.NonceSize()+len(plaintext)+aead.Overhead())
+	out = append(out, formatV1)
+	out = binary.BigEndian.AppendUint32(out, version)
+	out = binary.BigEndian.AppendUint16(out, uint16(len(wrapped)))
+	out = append(out, wrapped...)
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	out = append(out, nonce...)
+
+	return aead.Seal(out, nonce, []byte(plaintext), []byte(aad)), nil
+}
+
+func (c *Cipher) Decrypt(ctx context.Context, ciphertext []byte, aad string) (string, error) {
+	version, wrapped, rest, err := parse(ciphertext)
+	if err != nil {
+		return "", err
+	}
+
+	dataKey, err := c.keys.UnwrapKey(ctx, version, wrapped)
+	if err != nil {
+		return "", err
+	}
+
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return "", err
+	}
+
+	if len(rest) < aead.NonceSize() {
+		return "", ErrMalformed
+	}
+
+	plaintext, err := aead.Open(nil, rest[:aead.NonceSize()], rest[aead.NonceSize():], []byte(aad))
+	if err != nil {
+		return "", ErrMalformed
+	}
+
+	return string(plaintext), nil
+}
+
+// KeyVersion returns the version of the key that wraps the data key of
+// ciphertext, without decrypting it.
+func KeyVersion(ciphertext []byte) (uint32, error) {
+	version, _, _, err := parse(ciphertext)
+	return version, err
+}
+
+func parse(ciphertext []byte) (version uint32, wrapped, rest []byte, err error) {
+	if len(ciphertext) < headerSize || ciphertext[0] != formatV1 {
+		return 0, nil, nil, ErrMalformed
+	}
+
+	version = binary.BigEndian.Uint32(ciphertext[1:5])
+	n := int(binary.BigEndian.Uint16(ciphertext[5:7]))
+
+	if len(ciphertext) < headerSize+n {
+		return 0, nil, nil, ErrMalformed
+	}
+
+	return version, ciphertext[headerSize : headerSize+n], ciphertext[headerSize+n:], nil
+}
+
+// BlindIndex returns a keyed HMAC-SHA256 of value. Equal values give equal
+// indexes, so an index can stand in for the value in lookups and unique
+// constraints, but it can't be reversed without the index key. purpose keeps
+// the indexes of different fields apart.
+func (c *Cipher) BlindIndex(ctx context.Context, purpose, value string) ([]byte, error) {
+	key, err := c.keys.IndexKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(purpose))
+	mac.Write([]byte{0})
+	mac.Write([]byte(value))
+
+	return mac.Sum(nil), nil
+}
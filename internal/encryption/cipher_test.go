@@ -0,0 +1,154 @@
+package encryption
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, KeySize)
+}
+
+func newTestCipher(t *testing.T, current uint32, versions ...uint32) *Cipher {
+	t.Helper()
+
+	keys := map[uint32][]byte{}
+	for _, version := range versions {
+		keys[version] = testKey(byte(version))
+	}
+
+	p, err := NewLocalKeyProvider(current, keys, testKey(0xff))
+	require.NoError(t, err)
+
+	return New(p)
+}
+
+func TestCipher_RoundTrip(t *testing.T) {
+	c := newTestCipher(t, 1, 1)
+	ctx := context.Background()
+
+	sealed, err := c.Encrypt(ctx, "andrew@google.com", "acme/email")
+	require.NoError(t, err)
+	assert.NotContains(t, string(sealed), "andrew")
+
+	again, err := c.Encrypt(ctx, "andrew@google.com", "acme/email")
+	require.NoError(t, err)
+	assert.NotEqual(t, sealed, again, "every value gets its own data key and nonce")
+
+	plaintext, err := c.Decrypt(ctx, sealed, "acme/email")
+	require.NoError(t, err)
+	assert.Equal(t, "andrew@google.com", plaintext)
+
+	_, err = c.Decrypt(ctx, sealed, "other/email")
+	assert.ErrorIs(t, err, ErrMalformed, "aad must match")
+
+	sealed[len(sealed)-1] ^= 1
+	_, err = c.Decrypt(ctx, sealed, "acme/email")
+	assert.ErrorIs(t, err, ErrMalformed, "tampered")
+
+	_, err = c.Decrypt(ctx, []byte{formatV1, 0, 0}, "acme/email")
+	assert.ErrorIs(t, err, ErrMalformed, "truncated")
+}
+
+func TestCipher_KeyRotation(t *testing.T) {
+	ctx := context.Background()
+
+	old := newTestCipher(t, 1, 1)
+	sealed, err := old.Encrypt(ctx, "Andrew", "acme/name")
+	require.NoError(t, err)
+
+	rotated := newTestCipher(t, 2, 1, 2)
+
+	plaintext, err := rotated.Decrypt(ctx, sealed, "acme/name")
+	require.NoError(t, err)
+	assert.Equal(t, "Andrew", plaintext, "old versions still decrypt")
+
+	resealed, err := rotated.Encrypt(ctx, plaintext, "acme/name")
+	require.NoError(t, err)
+
+	version, err := KeyVersion(resealed)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(2), version)
+
+	_, err = newTestCipher(t, 2, 2).Decrypt(ctx, sealed, "acme/name")
+	assert.ErrorIs(t, err, ErrUnknownKey, "retired versions don't")
+}
+
+func TestCipher_BlindIndex(t *testing.T) {
+	c := newTestCipher(t, 1, 1)
+	ctx := context.Background()
+
+	index := func(purpose, value string) []byte {
+		b, err := c.BlindIndex(ctx, purpose, value)
+		require.NoError(t, err)
+		return b
+	}
+
+	assert.Equal(t, index("email", "andrew@google.com"), index("email", "andrew@google.com"))
+	assert.NotEqual(t, index("email", "andrew@google.com"), index("email", "john@gmail.com"))
+	assert.NotEqual(t, index("email", "andrew"), index("name", "andrew"))
+
+	rotated := newTestCipher(t, 2, 1, 2)
+	b, err := rotated.BlindIndex(ctx, "email", "andrew@google.com")
+	require.NoError(t, err)
+	assert.Equal(t, index("email", "andrew@google.com"), b, "rotating keys keeps indexes")
+}
+
+func TestLoadKeyFile(t *testing.T) {
+	encode := func(b []byte) string { return base64.StdEncoding.EncodeToString(b) }
+
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{
+			name:    "valid",
+			content: `{"current_version": 2, "keys": {"1": "` + encode(testKey(1)) + `", "2": "` + encode(testKey(2)) + `"}, "index_key": "` + encode(testKey(3)) + `"}`,
+		},
+		{
+			name:    "missing current key",
+			content: `{"current_version": 3, "keys": {"1": "` + encode(testKey(1)) + `"}, "index_key": "` + encode(testKey(3)) + `"}`,
+			wantErr: "current key version 3 has no key",
+		},
+		{
+			name:    "short key",
+			content: `{"current_version": 1, "keys": {"1": "` + encode([]byte("short")) + `"}, "index_key": "` + encode(testKey(3)) + `"}`,
+			wantErr: "key version 1 must be 32 bytes",
+		},
+		{
+			name:    "missing index key",
+			content: `{"current_version": 1, "keys": {"1": "` + encode(testKey(1)) + `"}}`,
+			wantErr: "index key must be at least 32 bytes",
+		},
+		{
+			name:    "invalid version",
+			content: `{"current_version": 1, "keys": {"one": "` + encode(testKey(1)) + `"}, "index_key": "` + encode(testKey(3)) + `"}`,
+			wantErr: `invalid key version "one"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "keys.json")
+			require.NoError(t, os.WriteFile(path, []byte(tt.content), 0o600))
+
+			p, err := LoadKeyFile(path)
+
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, uint32(2), p.CurrentVersion())
+		})
+	}
+}
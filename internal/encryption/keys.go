@@ -0,0 +1,194 @@
+package encryption
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// KeySize is the size of the key encryption keys and the data keys, which
+// selects AES-256.
+const KeySize = 32
+
+// IndexKeyMinSize is the minimum size of the blind index key.
+const IndexKeyMinSize = 32
+
+var ErrUnknownKey = errors.New("encryption: unknown key version")
+
+// KeyProvider holds the key encryption keys. They never leave the provider:
+// it only wraps and unwraps data keys, so an implementation backed by a KMS
+// fits the same interface as the local key file.
+type KeyProvider interface {
+	// CurrentVersion returns the version of the key that wraps new data keys.
+	CurrentVersion() uint32
+	// WrapKey encrypts dataKey with the key of the given version.
+	WrapKey(ctx context.Context, version uint32, dataKey []byte) ([]byte, error)
+	// UnwrapKey decrypts a data key wrapped by WrapKey with the same version.
+	UnwrapKey(ctx context.Context, version uint32, wrapped []byte) ([]byte, error)
+	// IndexKey returns the HMAC key of blind indexes. Unlike the key
+	// encryption keys it is not versioned: changing it invalidates every
+	// stored index.
+	IndexKey(ctx context.Context) ([]byte, error)
+}
+
+// LocalKeyProvider keeps the keys in process memory, usually after reading
+// them from a key file with LoadKeyFile.
+type LocalKeyProvider struct {
+	current  uint32
+	keys     map[uint32]cipher.AEAD
+	indexKey []byte
+}
+
+func NewLocalKeyProvider(current uint32, keys map[uint32][]byte, indexKey []byte) (*LocalKeyProvider, error) {
+	if _, ok := keys[current]; !ok {
+		return nil, fmt.Errorf("encryption: current key version %d has no key", current)
+	}
+
+	if len(indexKey) < IndexKeyMinSize {
+		return nil, fmt.Errorf("encryption: index key must be at least %d bytes", IndexKeyMinSize)
+	}
+
+	p := &LocalKeyProvider{
+		current:  current,
+		keys:     make(map[uint32]cipher.AEAD, len(keys)),
+		indexKey: indexKey,
+	}
+
+	for version, key := range keys {
+		if version == 0 {
+			return nil, errors.New("encryption: key versions start at 1")
+		}
+
+		if len(key) != KeySize {
+			return nil, fmt.Errorf("encryption: key version %d must be %d bytes", version, KeySize)
+		}
+
+		aead, err := newAEAD(key)
+		if err != nil {
+			return nil, err
+		}
+
+		p.keys[version] = aead
+	}
+
+	return p, nil
+}
+
+// keyFile is the JSON layout of a key file. Keys are base64 encoded and keyed
+// by their version:
+//
+//	{
+//	  "current_version": 2,
+//	  "keys": {"1": "...", "2": "..."},
+//	  "index_key": "..."
+//	}
+type keyFile struct {
+	CurrentVersion uint32            `json:"current_version"`
+	Keys           map[string]string `json:"keys"`
+	IndexKey       string            `json:"index_key"`
+}
+
+// LoadKeyFile reads the keys from a JSON key file. To rotate keys, add a new
+// version, make it current and keep the old ones until every value has been
+// re-encrypted.
+func LoadKeyFile(path string) (*LocalKeyProvider, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file keyFile
+	if err := json.Unmarshal(raw, &file); err != nil {
+		return nil, fmt.Errorf("key file %s: %w", path, err)
+	}
+
+	keys := make(map[uint32][]byte, len(file.Keys))
+
+	for name, encoded := range file.Keys {
+		version, err := strconv.ParseUint(name, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("key file %s: invalid key version %q", path, name)
+		}
+
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("key file %s: key version %s: %w", path, name, err)
+		}
+
+		keys[uint32(version)] = key
+	}
+
+	indexKey, err := base64.StdEncoding.DecodeString(file.IndexKey)
+	if err != nil {
+		return nil, fmt.Errorf("key file %s: index key: %w", path, err)
+	}
+
+	p, err := NewLocalKeyProvider(file.CurrentVersion, keys, indexKey)
+	if err != nil {
+		return nil, fmt.Errorf("key file %s: %w", path, err)
+	}
+
+	return p, nil
+}
+
+func (p *LocalKeyProvider) CurrentVersion() uint32 {
+	return p.current
+}
+
+// WrapKey seals dataKey with a random nonce, which it puts in front of the
+// result. The version is authenticated so a wrapped key can't be passed off
+// as one of another version.
+func (p *LocalKeyProvider) WrapKey(ctx context.Context, version uint32, dataKey []byte) ([]byte, error) {
+	aead, ok := p.keys[version]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return aead.Seal(nonce, nonce, dataKey, binary.BigEndian.AppendUint32(nil, version)), nil
+}
+
+func (p *LocalKeyProvider) UnwrapKey(ctx context.Context, version uint32, wrapped []byte) ([]byte, error) {
+	aead, ok := p.keys[version]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+
+	if len(wrapped) < aead.NonceSize() {
+		return nil, ErrMalformed
+	}
+
+	nonce, sealed := wrapped[:aead.NonceSize()], wrapped[aead.NonceSize():]
+
+	dataKey, err := aead.Open(nil, nonce, sealed, binary.BigEndian.AppendUint32(nil, version))
+	if err != nil {
+		return nil, ErrMalformed
+	}
+
+	return dataKey, nil
+}
+
+func (p *LocalKeyProvider) IndexKey(ctx context.Context) ([]byte, error) {
+	return p.indexKey, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
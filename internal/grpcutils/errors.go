@@ -1,85 +1,270 @@
 package grpcutils
 
 import (
+	"crypto/rand"
+	"errors"
 	"log/slog"
+	"maps"
+	"slices"
+	"time"
 
+	"github.com/Vadim-Makhnev/grpc/internal/i18n"
 	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+	"google.golang.org/protobuf/types/known/durationpb"
 )
 
 var (
-	ErrMessageUserNotFound           = "user not found"
-	ErrMessageEditConflict           = "unable to update due to edit conflict, please try again"
-	ErrMessageRateLimitExceeded      = "rate limit exceeded"
-	ErrMessageInvalidCredentials     = "invalid authentication credentials"
-	ErrMessageInvalidToken           = "invalid or missing authentication token"
-	ErrMessageAuthenticationRequired = "you must be authenticated to access this resource"
-	ErrMessageAccountInactive        = "your user account must be activated"
-	ErrMessageNotPermitted           = "you don't have permission to access this resource"
-	ErrMessageInternalProblem        = "the server encountered a problem"
-	ErrMessageBadRequest             = "invalid request"
-	ErrMessageInvalidRequest         = "invalid request"
-	ErrMessageInvalidArgument        = "invalid argument"
+	ErrMessageUserNotFound           = i18n.New(i18n.ReasonUserNotFound).String()
+	ErrMessageEditConflict           = i18n.New(i18n.ReasonEditConflict).String()
+	ErrMessageRateLimitExceeded      = i18n.New(i18n.ReasonRateLimitExceeded).String()
+	ErrMessageInvalidCredentials     = i18n.New(i18n.ReasonInvalidCredentials).String()
+	ErrMessageInvalidToken           = i18n.New(i18n.ReasonInvalidToken).String()
+	ErrMessageAuthenticationRequired = i18n.New(i18n.ReasonAuthenticationRequired).String()
+	ErrMessageAccountInactive        = i18n.New(i18n.ReasonAccountInactive).String()
+	ErrMessageNotPermitted           = i18n.New(i18n.ReasonNotPermitted).String()
+	ErrMessageInternalProblem        = i18n.New(i18n.ReasonInternal).String()
+	ErrMessageBadRequest             = i18n.New(i18n.ReasonInvalidRequest).String()
+	ErrMessageInvalidRequest         = i18n.New(i18n.ReasonInvalidRequest).String()
+	ErrMessageInvalidArgument        = i18n.New(i18n.ReasonInvalidArgument).String()
+	ErrMessageUnavailable            = i18n.New(i18n.ReasonUnavailable).String()
 )
 
-func NotFound(msg string) error {
-	if msg == "" {
-		msg = ErrMessageUserNotFound
-	}
-	return status.Error(codes.NotFound, msg)
+// ErrorDomain is reported in the ErrorInfo of every error created by this
+// package.
+const ErrorDomain = "github.com/Vadim-Makhnev/grpc"
+
+// Error is a status error that remembers its messages so they can be
+// localized later. As it is, it converts to an English status with an
+// ErrorInfo detail.
+type Error struct {
+	code     codes.Code
+	reason   string
+	message  i18n.Message
+	fields   []fieldViolation
+	metadata map[string]string
+	details  []protoadapt.MessageV1
+
+	locale    string
+	requestID string
+
+	st *status.Status
 }
 
-func FailedValidation(errors map[string]string) error {
-	st := status.New(codes.InvalidArgument, ErrMessageInvalidRequest)
+type fieldViolation struct {
+	field string
+	msg   i18n.Message
+}
 
-	for field, desc := range errors {
+func newError(e *Error) *Error {
+	e.st = e.status()
+	return e
+}
+
+func (e *Error) Error() string {
+	return e.st.Err().Error()
+}
+
+func (e *Error) GRPCStatus() *status.Status {
+	return e.st
+}
+
+// with returns a copy of e changed by fn.
+func (e *Error) with(fn func(e *Error)) *Error {
+	c := *e
+	fn(&c)
+	c.st = c.status()
+
+	return &c
+}
+
+// status builds the status of e. Field violations come first, in the order
+// they were added, followed by the ErrorInfo and, when known, the RequestInfo.
+// A locale adds LocalizedMessage details for the error itself and for every
+// field violation.
+func (e *Error) status() *status.Status {
+	st := status.New(e.code, e.message.String())
+
+	var details []protoadapt.MessageV1
+
+	for _, f := range e.fields {
 		violation := &errdetails.BadRequest_FieldViolation{
-			Field:       field,
-			Description: desc,
+			Field:       f.field,
+			Description: f.msg.String(),
+			Reason:      f.msg.Reason,
 		}
-		stWithDetail, err := st.WithDetails(violation)
-		if err != nil {
-			return status.Error(codes.Internal, ErrMessageInternalProblem)
+		if e.locale != "" {
+			violation.LocalizedMessage = &errdetails.LocalizedMessage{Locale: e.locale, Message: f.msg.Localize(e.locale)}
 		}
-		st = stWithDetail
+		details = append(details, violation)
+	}
+
+	details = append(details, e.details...)
+	details = append(details, &errdetails.ErrorInfo{
+		Reason:   e.reason,
+		Domain:   ErrorDomain,
+		Metadata: e.metadata,
+	})
+
+	if e.requestID != "" {
+		details = append(details, &errdetails.RequestInfo{RequestId: e.requestID})
+	}
+
+	if e.locale != "" {
+		details = append(details, &errdetails.LocalizedMessage{Locale: e.locale, Message: e.message.Localize(e.locale)})
+	}
+
+	stWithDetails, err := st.WithDetails(details...)
+	if err != nil {
+		return status.New(codes.Internal, ErrMessageInternalProblem)
 	}
 
-	return st.Err()
+	return stWithDetails
 }
 
-func Internal(logger *slog.Logger, err error, msg string) error {
-	if msg == "" {
-		msg = ErrMessageInternalProblem
+// Localize adds LocalizedMessage details in locale to errors created by this
+// package and returns any other error unchanged.
+func Localize(err error, locale string) error {
+	var e *Error
+	if !errors.As(err, &e) {
+		return err
 	}
 
-	if logger != nil {
-		logger.Error("internal server error", "error", err)
+	return e.with(func(e *Error) { e.locale = locale })
+}
+
+// WithRequestInfo adds a RequestInfo detail with requestID to errors created
+// by this package and returns any other error unchanged.
+func WithRequestInfo(err error, requestID string) error {
+	var e *Error
+	if !errors.As(err, &e) {
+		return err
 	}
 
-	return status.Error(codes.Internal, msg)
+	return e.with(func(e *Error) { e.requestID = requestID })
 }
 
-func InvalidArgument(logger *slog.Logger, err error, msg string) error {
+// Reason returns the ErrorInfo reason of err, or "" if err was not created by
+// this package.
+func Reason(err error) string {
+	var e *Error
+	if !errors.As(err, &e) {
+		return ""
+	}
+
+	return e.reason
+}
+
+// message returns msg as is, or the catalog message for reason if msg is
+// empty.
+func message(msg, reason string) i18n.Message {
 	if msg == "" {
-		msg = ErrMessageInvalidArgument
+		return i18n.New(reason)
+	}
+	return i18n.Raw(msg)
+}
+
+// New returns an error with the given code and a catalog message, whose
+// reason is also reported in the ErrorInfo.
+func New(code codes.Code, msg i18n.Message) error {
+	return newError(&Error{code: code, reason: msg.Reason, message: msg})
+}
+
+func NotFound(msg string) error {
+	return newError(&Error{
+		code:    codes.NotFound,
+		reason:  i18n.ReasonUserNotFound,
+		message: message(msg, i18n.ReasonUserNotFound),
+	})
+}
+
+func FailedValidation(errors map[string]string) error {
+	fields := make(map[string][]i18n.Message, len(errors))
+	for field, desc := range errors {
+		fields[field] = []i18n.Message{i18n.Raw(desc)}
 	}
 
+	return FailedValidationFields(fields)
+}
+
+// FailedValidationFields is like FailedValidation but reports every message of
+// a field as a separate violation. Fields are reported in lexical order, the
+// messages of a field in the order they were recorded.
+func FailedValidationFields(errors map[string][]i18n.Message) error {
+	var fields []fieldViolation
+
+	for _, field := range slices.Sorted(maps.Keys(errors)) {
+		for _, msg := range errors[field] {
+			fields = append(fields, fieldViolation{field: field, msg: msg})
+		}
+	}
+
+	return newError(&Error{
+		code:    codes.InvalidArgument,
+		reason:  i18n.ReasonInvalidRequest,
+		message: i18n.New(i18n.ReasonInvalidRequest),
+		fields:  fields,
+	})
+}
+
+// InvalidField reports a single field violation with a catalog message.
+func InvalidField(field, reason string, args ...any) error {
+	return FailedValidationFields(map[string][]i18n.Message{field: {i18n.New(reason, args...)}})
+}
+
+// Internal logs err under a random reference and returns an error that
+// carries the reference in its ErrorInfo metadata, so a report from a client
+// can be matched with the log entry.
+func Internal(logger *slog.Logger, err error, msg string) error {
+	reference := rand.Text()
+
+	if logger != nil {
+		logger.Error("internal server error", "error", err, "reference", reference)
+	}
+
+	return newError(&Error{
+		code:     codes.Internal,
+		reason:   i18n.ReasonInternal,
+		message:  message(msg, i18n.ReasonInternal),
+		metadata: map[string]string{"reference": reference},
+	})
+}
+
+func InvalidArgument(logger *slog.Logger, err error, msg string) error {
 	if logger != nil {
 		logger.Error("invalid argument", "error", err)
 	}
 
-	return status.Error(codes.InvalidArgument, msg)
+	return newError(&Error{
+		code:    codes.InvalidArgument,
+		reason:  i18n.ReasonInvalidArgument,
+		message: message(msg, i18n.ReasonInvalidArgument),
+	})
 }
 
 func EditConflict(logger *slog.Logger, err error, msg string) error {
-	if msg == "" {
-		msg = ErrMessageEditConflict
+	if logger != nil {
+		logger.Error("edit conflict", "error", err)
 	}
 
+	return newError(&Error{
+		code:    codes.Aborted,
+		reason:  i18n.ReasonEditConflict,
+		message: message(msg, i18n.ReasonEditConflict),
+	})
+}
+
+func Unavailable(logger *slog.Logger, err error, retryAfter time.Duration) error {
 	if logger != nil {
-		logger.Error("edit conflict", "error", err)
+		logger.Warn("service unavailable", "error", err, "retry_after", retryAfter)
 	}
 
-	return status.Error(codes.Aborted, msg)
+	return newError(&Error{
+		code:    codes.Unavailable,
+		reason:  i18n.ReasonUnavailable,
+		message: i18n.New(i18n.ReasonUnavailable),
+		details: []protoadapt.MessageV1{&errdetails.RetryInfo{RetryDelay: durationpb.New(retryAfter)}},
+	})
 }
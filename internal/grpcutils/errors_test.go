@@ -0,0 +1,110 @@
+package grpcutils
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/Vadim-Makhnev/grpc/internal/i18n"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestFailedValidation_DeterministicOrder(t *testing.T) {
+	errs := map[string]string{
+		"name":  "must be provided",
+		"age":   "must be greater than 0",
+		"email": "must be provided",
+		"a.b":   "must be provided",
+	}
+
+	for range 20 {
+		st := status.Convert(FailedValidation(errs))
+
+		var fields []string
+		for _, detail := range st.Details() {
+			if violation, ok := detail.(*errdetails.BadRequest_FieldViolation); ok {
+				fields = append(fields, violation.Field)
+			}
+		}
+
+		require.Equal(t, []string{"a.b", "age", "email", "name"}, fields)
+	}
+}
+
+func TestErrorInfo(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		code   codes.Code
+		reason string
+	}{
+		{name: "not found", err: NotFound(""), code: codes.NotFound, reason: "USER_NOT_FOUND"},
+		{name: "edit conflict", err: EditConflict(nil, nil, ""), code: codes.Aborted, reason: "EDIT_CONFLICT"},
+		{name: "invalid argument", err: InvalidArgument(nil, nil, "custom message"), code: codes.InvalidArgument, reason: "INVALID_ARGUMENT"},
+		{name: "unavailable", err: Unavailable(nil, nil, 0), code: codes.Unavailable, reason: "UNAVAILABLE"},
+		{name: "catalog message", err: New(codes.PermissionDenied, i18n.New(i18n.ReasonTenantMismatch)), code: codes.PermissionDenied, reason: "TENANT_MISMATCH"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := status.Convert(tt.err)
+			assert.Equal(t, tt.code, st.Code())
+			assert.Equal(t, tt.reason, Reason(tt.err))
+
+			info := findDetail[*errdetails.ErrorInfo](st)
+			require.NotNil(t, info)
+			assert.Equal(t, tt.reason, info.Reason)
+			assert.Equal(t, ErrorDomain, info.Domain)
+		})
+	}
+}
+
+func TestInternal_Reference(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	st := status.Convert(Internal(logger, errors.New("connection reset"), ""))
+
+	info := findDetail[*errdetails.ErrorInfo](st)
+	require.NotNil(t, info)
+	require.NotEmpty(t, info.Metadata["reference"])
+
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, info.Metadata["reference"], entry["reference"])
+	assert.Equal(t, ErrMessageInternalProblem, st.Message())
+}
+
+func TestWithRequestInfo(t *testing.T) {
+	err := WithRequestInfo(Localize(NotFound(""), i18n.Russian), "req-1")
+
+	st := status.Convert(err)
+	require.Len(t, st.Details(), 3)
+
+	requestInfo := findDetail[*errdetails.RequestInfo](st)
+	require.NotNil(t, requestInfo)
+	assert.Equal(t, "req-1", requestInfo.RequestId)
+
+	localized := findDetail[*errdetails.LocalizedMessage](st)
+	require.NotNil(t, localized)
+	assert.Equal(t, "пользователь не найден", localized.Message)
+
+	plain := status.Error(codes.NotFound, "plain")
+	assert.Equal(t, plain, WithRequestInfo(plain, "req-1"), "foreign errors must be returned unchanged")
+}
+
+func findDetail[T any](st *status.Status) T {
+	var zero T
+	for _, detail := range st.Details() {
+		if d, ok := detail.(T); ok {
+			return d
+		}
+	}
+	return zero
+}
@@ -0,0 +1,54 @@
+package i18n
+
+var english = map[string]string{
+	ReasonUserNotFound:           "user not found",
+	ReasonEditConflict:           "unable to update due to edit conflict, please try again",
+	ReasonRateLimitExceeded:      "rate limit exceeded",
+	ReasonInvalidCredentials:     "invalid authentication credentials",
+	ReasonInvalidToken:           "invalid or missing authentication token",
+	ReasonAuthenticationRequired: "you must be authenticated to access this resource",
+	ReasonAccountInactive:        "your user account must be activated",
+	ReasonNotPermitted:           "you don't have permission to access this resource",
+	ReasonInternal:               "the server encountered a problem",
+	ReasonInvalidRequest:         "invalid request",
+	ReasonInvalidArgument:        "invalid argument",
+	ReasonUnavailable:            "the service is temporarily unavailable, please try again later",
+	ReasonCanceled:               "the request was canceled",
+	ReasonDeadlineExceeded:       "the request did not complete before its deadline",
+	ReasonTenantMismatch:         "the requested tenant does not match the client certificate",
+	ReasonBodyTooLarge:           "body must not be larger than %d bytes",
+	ReasonBodyEmpty:              "body must not be empty",
+	ReasonBodyMalformed:          "body contains badly-formed JSON",
+
+	ReasonRequired:          "must be provided",
+	ReasonBlank:             "must not be blank",
+	ReasonTooShort:          "must be at least %d characters long",
+	ReasonTooLong:           "must not be more than %d characters long",
+	ReasonInvalidUTF8:       "must be valid UTF-8 text",
+	ReasonControlCharacters: "must not contain control characters",
+	ReasonNotOneOf:          "must be one of %s",
+	ReasonNotGreaterThan:    "must be greater than %v",
+	ReasonBelowMinimum:      "must be at least %v",
+	ReasonAboveMaximum:      "must be a maximum of %v",
+	ReasonOutOfRange:        "must be between %v and %v",
+	ReasonNotAboveZero:      "must be greater than zero",
+	ReasonNotPositive:       "must be a positive integer",
+	ReasonNotInteger:        "must be an integer value",
+	ReasonInvalidEmail:      "must be a valid email address",
+	ReasonInvalidPhone:      "must be a phone number in international format, e.g. +14155550123",
+	ReasonInvalidLocale:     "must be a valid BCP 47 language tag, e.g. en-US",
+	ReasonInvalidTimeZone:   "must be a valid IANA time zone, e.g. Europe/Moscow",
+	ReasonInvalidDate:       "must be a date in YYYY-MM-DD format",
+	ReasonInvalidTimestamp:  "must be a date in YYYY-MM-DD format or an RFC 3339 timestamp",
+	ReasonDateInFuture:      "must not be in the future",
+	ReasonDateTooFarInPast:  "must not be more than %d years ago",
+	ReasonTooManyEntries:    "must not have more than %d entries",
+	ReasonInvalidKey:        "must be a key of lowercase letters, digits, '_', '.' or '-' starting with a letter",
+	ReasonNotAfter:          "must be after %s",
+	ReasonDuplicateEmail:    "a user with this email address already exists",
+	ReasonInvalidSort:       "invalid sort value",
+	ReasonPageTooLarge:      "must be a maximum of 10 million",
+	ReasonInvalidTenant:     "must contain only lowercase letters, digits, '-' and '_' (max 63 characters)",
+	ReasonLookupKeyMissing:  "one of id or email must be provided",
+	ReasonLookupKeyConflict: "only one of id or email may be provided",
+}
@@ -0,0 +1,103 @@
+// Package i18n holds the message catalogs used for client-facing errors and
+// negotiates the locale a client asked for.
+package i18n
+
+import (
+	"context"
+	"fmt"
+
+	"golang.org/x/text/language"
+)
+
+const (
+	English = "en"
+	Russian = "ru"
+
+	// DefaultLocale is used for logs, status messages and whenever a client
+	// does not ask for a supported locale.
+	DefaultLocale = English
+)
+
+var catalogs = map[string]map[string]string{
+	English: english,
+	Russian: russian,
+}
+
+var matcher = language.NewMatcher([]language.Tag{language.English, language.Russian})
+
+// Message is a client-facing message identified by a stable reason and the
+// arguments of its catalog template. Messages without a reason carry their
+// text verbatim and are never translated.
+type Message struct {
+	Reason string
+	Args   []any
+	Text   string
+}
+
+func New(reason string, args ...any) Message {
+	return Message{Reason: reason, Args: args}
+}
+
+func Raw(text string) Message {
+	return Message{Text: text}
+}
+
+// Localize renders m in locale, falling back to English when the locale has
+// no translation for m.Reason.
+func (m Message) Localize(locale string) string {
+	if m.Reason == "" {
+		return m.Text
+	}
+
+	tmpl, ok := catalogs[locale][m.Reason]
+	if !ok {
+		tmpl, ok = catalogs[DefaultLocale][m.Reason]
+	}
+	if !ok {
+		return m.Reason
+	}
+
+	if len(m.Args) == 0 {
+		return tmpl
+	}
+
+	return fmt.Sprintf(tmpl, m.Args...)
+}
+
+func (m Message) String() string {
+	return m.Localize(DefaultLocale)
+}
+
+// Negotiate picks the supported locale that best matches an Accept-Language
+// value, defaulting to English.
+func Negotiate(acceptLanguage string) string {
+	tags, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(tags) == 0 {
+		return DefaultLocale
+	}
+
+	_, index, confidence := matcher.Match(tags...)
+	if confidence == language.No {
+		return DefaultLocale
+	}
+
+	switch index {
+	case 1:
+		return Russian
+	default:
+		return English
+	}
+}
+
+type contextKey struct{}
+
+func ContextWithLocale(ctx context.Context, locale string) context.Context {
+	return context.WithValue(ctx, contextKey{}, locale)
+}
+
+// LocaleFromContext returns the locale negotiated for the request, if the
+// client asked for one.
+func LocaleFromContext(ctx context.Context) (string, bool) {
+	locale, ok := ctx.Value(contextKey{}).(string)
+	return locale, ok && locale != ""
+}
@@ -0,0 +1,48 @@
+package i18n
+
+import (
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{header: "", want: English},
+		{header: "ru", want: Russian},
+		{header: "ru-RU,ru;q=0.9,en-US;q=0.8,en;q=0.7", want: Russian},
+		{header: "en-GB", want: English},
+		{header: "de-DE, ru;q=0.5", want: Russian},
+		{header: "de-DE", want: English},
+		{header: "en;q=0.5, ru;q=0.9", want: Russian},
+		{header: "not a language tag;;", want: English},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			assert.Equal(t, tt.want, Negotiate(tt.header))
+		})
+	}
+}
+
+func TestMessage_Localize(t *testing.T) {
+	msg := New(ReasonTooLong, 50)
+
+	assert.Equal(t, "must not be more than 50 characters long", msg.String())
+	assert.Equal(t, "количество символов не должно превышать 50", msg.Localize(Russian))
+	assert.Equal(t, msg.String(), msg.Localize("de"), "unsupported locales must fall back to English")
+	assert.Equal(t, "as is", Raw("as is").Localize(Russian))
+}
+
+func TestCatalogs_Complete(t *testing.T) {
+	want := slices.Sorted(maps.Keys(english))
+
+	for locale, catalog := range catalogs {
+		assert.Equal(t, want, slices.Sorted(maps.Keys(catalog)), "catalog %q must translate every reason", locale)
+	}
+}
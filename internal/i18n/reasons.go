@@ -0,0 +1,58 @@
+package i18n
+
+// Reasons of request-level errors.
+const (
+	ReasonUserNotFound           = "USER_NOT_FOUND"
+	ReasonEditConflict           = "EDIT_CONFLICT"
+	ReasonRateLimitExceeded      = "RATE_LIMIT_EXCEEDED"
+	ReasonInvalidCredentials     = "INVALID_CREDENTIALS"
+	ReasonInvalidToken           = "INVALID_TOKEN"
+	ReasonAuthenticationRequired = "AUTHENTICATION_REQUIRED"
+	ReasonAccountInactive        = "ACCOUNT_INACTIVE"
+	ReasonNotPermitted           = "NOT_PERMITTED"
+	ReasonInternal               = "INTERNAL"
+	ReasonInvalidRequest         = "INVALID_REQUEST"
+	ReasonInvalidArgument        = "INVALID_ARGUMENT"
+	ReasonUnavailable            = "UNAVAILABLE"
+	ReasonCanceled               = "CANCELED"
+	ReasonDeadlineExceeded       = "DEADLINE_EXCEEDED"
+	ReasonTenantMismatch         = "TENANT_MISMATCH"
+	ReasonBodyTooLarge           = "BODY_TOO_LARGE"
+	ReasonBodyEmpty              = "BODY_EMPTY"
+	ReasonBodyMalformed          = "BODY_MALFORMED"
+)
+
+// Reasons of field violations.
+const (
+	ReasonRequired          = "REQUIRED"
+	ReasonBlank             = "BLANK"
+	ReasonTooShort          = "TOO_SHORT"
+	ReasonTooLong           = "TOO_LONG"
+	ReasonInvalidUTF8       = "INVALID_UTF8"
+	ReasonControlCharacters = "CONTROL_CHARACTERS"
+	ReasonNotOneOf          = "NOT_ONE_OF"
+	ReasonNotGreaterThan    = "NOT_GREATER_THAN"
+	ReasonBelowMinimum      = "BELOW_MINIMUM"
+	ReasonAboveMaximum      = "ABOVE_MAXIMUM"
+	ReasonOutOfRange        = "OUT_OF_RANGE"
+	ReasonNotAboveZero      = "NOT_ABOVE_ZERO"
+	ReasonNotPositive       = "NOT_POSITIVE"
+	ReasonNotInteger        = "NOT_INTEGER"
+	ReasonInvalidEmail      = "INVALID_EMAIL"
+	ReasonInvalidPhone      = "INVALID_PHONE"
+	ReasonInvalidLocale     = "INVALID_LOCALE"
+	ReasonInvalidTimeZone   = "INVALID_TIME_ZONE"
+	ReasonInvalidDate       = "INVALID_DATE"
+	ReasonInvalidTimestamp  = "INVALID_TIMESTAMP"
+	ReasonDateInFuture      = "DATE_IN_FUTURE"
+	ReasonDateTooFarInPast  = "DATE_TOO_FAR_IN_PAST"
+	ReasonTooManyEntries    = "TOO_MANY_ENTRIES"
+	ReasonInvalidKey        = "INVALID_KEY"
+	ReasonNotAfter          = "NOT_AFTER"
+	ReasonDuplicateEmail    = "DUPLICATE_EMAIL"
+	ReasonInvalidSort       = "INVALID_SORT"
+	ReasonPageTooLarge      = "PAGE_TOO_LARGE"
+	ReasonInvalidTenant     = "INVALID_TENANT"
+	ReasonLookupKeyMissing  = "LOOKUP_KEY_MISSING"
+	ReasonLookupKeyConflict = "LOOKUP_KEY_CONFLICT"
+)
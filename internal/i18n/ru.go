@@ -0,0 +1,54 @@
+package i18n
+
+var russian = map[string]string{
+	ReasonUserNotFound:           "пользователь не найден",
+	ReasonEditConflict:           "не удалось сохранить изменения из-за конфликта правок, попробуйте ещё раз",
+	ReasonRateLimitExceeded:      "превышен лимит запросов",
+	ReasonInvalidCredentials:     "неверные учётные данные",
+	ReasonInvalidToken:           "токен аутентификации отсутствует или недействителен",
+	ReasonAuthenticationRequired: "для доступа к этому ресурсу необходимо пройти аутентификацию",
+	ReasonAccountInactive:        "учётная запись пользователя должна быть активирована",
+	ReasonNotPermitted:           "у вас нет прав на доступ к этому ресурсу",
+	ReasonInternal:               "на сервере произошла ошибка",
+	ReasonInvalidRequest:         "некорректный запрос",
+	ReasonInvalidArgument:        "некорректный аргумент",
+	ReasonUnavailable:            "сервис временно недоступен, попробуйте позже",
+	ReasonCanceled:               "запрос отменён",
+	ReasonDeadlineExceeded:       "запрос не завершился до истечения срока",
+	ReasonTenantMismatch:         "запрошенный арендатор не совпадает с клиентским сертификатом",
+	ReasonBodyTooLarge:           "размер тела запроса не должен превышать %d байт",
+	ReasonBodyEmpty:              "тело запроса не должно быть пустым",
+	ReasonBodyMalformed:          "тело запроса содержит некорректный JSON",
+
+	ReasonRequired:          "обязательное поле",
+	ReasonBlank:             "не может состоять только из пробелов",
+	ReasonTooShort:          "количество символов должно быть не меньше %d",
+	ReasonTooLong:           "количество символов не должно превышать %d",
+	ReasonInvalidUTF8:       "должно быть корректным текстом в UTF-8",
+	ReasonControlCharacters: "не должно содержать управляющих символов",
+	ReasonNotOneOf:          "допустимые значения: %s",
+	ReasonNotGreaterThan:    "должно быть больше %v",
+	ReasonBelowMinimum:      "должно быть не меньше %v",
+	ReasonAboveMaximum:      "должно быть не больше %v",
+	ReasonOutOfRange:        "должно быть в диапазоне от %v до %v",
+	ReasonNotAboveZero:      "должно быть больше нуля",
+	ReasonNotPositive:       "должно быть положительным целым числом",
+	ReasonNotInteger:        "должно быть целым числом",
+	ReasonInvalidEmail:      "должно быть корректным адресом электронной почты",
+	ReasonInvalidPhone:      "должно быть номером телефона в международном формате, например +79991234567",
+	ReasonInvalidLocale:     "должно быть корректным языковым тегом BCP 47, например ru-RU",
+	ReasonInvalidTimeZone:   "должно быть корректным часовым поясом IANA, например Europe/Moscow",
+	ReasonInvalidDate:       "должно быть датой в формате ГГГГ-ММ-ДД",
+	ReasonInvalidTimestamp:  "должно быть датой в формате ГГГГ-ММ-ДД или меткой времени RFC 3339",
+	ReasonDateInFuture:      "не может быть в будущем",
+	ReasonDateTooFarInPast:  "не может быть раньше, чем %d лет назад",
+	ReasonTooManyEntries:    "количество элементов не может превышать %d",
+	ReasonInvalidKey:        "должно быть ключом из строчных латинских букв, цифр, '_', '.' или '-', начинающимся с буквы",
+	ReasonNotAfter:          "должно быть позже, чем %s",
+	ReasonDuplicateEmail:    "пользователь с таким адресом электронной почты уже существует",
+	ReasonInvalidSort:       "недопустимое значение сортировки",
+	ReasonPageTooLarge:      "должно быть не больше 10 миллионов",
+	ReasonInvalidTenant:     "может содержать только строчные латинские буквы, цифры, '-' и '_' (не более 63 символов)",
+	ReasonLookupKeyMissing:  "необходимо указать id или email",
+	ReasonLookupKeyConflict: "можно указать только один из параметров: id или email",
+}
@@ -0,0 +1,145 @@
+package validator
+
+import (
+	"cmp"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/Vadim-Makhnev/grpc/internal/i18n"
+)
+
+// Rule checks a single value and returns ok, or a message describing the
+// problem.
+//
+// String rules other than NotBlank accept the empty string, so an optional
+// field only needs NotBlank added when it is required.
+type Rule[T any] func(value T) (msg i18n.Message, ok bool)
+
+// Message returns a rule that reports msg instead of r's own message.
+func (r Rule[T]) Message(msg i18n.Message) Rule[T] {
+	return func(value T) (i18n.Message, bool) {
+		if _, ok := r(value); ok {
+			return i18n.Message{}, true
+		}
+		return msg, false
+	}
+}
+
+// Field runs every rule against value and records each failure under key, so
+// a field can report several problems at once.
+func Field[T any](v *Validator, key string, value T, rules ...Rule[T]) {
+	for _, rule := range rules {
+		if msg, ok := rule(value); !ok {
+			v.Add(key, msg)
+		}
+	}
+}
+
+func check(ok bool, reason string, args ...any) (i18n.Message, bool) {
+	if ok {
+		return i18n.Message{}, true
+	}
+	return i18n.New(reason, args...), false
+}
+
+func Required[T comparable]() Rule[T] {
+	return func(value T) (i18n.Message, bool) {
+		var zero T
+		return check(value != zero, i18n.ReasonRequired)
+	}
+}
+
+func NotBlank() Rule[string] {
+	return func(value string) (i18n.Message, bool) {
+		if value == "" {
+			return check(false, i18n.ReasonRequired)
+		}
+		return check(strings.TrimFunc(value, unicode.IsSpace) != "", i18n.ReasonBlank)
+	}
+}
+
+func MinRunes(n int) Rule[string] {
+	return func(value string) (i18n.Message, bool) {
+		return check(value == "" || utf8.RuneCountInString(value) >= n, i18n.ReasonTooShort, n)
+	}
+}
+
+func MaxRunes(n int) Rule[string] {
+	return func(value string) (i18n.Message, bool) {
+		return check(utf8.RuneCountInString(value) <= n, i18n.ReasonTooLong, n)
+	}
+}
+
+func ValidUTF8() Rule[string] {
+	return func(value string) (i18n.Message, bool) {
+		return check(utf8.ValidString(value), i18n.ReasonInvalidUTF8)
+	}
+}
+
+// NoControlChars rejects C0 and C1 control characters, including tabs and
+// line breaks.
+func NoControlChars() Rule[string] {
+	return func(value string) (i18n.Message, bool) {
+		return check(strings.IndexFunc(value, unicode.IsControl) < 0, i18n.ReasonControlCharacters)
+	}
+}
+
+func Match(rx *regexp.Regexp, msg i18n.Message) Rule[string] {
+	return func(value string) (i18n.Message, bool) {
+		if value == "" || rx.MatchString(value) {
+			return i18n.Message{}, true
+		}
+		return msg, false
+	}
+}
+
+func OneOf(list ...string) Rule[string] {
+	return func(value string) (i18n.Message, bool) {
+		return check(In(value, list...), i18n.ReasonNotOneOf, strings.Join(list, ", "))
+	}
+}
+
+func GreaterThan[T cmp.Ordered](n T) Rule[T] {
+	return func(value T) (i18n.Message, bool) {
+		return check(value > n, i18n.ReasonNotGreaterThan, n)
+	}
+}
+
+func Min[T cmp.Ordered](n T) Rule[T] {
+	return func(value T) (i18n.Message, bool) {
+		return check(value >= n, i18n.ReasonBelowMinimum, n)
+	}
+}
+
+func Max[T cmp.Ordered](n T) Rule[T] {
+	return func(value T) (i18n.Message, bool) {
+		return check(value <= n, i18n.ReasonAboveMaximum, n)
+	}
+}
+
+func Between[T cmp.Ordered](lo, hi T) Rule[T] {
+	return func(value T) (i18n.Message, bool) {
+		return check(value >= lo && value <= hi, i18n.ReasonOutOfRange, lo, hi)
+	}
+}
+
+func NotBefore(t time.Time) Rule[time.Time] {
+	return func(value time.Time) (i18n.Message, bool) {
+		return check(!value.Before(t), i18n.ReasonBelowMinimum, t.Format(time.DateOnly))
+	}
+}
+
+func NotAfter(t time.Time) Rule[time.Time] {
+	return func(value time.Time) (i18n.Message, bool) {
+		return check(!value.After(t), i18n.ReasonAboveMaximum, t.Format(time.DateOnly))
+	}
+}
+
+func MaxEntries[M ~map[K]V, K comparable, V any](n int) Rule[M] {
+	return func(value M) (i18n.Message, bool) {
+		return check(len(value) <= n, i18n.ReasonTooManyEntries, n)
+	}
+}
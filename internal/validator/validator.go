@@ -1,18 +1,34 @@
 package validator
 
-import "regexp"
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/Vadim-Makhnev/grpc/internal/i18n"
+)
 
 var (
 	EmailRX = regexp.MustCompile("^[a-zA-Z0-9.!#$&'*+\\/=?^_`{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$")
 )
 
+// Validator collects validation errors keyed by field path. Errors holds the
+// first message reported for each field in English, FieldErrors every distinct
+// message in the order it was reported, ready to be localized.
 type Validator struct {
-	Errors map[string]string
+	Errors      map[string]string
+	FieldErrors map[string][]i18n.Message
+
+	prefix string
 }
 
 func New() *Validator {
 	return &Validator{
-		Errors: make(map[string]string),
+		Errors:      make(map[string]string),
+		FieldErrors: make(map[string][]i18n.Message),
 	}
 }
 
@@ -21,9 +37,29 @@ func (v *Validator) Valid() bool {
 }
 
 func (v *Validator) AddError(key, message string) {
+	v.Add(key, i18n.Raw(message))
+}
+
+func (v *Validator) Add(key string, msg i18n.Message) {
+	key = v.prefix + key
+	text := msg.String()
+
 	if _, exists := v.Errors[key]; !exists {
-		v.Errors[key] = message
+		v.Errors[key] = text
 	}
+
+	if !slices.ContainsFunc(v.FieldErrors[key], func(m i18n.Message) bool { return m.String() == text }) {
+		v.FieldErrors[key] = append(v.FieldErrors[key], msg)
+	}
+}
+
+// Err returns a *ValidationError with the collected errors, or nil if there
+// are none.
+func (v *Validator) Err() error {
+	if v.Valid() {
+		return nil
+	}
+	return &ValidationError{Fields: v.FieldErrors}
 }
 
 func (v *Validator) Check(ok bool, key, message string) {
@@ -32,6 +68,41 @@ func (v *Validator) Check(ok bool, key, message string) {
 	}
 }
 
+// Scope returns a validator that shares v's errors but reports keys nested
+// under key, so that Scope("address").AddError("city", ...) is recorded as
+// "address.city".
+func (v *Validator) Scope(key string) *Validator {
+	return &Validator{
+		Errors:      v.Errors,
+		FieldErrors: v.FieldErrors,
+		prefix:      v.prefix + key + ".",
+	}
+}
+
+// Index returns the path of the i-th element of a list field, e.g. "tags[2]".
+func Index(key string, i int) string {
+	return key + "[" + strconv.Itoa(i) + "]"
+}
+
+// ValidationError reports the failed checks of a Validator.
+type ValidationError struct {
+	Fields map[string][]i18n.Message
+}
+
+func (e *ValidationError) Error() string {
+	var b strings.Builder
+
+	b.WriteString("validation failed")
+
+	for _, field := range slices.Sorted(maps.Keys(e.Fields)) {
+		for _, msg := range e.Fields[field] {
+			fmt.Fprintf(&b, "; %s: %s", field, msg)
+		}
+	}
+
+	return b.String()
+}
+
 func In(value string, list ...string) bool {
 	for i := range list {
 		if value == list[i] {
@@ -0,0 +1,99 @@
+package validator
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/Vadim-Makhnev/grpc/internal/i18n"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestField(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		rules []Rule[string]
+		want  []string
+	}{
+		{name: "valid", value: "Андрей", rules: []Rule[string]{NotBlank(), MinRunes(2), MaxRunes(6)}},
+		{name: "empty", value: "", rules: []Rule[string]{NotBlank(), MinRunes(2)}, want: []string{"must be provided"}},
+		{name: "blank", value: " \t ", rules: []Rule[string]{NotBlank()}, want: []string{"must not be blank"}},
+		{name: "too short", value: "я", rules: []Rule[string]{MinRunes(2)}, want: []string{"must be at least 2 characters long"}},
+		{name: "too long in runes", value: "Андрей!", rules: []Rule[string]{MaxRunes(6)}, want: []string{"must not be more than 6 characters long"}},
+		{name: "control characters", value: "An\x00drew", rules: []Rule[string]{NoControlChars()}, want: []string{"must not contain control characters"}},
+		{name: "invalid utf-8", value: "An\xffdrew", rules: []Rule[string]{ValidUTF8()}, want: []string{"must be valid UTF-8 text"}},
+		{name: "one of", value: "b", rules: []Rule[string]{OneOf("a", "c")}, want: []string{"must be one of a, c"}},
+		{name: "match skips empty", value: "", rules: []Rule[string]{Match(regexp.MustCompile("^a+$"), i18n.Raw("must be a's"))}},
+		{
+			name:  "several errors",
+			value: "\n" + strings.Repeat("x", 10),
+			rules: []Rule[string]{MaxRunes(5), NoControlChars(), Match(regexp.MustCompile("^x+$"), i18n.Raw("must contain only x"))},
+			want:  []string{"must not be more than 5 characters long", "must not contain control characters", "must contain only x"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := New()
+			Field(v, "field", tt.value, tt.rules...)
+
+			assert.Equal(t, tt.want, texts(v.FieldErrors["field"]))
+			if len(tt.want) > 0 {
+				assert.Equal(t, tt.want[0], v.Errors["field"])
+			}
+		})
+	}
+}
+
+func TestField_Ranges(t *testing.T) {
+	v := New()
+
+	Field(v, "a", 0, GreaterThan(0))
+	Field(v, "b", 11, Min(1), Max(10))
+	Field(v, "c", 2.5, Between(0.0, 1.0))
+	Field(v, "d", 5, Between(1, 10))
+	Field(v, "e", 101, Max(100).Message(i18n.Raw("too big")))
+
+	assert.Equal(t, map[string]string{
+		"a": "must be greater than 0",
+		"b": "must be a maximum of 10",
+		"c": "must be between 0 and 1",
+		"e": "too big",
+	}, v.Errors)
+}
+
+func TestValidator_AddError(t *testing.T) {
+	v := New()
+
+	v.AddError("name", "must be provided")
+	v.AddError("name", "must be provided")
+	v.Add("name", i18n.New(i18n.ReasonBlank))
+
+	assert.Equal(t, "must be provided", v.Errors["name"])
+	assert.Equal(t, []string{"must be provided", "must not be blank"}, texts(v.FieldErrors["name"]))
+}
+
+func TestValidator_Scope(t *testing.T) {
+	v := New()
+
+	address := v.Scope("address")
+	Field(address, "city", "", NotBlank())
+	address.Scope("geo").AddError("lat", "must be between -90 and 90")
+	Field(v, Index("tags", 2), "", Required[string]())
+
+	assert.False(t, v.Valid())
+	assert.Equal(t, map[string]string{
+		"address.city":    "must be provided",
+		"address.geo.lat": "must be between -90 and 90",
+		"tags[2]":         "must be provided",
+	}, v.Errors)
+}
+
+func texts(msgs []i18n.Message) []string {
+	var out []string
+	for _, msg := range msgs {
+		out = append(out, msg.String())
+	}
+	return out
+}
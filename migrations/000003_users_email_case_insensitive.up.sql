@@ -0,0 +1,22 @@
+DO $$
+DECLARE
+    duplicates TEXT;
+BEGIN
+    SELECT string_agg(format('tenant %s, email %s: ids %s', tenant_id, email_key, ids), E'\n')
+    INTO duplicates
+    FROM (
+        SELECT tenant_id, lower(email) AS email_key, string_agg(id::text, ', ' ORDER BY id) AS ids
+        FROM users
+        GROUP BY tenant_id, lower(email)
+        HAVING count(*) > 1
+    ) d;
+
+    IF duplicates IS NOT NULL THEN
+        RAISE EXCEPTION 'users with emails that differ only in case must be merged or renamed before applying this migration'
+            USING DETAIL = duplicates;
+    END IF;
+END $$;
+
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_tenant_id_email_key;
+
+CREATE UNIQUE INDEX IF NOT EXISTS users_tenant_id_lower_email_key ON users (tenant_id, lower(email));
@@ -0,0 +1,27 @@
+DROP TRIGGER IF EXISTS users_set_updated_at ON users;
+DROP FUNCTION IF EXISTS users_set_updated_at();
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS age INT;
+
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'users' AND column_name = 'birthdate') THEN
+        UPDATE users
+        SET age = GREATEST(date_part('year', age(CURRENT_DATE, birthdate))::int, 1)
+        WHERE age IS NULL;
+    END IF;
+
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'check_age') THEN
+        ALTER TABLE users ADD CONSTRAINT check_age CHECK (age > 0);
+    END IF;
+END $$;
+
+ALTER TABLE users ALTER COLUMN age SET NOT NULL;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS birthdate,
+    DROP COLUMN IF EXISTS display_name,
+    DROP COLUMN IF EXISTS phone,
+    DROP COLUMN IF EXISTS locale,
+    DROP COLUMN IF EXISTS time_zone,
+    DROP COLUMN IF EXISTS updated_at;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_users_attributes;
+
+ALTER TABLE users DROP CONSTRAINT IF EXISTS check_attributes_object;
+ALTER TABLE users DROP COLUMN IF EXISTS attributes;
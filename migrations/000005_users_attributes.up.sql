@@ -0,0 +1,11 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS attributes JSONB NOT NULL DEFAULT '{}';
+
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'check_attributes_object') THEN
+        ALTER TABLE users ADD CONSTRAINT check_attributes_object CHECK (jsonb_typeof(attributes) = 'object');
+    END IF;
+END $$;
+
+-- Serves the containment (@>) and key existence (?, ?&) filters of ListUsers.
+CREATE INDEX IF NOT EXISTS idx_users_attributes ON users USING GIN (attributes);
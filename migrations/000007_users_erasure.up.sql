@@ -0,0 +1,3 @@
+-- Set when EraseUser anonymizes a user. The row itself is kept so its id and
+-- version stay valid for anything that refers to them.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS erased_at TIMESTAMPTZ;
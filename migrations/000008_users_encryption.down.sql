@@ -0,0 +1,40 @@
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'users' AND column_name = 'name_encrypted') THEN
+        IF EXISTS (SELECT 1 FROM users WHERE name_encrypted IS NOT NULL OR email_encrypted IS NOT NULL) THEN
+            RAISE EXCEPTION 'users with encrypted names or emails must be decrypted before reverting this migration';
+        END IF;
+    END IF;
+END $$;
+
+CREATE OR REPLACE FUNCTION users_set_updated_at() RETURNS trigger AS $$
+BEGIN
+    NEW.updated_at = NOW();
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+ALTER TABLE users DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE users ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('simple', name || ' ' || display_name), 'A') ||
+    setweight(to_tsvector('simple', email || ' ' || replace(email, '@', ' ')), 'B')
+) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_users_search_vector ON users USING GIN (search_vector);
+
+DROP INDEX IF EXISTS idx_users_display_name_search;
+DROP INDEX IF EXISTS idx_users_encryption_key_version;
+DROP INDEX IF EXISTS users_tenant_id_email_hash_key;
+
+ALTER TABLE users DROP CONSTRAINT IF EXISTS check_name_stored;
+ALTER TABLE users DROP CONSTRAINT IF EXISTS check_email_stored;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS name_encrypted,
+    DROP COLUMN IF EXISTS email_encrypted,
+    DROP COLUMN IF EXISTS email_hash,
+    DROP COLUMN IF EXISTS email_domain,
+    DROP COLUMN IF EXISTS encryption_key_version;
+
+ALTER TABLE users ALTER COLUMN name SET NOT NULL;
+ALTER TABLE users ALTER COLUMN email SET NOT NULL;
@@ -0,0 +1,56 @@
+-- With field encryption enabled, name and email are NULL and their values are
+-- kept in name_encrypted and email_encrypted instead.
+ALTER TABLE users ALTER COLUMN name DROP NOT NULL;
+ALTER TABLE users ALTER COLUMN email DROP NOT NULL;
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS name_encrypted BYTEA;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email_encrypted BYTEA;
+-- Keyed HMAC of lower(email). It stands in for the email in lookups and in the
+-- unique constraint.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email_hash BYTEA;
+-- Domain of an encrypted email, kept in plaintext for the domain statistics.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email_domain TEXT;
+-- Version of the key that wraps the data keys of the encrypted columns, NULL
+-- while they are in plaintext.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS encryption_key_version INTEGER;
+
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'check_name_stored') THEN
+        ALTER TABLE users ADD CONSTRAINT check_name_stored CHECK ((name IS NULL) <> (name_encrypted IS NULL));
+    END IF;
+
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'check_email_stored') THEN
+        ALTER TABLE users ADD CONSTRAINT check_email_stored CHECK ((email IS NULL) <> (email_encrypted IS NULL));
+    END IF;
+END $$;
+
+CREATE UNIQUE INDEX IF NOT EXISTS users_tenant_id_email_hash_key ON users (tenant_id, email_hash);
+
+-- Finds the users that the re-encryption job still has to rewrite.
+CREATE INDEX IF NOT EXISTS idx_users_encryption_key_version ON users (tenant_id, encryption_key_version);
+
+-- search_vector has to cope with NULL names and emails. Generated columns can
+-- only be changed by adding them again before PostgreSQL 17.
+ALTER TABLE users DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE users ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('simple', coalesce(name, '') || ' ' || display_name), 'A') ||
+    setweight(to_tsvector('simple', coalesce(email, '') || ' ' || replace(coalesce(email, ''), '@', ' ')), 'B')
+) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_users_search_vector ON users USING GIN (search_vector);
+
+-- With field encryption enabled, SearchUsers matches display_name alone, for
+-- plaintext and encrypted users alike.
+CREATE INDEX IF NOT EXISTS idx_users_display_name_search ON users USING GIN (to_tsvector('simple', display_name));
+
+-- Re-encryption rewrites rows without changing what they hold, so only the
+-- updates that bump the version count as changes.
+CREATE OR REPLACE FUNCTION users_set_updated_at() RETURNS trigger AS $$
+BEGIN
+    IF NEW.version IS DISTINCT FROM OLD.version THEN
+        NEW.updated_at = NOW();
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
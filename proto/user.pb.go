@@ -9,6 +9,7 @@ package proto
 import (
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
 	wrapperspb "google.golang.org/protobuf/types/known/wrapperspb"
 	reflect "reflect"
 	sync "sync"
@@ -23,10 +24,19 @@ const (
 )
 
 type CreateUserRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
-	Email         string                 `protobuf:"bytes,2,opt,name=email,proto3" json:"email,omitempty"`
-	Age           int32                  `protobuf:"varint,3,opt,name=age,proto3" json:"age,omitempty"`
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Name  string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Email string                 `protobuf:"bytes,2,opt,name=email,proto3" json:"email,omitempty"`
+	// Deprecated: send birthdate instead. Only used when birthdate is empty.
+	Age         int32  `protobuf:"varint,3,opt,name=age,proto3" json:"age,omitempty"`
+	DisplayName string `protobuf:"bytes,4,opt,name=display_name,json=displayName,proto3" json:"display_name,omitempty"`
+	Phone       string `protobuf:"bytes,5,opt,name=phone,proto3" json:"phone,omitempty"`
+	Locale      string `protobuf:"bytes,6,opt,name=locale,proto3" json:"locale,omitempty"`
+	TimeZone    string `protobuf:"bytes,7,opt,name=time_zone,json=timeZone,proto3" json:"time_zone,omitempty"`
+	// YYYY-MM-DD
+	Birthdate string `protobuf:"bytes,8,opt,name=birthdate,proto3" json:"birthdate,omitempty"`
+	// Attributes with an empty value are ignored.
+	Attributes    map[string]string `protobuf:"bytes,9,rep,name=attributes,proto3" json:"attributes,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -82,6 +92,48 @@ func (x *CreateUserRequest) GetAge() int32 {
 	return 0
 }
 
+func (x *CreateUserRequest) GetDisplayName() string {
+	if x != nil {
+		return x.DisplayName
+	}
+	return ""
+}
+
+func (x *CreateUserRequest) GetPhone() string {
+	if x != nil {
+		return x.Phone
+	}
+	return ""
+}
+
+func (x *CreateUserRequest) GetLocale() string {
+	if x != nil {
+		return x.Locale
+	}
+	return ""
+}
+
+func (x *CreateUserRequest) GetTimeZone() string {
+	if x != nil {
+		return x.TimeZone
+	}
+	return ""
+}
+
+func (x *CreateUserRequest) GetBirthdate() string {
+	if x != nil {
+		return x.Birthdate
+	}
+	return ""
+}
+
+func (x *CreateUserRequest) GetAttributes() map[string]string {
+	if x != nil {
+		return x.Attributes
+	}
+	return nil
+}
+
 type GetUserRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
@@ -127,10 +179,14 @@ func (x *GetUserRequest) GetId() int64 {
 }
 
 type ListUsersRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Page          int32                  `protobuf:"varint,1,opt,name=page,proto3" json:"page,omitempty"`
-	PageSize      int32                  `protobuf:"varint,2,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
-	Sort          string                 `protobuf:"bytes,3,opt,name=sort,proto3" json:"sort,omitempty"`
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Page     int32                  `protobuf:"varint,1,opt,name=page,proto3" json:"page,omitempty"`
+	PageSize int32                  `protobuf:"varint,2,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	Sort     string                 `protobuf:"bytes,3,opt,name=sort,proto3" json:"sort,omitempty"`
+	// Only users with all of these attribute values.
+	Attributes map[string]string `protobuf:"bytes,4,rep,name=attributes,proto3" json:"attributes,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	// Only users that have all of these attribute keys, whatever their values.
+	HasAttributes []string `protobuf:"bytes,5,rep,name=has_attributes,json=hasAttributes,proto3" json:"has_attributes,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -186,13 +242,39 @@ func (x *ListUsersRequest) GetSort() string {
 	return ""
 }
 
+func (x *ListUsersRequest) GetAttributes() map[string]string {
+	if x != nil {
+		return x.Attributes
+	}
+	return nil
+}
+
+func (x *ListUsersRequest) GetHasAttributes() []string {
+	if x != nil {
+		return x.HasAttributes
+	}
+	return nil
+}
+
 type UserResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
-	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
-	Email         string                 `protobuf:"bytes,3,opt,name=email,proto3" json:"email,omitempty"`
-	Age           int32                  `protobuf:"varint,4,opt,name=age,proto3" json:"age,omitempty"`
-	Version       int32                  `protobuf:"varint,5,opt,name=version,proto3" json:"version,omitempty"`
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name  string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Email string                 `protobuf:"bytes,3,opt,name=email,proto3" json:"email,omitempty"`
+	// Computed from birthdate.
+	Age         int32  `protobuf:"varint,4,opt,name=age,proto3" json:"age,omitempty"`
+	Version     int32  `protobuf:"varint,5,opt,name=version,proto3" json:"version,omitempty"`
+	DisplayName string `protobuf:"bytes,6,opt,name=display_name,json=displayName,proto3" json:"display_name,omitempty"`
+	Phone       string `protobuf:"bytes,7,opt,name=phone,proto3" json:"phone,omitempty"`
+	Locale      string `protobuf:"bytes,8,opt,name=locale,proto3" json:"locale,omitempty"`
+	TimeZone    string `protobuf:"bytes,9,opt,name=time_zone,json=timeZone,proto3" json:"time_zone,omitempty"`
+	// YYYY-MM-DD
+	Birthdate  string                 `protobuf:"bytes,10,opt,name=birthdate,proto3" json:"birthdate,omitempty"`
+	CreatedAt  *timestamppb.Timestamp `protobuf:"bytes,11,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	UpdatedAt  *timestamppb.Timestamp `protobuf:"bytes,12,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	Attributes map[string]string      `protobuf:"bytes,13,rep,name=attributes,proto3" json:"attributes,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	// Set once EraseUser has anonymized the user.
+	ErasedAt      *timestamppb.Timestamp `protobuf:"bytes,14,opt,name=erased_at,json=erasedAt,proto3" json:"erased_at,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -262,6 +344,69 @@ func (x *UserResponse) GetVersion() int32 {
 	return 0
 }
 
+func (x *UserResponse) GetDisplayName() string {
+	if x != nil {
+		return x.DisplayName
+	}
+	return ""
+}
+
+func (x *UserResponse) GetPhone() string {
+	if x != nil {
+		return x.Phone
+	}
+	return ""
+}
+
+func (x *UserResponse) GetLocale() string {
+	if x != nil {
+		return x.Locale
+	}
+	return ""
+}
+
+func (x *UserResponse) GetTimeZone() string {
+	if x != nil {
+		return x.TimeZone
+	}
+	return ""
+}
+
+func (x *UserResponse) GetBirthdate() string {
+	if x != nil {
+		return x.Birthdate
+	}
+	return ""
+}
+
+func (x *UserResponse) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *UserResponse) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
+func (x *UserResponse) GetAttributes() map[string]string {
+	if x != nil {
+		return x.Attributes
+	}
+	return nil
+}
+
+func (x *UserResponse) GetErasedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ErasedAt
+	}
+	return nil
+}
+
 type ListUsersResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Users         []*UserResponse        `protobuf:"bytes,1,rep,name=users,proto3" json:"users,omitempty"`
@@ -315,11 +460,21 @@ func (x *ListUsersResponse) GetMetadata() *MetaData {
 }
 
 type UpdateUserRequest struct {
-	state         protoimpl.MessageState  `protogen:"open.v1"`
-	Id            int64                   `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
-	Name          *wrapperspb.StringValue `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
-	Email         *wrapperspb.StringValue `protobuf:"bytes,3,opt,name=email,proto3" json:"email,omitempty"`
-	Age           *wrapperspb.Int32Value  `protobuf:"bytes,4,opt,name=age,proto3" json:"age,omitempty"`
+	state protoimpl.MessageState  `protogen:"open.v1"`
+	Id    int64                   `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name  *wrapperspb.StringValue `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Email *wrapperspb.StringValue `protobuf:"bytes,3,opt,name=email,proto3" json:"email,omitempty"`
+	// Deprecated: send birthdate instead. Ignored when birthdate is set.
+	Age         *wrapperspb.Int32Value  `protobuf:"bytes,4,opt,name=age,proto3" json:"age,omitempty"`
+	DisplayName *wrapperspb.StringValue `protobuf:"bytes,5,opt,name=display_name,json=displayName,proto3" json:"display_name,omitempty"`
+	Phone       *wrapperspb.StringValue `protobuf:"bytes,6,opt,name=phone,proto3" json:"phone,omitempty"`
+	Locale      *wrapperspb.StringValue `protobuf:"bytes,7,opt,name=locale,proto3" json:"locale,omitempty"`
+	TimeZone    *wrapperspb.StringValue `protobuf:"bytes,8,opt,name=time_zone,json=timeZone,proto3" json:"time_zone,omitempty"`
+	// YYYY-MM-DD
+	Birthdate *wrapperspb.StringValue `protobuf:"bytes,9,opt,name=birthdate,proto3" json:"birthdate,omitempty"`
+	// Merged into the current attributes key by key: an empty value removes
+	// the key, any other value sets it, and keys not mentioned are kept.
+	Attributes    map[string]string `protobuf:"bytes,10,rep,name=attributes,proto3" json:"attributes,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -382,6 +537,48 @@ func (x *UpdateUserRequest) GetAge() *wrapperspb.Int32Value {
 	return nil
 }
 
+func (x *UpdateUserRequest) GetDisplayName() *wrapperspb.StringValue {
+	if x != nil {
+		return x.DisplayName
+	}
+	return nil
+}
+
+func (x *UpdateUserRequest) GetPhone() *wrapperspb.StringValue {
+	if x != nil {
+		return x.Phone
+	}
+	return nil
+}
+
+func (x *UpdateUserRequest) GetLocale() *wrapperspb.StringValue {
+	if x != nil {
+		return x.Locale
+	}
+	return nil
+}
+
+func (x *UpdateUserRequest) GetTimeZone() *wrapperspb.StringValue {
+	if x != nil {
+		return x.TimeZone
+	}
+	return nil
+}
+
+func (x *UpdateUserRequest) GetBirthdate() *wrapperspb.StringValue {
+	if x != nil {
+		return x.Birthdate
+	}
+	return nil
+}
+
+func (x *UpdateUserRequest) GetAttributes() map[string]string {
+	if x != nil {
+		return x.Attributes
+	}
+	return nil
+}
+
 type DeleteUserRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
@@ -426,29 +623,27 @@ func (x *DeleteUserRequest) GetId() int64 {
 	return 0
 }
 
-type MetaData struct {
+type ExportUserDataRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	TotalRecords  int32                  `protobuf:"varint,1,opt,name=total_records,json=totalRecords,proto3" json:"total_records,omitempty"`
-	Page          int32                  `protobuf:"varint,2,opt,name=page,proto3" json:"page,omitempty"`
-	PageSize      int32                  `protobuf:"varint,3,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *MetaData) Reset() {
-	*x = MetaData{}
+func (x *ExportUserDataRequest) Reset() {
+	*x = ExportUserDataRequest{}
 	mi := &file_user_proto_msgTypes[7]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *MetaData) String() string {
+func (x *ExportUserDataRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*MetaData) ProtoMessage() {}
+func (*ExportUserDataRequest) ProtoMessage() {}
 
-func (x *MetaData) ProtoReflect() protoreflect.Message {
+func (x *ExportUserDataRequest) ProtoReflect() protoreflect.Message {
 	mi := &file_user_proto_msgTypes[7]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -460,53 +655,101 @@ func (x *MetaData) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use MetaData.ProtoReflect.Descriptor instead.
-func (*MetaData) Descriptor() ([]byte, []int) {
+// Deprecated: Use ExportUserDataRequest.ProtoReflect.Descriptor instead.
+func (*ExportUserDataRequest) Descriptor() ([]byte, []int) {
 	return file_user_proto_rawDescGZIP(), []int{7}
 }
 
-func (x *MetaData) GetTotalRecords() int32 {
+func (x *ExportUserDataRequest) GetId() int64 {
 	if x != nil {
-		return x.TotalRecords
+		return x.Id
 	}
 	return 0
 }
 
-func (x *MetaData) GetPage() int32 {
+// UserDataExport is everything the service stores about a user.
+type UserDataExport struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TenantId      string                 `protobuf:"bytes,1,opt,name=tenant_id,json=tenantId,proto3" json:"tenant_id,omitempty"`
+	User          *UserResponse          `protobuf:"bytes,2,opt,name=user,proto3" json:"user,omitempty"`
+	ExportedAt    *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=exported_at,json=exportedAt,proto3" json:"exported_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UserDataExport) Reset() {
+	*x = UserDataExport{}
+	mi := &file_user_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UserDataExport) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UserDataExport) ProtoMessage() {}
+
+func (x *UserDataExport) ProtoReflect() protoreflect.Message {
+	mi := &file_user_proto_msgTypes[8]
 	if x != nil {
-		return x.Page
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
 	}
-	return 0
+	return mi.MessageOf(x)
 }
 
-func (x *MetaData) GetPageSize() int32 {
+// Deprecated: Use UserDataExport.ProtoReflect.Descriptor instead.
+func (*UserDataExport) Descriptor() ([]byte, []int) {
+	return file_user_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *UserDataExport) GetTenantId() string {
 	if x != nil {
-		return x.PageSize
+		return x.TenantId
 	}
-	return 0
+	return ""
 }
 
-type Empty struct {
+func (x *UserDataExport) GetUser() *UserResponse {
+	if x != nil {
+		return x.User
+	}
+	return nil
+}
+
+func (x *UserDataExport) GetExportedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ExportedAt
+	}
+	return nil
+}
+
+type EraseUserRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *Empty) Reset() {
-	*x = Empty{}
-	mi := &file_user_proto_msgTypes[8]
+func (x *EraseUserRequest) Reset() {
+	*x = EraseUserRequest{}
+	mi := &file_user_proto_msgTypes[9]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *Empty) String() string {
+func (x *EraseUserRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*Empty) ProtoMessage() {}
+func (*EraseUserRequest) ProtoMessage() {}
 
-func (x *Empty) ProtoReflect() protoreflect.Message {
-	mi := &file_user_proto_msgTypes[8]
+func (x *EraseUserRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_user_proto_msgTypes[9]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -517,119 +760,949 @@ func (x *Empty) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use Empty.ProtoReflect.Descriptor instead.
-func (*Empty) Descriptor() ([]byte, []int) {
-	return file_user_proto_rawDescGZIP(), []int{8}
+// Deprecated: Use EraseUserRequest.ProtoReflect.Descriptor instead.
+func (*EraseUserRequest) Descriptor() ([]byte, []int) {
+	return file_user_proto_rawDescGZIP(), []int{9}
 }
 
-var File_user_proto protoreflect.FileDescriptor
-
-const file_user_proto_rawDesc = "" +
-	"\n" +
-	"\n" +
-	"user.proto\x12\x04user\x1a\x1egoogle/protobuf/wrappers.proto\"O\n" +
-	"\x11CreateUserRequest\x12\x12\n" +
-	"\x04name\x18\x01 \x01(\tR\x04name\x12\x14\n" +
-	"\x05email\x18\x02 \x01(\tR\x05email\x12\x10\n" +
-	"\x03age\x18\x03 \x01(\x05R\x03age\" \n" +
-	"\x0eGetUserRequest\x12\x0e\n" +
-	"\x02id\x18\x01 \x01(\x03R\x02id\"W\n" +
-	"\x10ListUsersRequest\x12\x12\n" +
-	"\x04page\x18\x01 \x01(\x05R\x04page\x12\x1b\n" +
-	"\tpage_size\x18\x02 \x01(\x05R\bpageSize\x12\x12\n" +
-	"\x04sort\x18\x03 \x01(\tR\x04sort\"t\n" +
-	"\fUserResponse\x12\x0e\n" +
-	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x12\n" +
-	"\x04name\x18\x02 \x01(\tR\x04name\x12\x14\n" +
-	"\x05email\x18\x03 \x01(\tR\x05email\x12\x10\n" +
-	"\x03age\x18\x04 \x01(\x05R\x03age\x12\x18\n" +
-	"\aversion\x18\x05 \x01(\x05R\aversion\"i\n" +
-	"\x11ListUsersResponse\x12(\n" +
-	"\x05users\x18\x01 \x03(\v2\x12.user.UserResponseR\x05users\x12*\n" +
-	"\bmetadata\x18\x02 \x01(\v2\x0e.user.MetaDataR\bmetadata\"\xb8\x01\n" +
-	"\x11UpdateUserRequest\x12\x0e\n" +
-	"\x02id\x18\x01 \x01(\x03R\x02id\x120\n" +
-	"\x04name\x18\x02 \x01(\v2\x1c.google.protobuf.StringValueR\x04name\x122\n" +
-	"\x05email\x18\x03 \x01(\v2\x1c.google.protobuf.StringValueR\x05email\x12-\n" +
-	"\x03age\x18\x04 \x01(\v2\x1b.google.protobuf.Int32ValueR\x03age\"#\n" +
-	"\x11DeleteUserRequest\x12\x0e\n" +
-	"\x02id\x18\x01 \x01(\x03R\x02id\"`\n" +
-	"\bMetaData\x12#\n" +
-	"\rtotal_records\x18\x01 \x01(\x05R\ftotalRecords\x12\x12\n" +
-	"\x04page\x18\x02 \x01(\x05R\x04page\x12\x1b\n" +
-	"\tpage_size\x18\x03 \x01(\x05R\bpageSize\"\a\n" +
-	"\x05Empty2\xbb\x02\n" +
-	"\vUserService\x12;\n" +
-	"\n" +
-	"CreateUser\x12\x17.user.CreateUserRequest\x1a\x12.user.UserResponse\"\x00\x125\n" +
-	"\aGetUser\x12\x14.user.GetUserRequest\x1a\x12.user.UserResponse\"\x00\x12>\n" +
-	"\tListUsers\x12\x16.user.ListUsersRequest\x1a\x17.user.ListUsersResponse\"\x00\x12;\n" +
-	"\n" +
-	"UpdateUser\x12\x17.user.UpdateUserRequest\x1a\x12.user.UserResponse\"\x00\x12;\n" +
-	"\n" +
-	"DeleteUser\x12\x17.user.DeleteUserRequest\x1a\x12.user.UserResponse\"\x00B\tZ\a./protob\x06proto3"
-
-var (
-	file_user_proto_rawDescOnce sync.Once
-	file_user_proto_rawDescData []byte
-)
+func (x *EraseUserRequest) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
 
-func file_user_proto_rawDescGZIP() []byte {
-	file_user_proto_rawDescOnce.Do(func() {
-		file_user_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_user_proto_rawDesc), len(file_user_proto_rawDesc)))
-	})
-	return file_user_proto_rawDescData
+type LookupUserRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Key:
+	//
+	//	*LookupUserRequest_Id
+	//	*LookupUserRequest_Email
+	Key           isLookupUserRequest_Key `protobuf_oneof:"key"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
-var file_user_proto_msgTypes = make([]protoimpl.MessageInfo, 9)
-var file_user_proto_goTypes = []any{
-	(*CreateUserRequest)(nil),      // 0: user.CreateUserRequest
-	(*GetUserRequest)(nil),         // 1: user.GetUserRequest
-	(*ListUsersRequest)(nil),       // 2: user.ListUsersRequest
-	(*UserResponse)(nil),           // 3: user.UserResponse
-	(*ListUsersResponse)(nil),      // 4: user.ListUsersResponse
-	(*UpdateUserRequest)(nil),      // 5: user.UpdateUserRequest
-	(*DeleteUserRequest)(nil),      // 6: user.DeleteUserRequest
-	(*MetaData)(nil),               // 7: user.MetaData
-	(*Empty)(nil),                  // 8: user.Empty
-	(*wrapperspb.StringValue)(nil), // 9: google.protobuf.StringValue
-	(*wrapperspb.Int32Value)(nil),  // 10: google.protobuf.Int32Value
+func (x *LookupUserRequest) Reset() {
+	*x = LookupUserRequest{}
+	mi := &file_user_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
 }
-var file_user_proto_depIdxs = []int32{
-	3,  // 0: user.ListUsersResponse.users:type_name -> user.UserResponse
-	7,  // 1: user.ListUsersResponse.metadata:type_name -> user.MetaData
-	9,  // 2: user.UpdateUserRequest.name:type_name -> google.protobuf.StringValue
-	9,  // 3: user.UpdateUserRequest.email:type_name -> google.protobuf.StringValue
-	10, // 4: user.UpdateUserRequest.age:type_name -> google.protobuf.Int32Value
-	0,  // 5: user.UserService.CreateUser:input_type -> user.CreateUserRequest
-	1,  // 6: user.UserService.GetUser:input_type -> user.GetUserRequest
-	2,  // 7: user.UserService.ListUsers:input_type -> user.ListUsersRequest
-	5,  // 8: user.UserService.UpdateUser:input_type -> user.UpdateUserRequest
-	6,  // 9: user.UserService.DeleteUser:input_type -> user.DeleteUserRequest
-	3,  // 10: user.UserService.CreateUser:output_type -> user.UserResponse
-	3,  // 11: user.UserService.GetUser:output_type -> user.UserResponse
-	4,  // 12: user.UserService.ListUsers:output_type -> user.ListUsersResponse
-	3,  // 13: user.UserService.UpdateUser:output_type -> user.UserResponse
-	3,  // 14: user.UserService.DeleteUser:output_type -> user.UserResponse
-	10, // [10:15] is the sub-list for method output_type
-	5,  // [5:10] is the sub-list for method input_type
-	5,  // [5:5] is the sub-list for extension type_name
-	5,  // [5:5] is the sub-list for extension extendee
-	0,  // [0:5] is the sub-list for field type_name
+
+func (x *LookupUserRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
 }
 
-func init() { file_user_proto_init() }
-func file_user_proto_init() {
-	if File_user_proto != nil {
+func (*LookupUserRequest) ProtoMessage() {}
+
+func (x *LookupUserRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_user_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LookupUserRequest.ProtoReflect.Descriptor instead.
+func (*LookupUserRequest) Descriptor() ([]byte, []int) {
+	return file_user_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *LookupUserRequest) GetKey() isLookupUserRequest_Key {
+	if x != nil {
+		return x.Key
+	}
+	return nil
+}
+
+func (x *LookupUserRequest) GetId() int64 {
+	if x != nil {
+		if x, ok := x.Key.(*LookupUserRequest_Id); ok {
+			return x.Id
+		}
+	}
+	return 0
+}
+
+func (x *LookupUserRequest) GetEmail() string {
+	if x != nil {
+		if x, ok := x.Key.(*LookupUserRequest_Email); ok {
+			return x.Email
+		}
+	}
+	return ""
+}
+
+type isLookupUserRequest_Key interface {
+	isLookupUserRequest_Key()
+}
+
+type LookupUserRequest_Id struct {
+	Id int64 `protobuf:"varint,1,opt,name=id,proto3,oneof"`
+}
+
+type LookupUserRequest_Email struct {
+	Email string `protobuf:"bytes,2,opt,name=email,proto3,oneof"`
+}
+
+func (*LookupUserRequest_Id) isLookupUserRequest_Key() {}
+
+func (*LookupUserRequest_Email) isLookupUserRequest_Key() {}
+
+type SearchUsersRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Query         string                 `protobuf:"bytes,1,opt,name=query,proto3" json:"query,omitempty"`
+	Page          int32                  `protobuf:"varint,2,opt,name=page,proto3" json:"page,omitempty"`
+	PageSize      int32                  `protobuf:"varint,3,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SearchUsersRequest) Reset() {
+	*x = SearchUsersRequest{}
+	mi := &file_user_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SearchUsersRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchUsersRequest) ProtoMessage() {}
+
+func (x *SearchUsersRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_user_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchUsersRequest.ProtoReflect.Descriptor instead.
+func (*SearchUsersRequest) Descriptor() ([]byte, []int) {
+	return file_user_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *SearchUsersRequest) GetQuery() string {
+	if x != nil {
+		return x.Query
+	}
+	return ""
+}
+
+func (x *SearchUsersRequest) GetPage() int32 {
+	if x != nil {
+		return x.Page
+	}
+	return 0
+}
+
+func (x *SearchUsersRequest) GetPageSize() int32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+type SearchUsersResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Ordered by score, best match first.
+	Matches       []*UserMatch `protobuf:"bytes,1,rep,name=matches,proto3" json:"matches,omitempty"`
+	Metadata      *MetaData    `protobuf:"bytes,2,opt,name=metadata,proto3" json:"metadata,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SearchUsersResponse) Reset() {
+	*x = SearchUsersResponse{}
+	mi := &file_user_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SearchUsersResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchUsersResponse) ProtoMessage() {}
+
+func (x *SearchUsersResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_user_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchUsersResponse.ProtoReflect.Descriptor instead.
+func (*SearchUsersResponse) Descriptor() ([]byte, []int) {
+	return file_user_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *SearchUsersResponse) GetMatches() []*UserMatch {
+	if x != nil {
+		return x.Matches
+	}
+	return nil
+}
+
+func (x *SearchUsersResponse) GetMetadata() *MetaData {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
+type UserMatch struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	User  *UserResponse          `protobuf:"bytes,1,opt,name=user,proto3" json:"user,omitempty"`
+	// Relevance to the query; only comparable within one response.
+	Score         float64 `protobuf:"fixed64,2,opt,name=score,proto3" json:"score,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UserMatch) Reset() {
+	*x = UserMatch{}
+	mi := &file_user_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UserMatch) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UserMatch) ProtoMessage() {}
+
+func (x *UserMatch) ProtoReflect() protoreflect.Message {
+	mi := &file_user_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UserMatch.ProtoReflect.Descriptor instead.
+func (*UserMatch) Descriptor() ([]byte, []int) {
+	return file_user_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *UserMatch) GetUser() *UserResponse {
+	if x != nil {
+		return x.User
+	}
+	return nil
+}
+
+func (x *UserMatch) GetScore() float64 {
+	if x != nil {
+		return x.Score
+	}
+	return 0
+}
+
+type GetUserStatsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Only users created at or after created_from and before created_to.
+	CreatedFrom *timestamppb.Timestamp `protobuf:"bytes,1,opt,name=created_from,json=createdFrom,proto3" json:"created_from,omitempty"`
+	CreatedTo   *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=created_to,json=createdTo,proto3" json:"created_to,omitempty"`
+	// Only users with all of these attribute values.
+	Attributes map[string]string `protobuf:"bytes,3,rep,name=attributes,proto3" json:"attributes,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	// Length of the signup periods: day (default), week or month.
+	Interval string `protobuf:"bytes,4,opt,name=interval,proto3" json:"interval,omitempty"`
+	// Number of email domains to return, 10 by default.
+	TopDomains    int32 `protobuf:"varint,5,opt,name=top_domains,json=topDomains,proto3" json:"top_domains,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetUserStatsRequest) Reset() {
+	*x = GetUserStatsRequest{}
+	mi := &file_user_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetUserStatsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetUserStatsRequest) ProtoMessage() {}
+
+func (x *GetUserStatsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_user_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetUserStatsRequest.ProtoReflect.Descriptor instead.
+func (*GetUserStatsRequest) Descriptor() ([]byte, []int) {
+	return file_user_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *GetUserStatsRequest) GetCreatedFrom() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedFrom
+	}
+	return nil
+}
+
+func (x *GetUserStatsRequest) GetCreatedTo() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedTo
+	}
+	return nil
+}
+
+func (x *GetUserStatsRequest) GetAttributes() map[string]string {
+	if x != nil {
+		return x.Attributes
+	}
+	return nil
+}
+
+func (x *GetUserStatsRequest) GetInterval() string {
+	if x != nil {
+		return x.Interval
+	}
+	return ""
+}
+
+func (x *GetUserStatsRequest) GetTopDomains() int32 {
+	if x != nil {
+		return x.TopDomains
+	}
+	return 0
+}
+
+type UserStatsResponse struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	Total      int64                  `protobuf:"varint,1,opt,name=total,proto3" json:"total,omitempty"`
+	AgeBuckets []*AgeBucketCount      `protobuf:"bytes,2,rep,name=age_buckets,json=ageBuckets,proto3" json:"age_buckets,omitempty"`
+	// Most common first.
+	TopDomains []*DomainCount `protobuf:"bytes,3,rep,name=top_domains,json=topDomains,proto3" json:"top_domains,omitempty"`
+	// One entry per period from the first signup to the last, in UTC.
+	Signups []*SignupCount `protobuf:"bytes,4,rep,name=signups,proto3" json:"signups,omitempty"`
+	// Statistics may be served from a cache; this is when they were computed.
+	GeneratedAt   *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=generated_at,json=generatedAt,proto3" json:"generated_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UserStatsResponse) Reset() {
+	*x = UserStatsResponse{}
+	mi := &file_user_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UserStatsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UserStatsResponse) ProtoMessage() {}
+
+func (x *UserStatsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_user_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UserStatsResponse.ProtoReflect.Descriptor instead.
+func (*UserStatsResponse) Descriptor() ([]byte, []int) {
+	return file_user_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *UserStatsResponse) GetTotal() int64 {
+	if x != nil {
+		return x.Total
+	}
+	return 0
+}
+
+func (x *UserStatsResponse) GetAgeBuckets() []*AgeBucketCount {
+	if x != nil {
+		return x.AgeBuckets
+	}
+	return nil
+}
+
+func (x *UserStatsResponse) GetTopDomains() []*DomainCount {
+	if x != nil {
+		return x.TopDomains
+	}
+	return nil
+}
+
+func (x *UserStatsResponse) GetSignups() []*SignupCount {
+	if x != nil {
+		return x.Signups
+	}
+	return nil
+}
+
+func (x *UserStatsResponse) GetGeneratedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.GeneratedAt
+	}
+	return nil
+}
+
+type AgeBucketCount struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// For example "18-24" or "65+".
+	Range         string `protobuf:"bytes,1,opt,name=range,proto3" json:"range,omitempty"`
+	Count         int64  `protobuf:"varint,2,opt,name=count,proto3" json:"count,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AgeBucketCount) Reset() {
+	*x = AgeBucketCount{}
+	mi := &file_user_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AgeBucketCount) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AgeBucketCount) ProtoMessage() {}
+
+func (x *AgeBucketCount) ProtoReflect() protoreflect.Message {
+	mi := &file_user_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AgeBucketCount.ProtoReflect.Descriptor instead.
+func (*AgeBucketCount) Descriptor() ([]byte, []int) {
+	return file_user_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *AgeBucketCount) GetRange() string {
+	if x != nil {
+		return x.Range
+	}
+	return ""
+}
+
+func (x *AgeBucketCount) GetCount() int64 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+type DomainCount struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Domain        string                 `protobuf:"bytes,1,opt,name=domain,proto3" json:"domain,omitempty"`
+	Count         int64                  `protobuf:"varint,2,opt,name=count,proto3" json:"count,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DomainCount) Reset() {
+	*x = DomainCount{}
+	mi := &file_user_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DomainCount) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DomainCount) ProtoMessage() {}
+
+func (x *DomainCount) ProtoReflect() protoreflect.Message {
+	mi := &file_user_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DomainCount.ProtoReflect.Descriptor instead.
+func (*DomainCount) Descriptor() ([]byte, []int) {
+	return file_user_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *DomainCount) GetDomain() string {
+	if x != nil {
+		return x.Domain
+	}
+	return ""
+}
+
+func (x *DomainCount) GetCount() int64 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+type SignupCount struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PeriodStart   *timestamppb.Timestamp `protobuf:"bytes,1,opt,name=period_start,json=periodStart,proto3" json:"period_start,omitempty"`
+	Count         int64                  `protobuf:"varint,2,opt,name=count,proto3" json:"count,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SignupCount) Reset() {
+	*x = SignupCount{}
+	mi := &file_user_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SignupCount) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SignupCount) ProtoMessage() {}
+
+func (x *SignupCount) ProtoReflect() protoreflect.Message {
+	mi := &file_user_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SignupCount.ProtoReflect.Descriptor instead.
+func (*SignupCount) Descriptor() ([]byte, []int) {
+	return file_user_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *SignupCount) GetPeriodStart() *timestamppb.Timestamp {
+	if x != nil {
+		return x.PeriodStart
+	}
+	return nil
+}
+
+func (x *SignupCount) GetCount() int64 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+type MetaData struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TotalRecords  int32                  `protobuf:"varint,1,opt,name=total_records,json=totalRecords,proto3" json:"total_records,omitempty"`
+	Page          int32                  `protobuf:"varint,2,opt,name=page,proto3" json:"page,omitempty"`
+	PageSize      int32                  `protobuf:"varint,3,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MetaData) Reset() {
+	*x = MetaData{}
+	mi := &file_user_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MetaData) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MetaData) ProtoMessage() {}
+
+func (x *MetaData) ProtoReflect() protoreflect.Message {
+	mi := &file_user_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MetaData.ProtoReflect.Descriptor instead.
+func (*MetaData) Descriptor() ([]byte, []int) {
+	return file_user_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *MetaData) GetTotalRecords() int32 {
+	if x != nil {
+		return x.TotalRecords
+	}
+	return 0
+}
+
+func (x *MetaData) GetPage() int32 {
+	if x != nil {
+		return x.Page
+	}
+	return 0
+}
+
+func (x *MetaData) GetPageSize() int32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+type Empty struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Empty) Reset() {
+	*x = Empty{}
+	mi := &file_user_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Empty) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Empty) ProtoMessage() {}
+
+func (x *Empty) ProtoReflect() protoreflect.Message {
+	mi := &file_user_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Empty.ProtoReflect.Descriptor instead.
+func (*Empty) Descriptor() ([]byte, []int) {
+	return file_user_proto_rawDescGZIP(), []int{20}
+}
+
+var File_user_proto protoreflect.FileDescriptor
+
+const file_user_proto_rawDesc = "" +
+	"\n" +
+	"\n" +
+	"user.proto\x12\x04user\x1a\x1fgoogle/protobuf/timestamp.proto\x1a\x1egoogle/protobuf/wrappers.proto\"\xe3\x02\n" +
+	"\x11CreateUserRequest\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x14\n" +
+	"\x05email\x18\x02 \x01(\tR\x05email\x12\x10\n" +
+	"\x03age\x18\x03 \x01(\x05R\x03age\x12!\n" +
+	"\fdisplay_name\x18\x04 \x01(\tR\vdisplayName\x12\x14\n" +
+	"\x05phone\x18\x05 \x01(\tR\x05phone\x12\x16\n" +
+	"\x06locale\x18\x06 \x01(\tR\x06locale\x12\x1b\n" +
+	"\ttime_zone\x18\a \x01(\tR\btimeZone\x12\x1c\n" +
+	"\tbirthdate\x18\b \x01(\tR\tbirthdate\x12G\n" +
+	"\n" +
+	"attributes\x18\t \x03(\v2'.user.CreateUserRequest.AttributesEntryR\n" +
+	"attributes\x1a=\n" +
+	"\x0fAttributesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\" \n" +
+	"\x0eGetUserRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\"\x85\x02\n" +
+	"\x10ListUsersRequest\x12\x12\n" +
+	"\x04page\x18\x01 \x01(\x05R\x04page\x12\x1b\n" +
+	"\tpage_size\x18\x02 \x01(\x05R\bpageSize\x12\x12\n" +
+	"\x04sort\x18\x03 \x01(\tR\x04sort\x12F\n" +
+	"\n" +
+	"attributes\x18\x04 \x03(\v2&.user.ListUsersRequest.AttributesEntryR\n" +
+	"attributes\x12%\n" +
+	"\x0ehas_attributes\x18\x05 \x03(\tR\rhasAttributes\x1a=\n" +
+	"\x0fAttributesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\xb2\x04\n" +
+	"\fUserResponse\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x14\n" +
+	"\x05email\x18\x03 \x01(\tR\x05email\x12\x10\n" +
+	"\x03age\x18\x04 \x01(\x05R\x03age\x12\x18\n" +
+	"\aversion\x18\x05 \x01(\x05R\aversion\x12!\n" +
+	"\fdisplay_name\x18\x06 \x01(\tR\vdisplayName\x12\x14\n" +
+	"\x05phone\x18\a \x01(\tR\x05phone\x12\x16\n" +
+	"\x06locale\x18\b \x01(\tR\x06locale\x12\x1b\n" +
+	"\ttime_zone\x18\t \x01(\tR\btimeZone\x12\x1c\n" +
+	"\tbirthdate\x18\n" +
+	" \x01(\tR\tbirthdate\x129\n" +
+	"\n" +
+	"created_at\x18\v \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x129\n" +
+	"\n" +
+	"updated_at\x18\f \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAt\x12B\n" +
+	"\n" +
+	"attributes\x18\r \x03(\v2\".user.UserResponse.AttributesEntryR\n" +
+	"attributes\x127\n" +
+	"\terased_at\x18\x0e \x01(\v2\x1a.google.protobuf.TimestampR\berasedAt\x1a=\n" +
+	"\x0fAttributesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"i\n" +
+	"\x11ListUsersResponse\x12(\n" +
+	"\x05users\x18\x01 \x03(\v2\x12.user.UserResponseR\x05users\x12*\n" +
+	"\bmetadata\x18\x02 \x01(\v2\x0e.user.MetaDataR\bmetadata\"\xe2\x04\n" +
+	"\x11UpdateUserRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x120\n" +
+	"\x04name\x18\x02 \x01(\v2\x1c.google.protobuf.StringValueR\x04name\x122\n" +
+	"\x05email\x18\x03 \x01(\v2\x1c.google.protobuf.StringValueR\x05email\x12-\n" +
+	"\x03age\x18\x04 \x01(\v2\x1b.google.protobuf.Int32ValueR\x03age\x12?\n" +
+	"\fdisplay_name\x18\x05 \x01(\v2\x1c.google.protobuf.StringValueR\vdisplayName\x122\n" +
+	"\x05phone\x18\x06 \x01(\v2\x1c.google.protobuf.StringValueR\x05phone\x124\n" +
+	"\x06locale\x18\a \x01(\v2\x1c.google.protobuf.StringValueR\x06locale\x129\n" +
+	"\ttime_zone\x18\b \x01(\v2\x1c.google.protobuf.StringValueR\btimeZone\x12:\n" +
+	"\tbirthdate\x18\t \x01(\v2\x1c.google.protobuf.StringValueR\tbirthdate\x12G\n" +
+	"\n" +
+	"attributes\x18\n" +
+	" \x03(\v2'.user.UpdateUserRequest.AttributesEntryR\n" +
+	"attributes\x1a=\n" +
+	"\x0fAttributesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"#\n" +
+	"\x11DeleteUserRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\"'\n" +
+	"\x15ExportUserDataRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\"\x92\x01\n" +
+	"\x0eUserDataExport\x12\x1b\n" +
+	"\ttenant_id\x18\x01 \x01(\tR\btenantId\x12&\n" +
+	"\x04user\x18\x02 \x01(\v2\x12.user.UserResponseR\x04user\x12;\n" +
+	"\vexported_at\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"exportedAt\"\"\n" +
+	"\x10EraseUserRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\"D\n" +
+	"\x11LookupUserRequest\x12\x10\n" +
+	"\x02id\x18\x01 \x01(\x03H\x00R\x02id\x12\x16\n" +
+	"\x05email\x18\x02 \x01(\tH\x00R\x05emailB\x05\n" +
+	"\x03key\"[\n" +
+	"\x12SearchUsersRequest\x12\x14\n" +
+	"\x05query\x18\x01 \x01(\tR\x05query\x12\x12\n" +
+	"\x04page\x18\x02 \x01(\x05R\x04page\x12\x1b\n" +
+	"\tpage_size\x18\x03 \x01(\x05R\bpageSize\"l\n" +
+	"\x13SearchUsersResponse\x12)\n" +
+	"\amatches\x18\x01 \x03(\v2\x0f.user.UserMatchR\amatches\x12*\n" +
+	"\bmetadata\x18\x02 \x01(\v2\x0e.user.MetaDataR\bmetadata\"I\n" +
+	"\tUserMatch\x12&\n" +
+	"\x04user\x18\x01 \x01(\v2\x12.user.UserResponseR\x04user\x12\x14\n" +
+	"\x05score\x18\x02 \x01(\x01R\x05score\"\xd6\x02\n" +
+	"\x13GetUserStatsRequest\x12=\n" +
+	"\fcreated_from\x18\x01 \x01(\v2\x1a.google.protobuf.TimestampR\vcreatedFrom\x129\n" +
+	"\n" +
+	"created_to\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedTo\x12I\n" +
+	"\n" +
+	"attributes\x18\x03 \x03(\v2).user.GetUserStatsRequest.AttributesEntryR\n" +
+	"attributes\x12\x1a\n" +
+	"\binterval\x18\x04 \x01(\tR\binterval\x12\x1f\n" +
+	"\vtop_domains\x18\x05 \x01(\x05R\n" +
+	"topDomains\x1a=\n" +
+	"\x0fAttributesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\x80\x02\n" +
+	"\x11UserStatsResponse\x12\x14\n" +
+	"\x05total\x18\x01 \x01(\x03R\x05total\x125\n" +
+	"\vage_buckets\x18\x02 \x03(\v2\x14.user.AgeBucketCountR\n" +
+	"ageBuckets\x122\n" +
+	"\vtop_domains\x18\x03 \x03(\v2\x11.user.DomainCountR\n" +
+	"topDomains\x12+\n" +
+	"\asignups\x18\x04 \x03(\v2\x11.user.SignupCountR\asignups\x12=\n" +
+	"\fgenerated_at\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\vgeneratedAt\"<\n" +
+	"\x0eAgeBucketCount\x12\x14\n" +
+	"\x05range\x18\x01 \x01(\tR\x05range\x12\x14\n" +
+	"\x05count\x18\x02 \x01(\x03R\x05count\";\n" +
+	"\vDomainCount\x12\x16\n" +
+	"\x06domain\x18\x01 \x01(\tR\x06domain\x12\x14\n" +
+	"\x05count\x18\x02 \x01(\x03R\x05count\"b\n" +
+	"\vSignupCount\x12=\n" +
+	"\fperiod_start\x18\x01 \x01(\v2\x1a.google.protobuf.TimestampR\vperiodStart\x12\x14\n" +
+	"\x05count\x18\x02 \x01(\x03R\x05count\"`\n" +
+	"\bMetaData\x12#\n" +
+	"\rtotal_records\x18\x01 \x01(\x05R\ftotalRecords\x12\x12\n" +
+	"\x04page\x18\x02 \x01(\x05R\x04page\x12\x1b\n" +
+	"\tpage_size\x18\x03 \x01(\x05R\bpageSize\"\a\n" +
+	"\x05Empty2\x86\x05\n" +
+	"\vUserService\x12;\n" +
+	"\n" +
+	"CreateUser\x12\x17.user.CreateUserRequest\x1a\x12.user.UserResponse\"\x00\x125\n" +
+	"\aGetUser\x12\x14.user.GetUserRequest\x1a\x12.user.UserResponse\"\x00\x12>\n" +
+	"\tListUsers\x12\x16.user.ListUsersRequest\x1a\x17.user.ListUsersResponse\"\x00\x12;\n" +
+	"\n" +
+	"UpdateUser\x12\x17.user.UpdateUserRequest\x1a\x12.user.UserResponse\"\x00\x12;\n" +
+	"\n" +
+	"DeleteUser\x12\x17.user.DeleteUserRequest\x1a\x12.user.UserResponse\"\x00\x12;\n" +
+	"\n" +
+	"LookupUser\x12\x17.user.LookupUserRequest\x1a\x12.user.UserResponse\"\x00\x12D\n" +
+	"\vSearchUsers\x12\x18.user.SearchUsersRequest\x1a\x19.user.SearchUsersResponse\"\x00\x12D\n" +
+	"\fGetUserStats\x12\x19.user.GetUserStatsRequest\x1a\x17.user.UserStatsResponse\"\x00\x12E\n" +
+	"\x0eExportUserData\x12\x1b.user.ExportUserDataRequest\x1a\x14.user.UserDataExport\"\x00\x129\n" +
+	"\tEraseUser\x12\x16.user.EraseUserRequest\x1a\x12.user.UserResponse\"\x00B\tZ\a./protob\x06proto3"
+
+var (
+	file_user_proto_rawDescOnce sync.Once
+	file_user_proto_rawDescData []byte
+)
+
+func file_user_proto_rawDescGZIP() []byte {
+	file_user_proto_rawDescOnce.Do(func() {
+		file_user_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_user_proto_rawDesc), len(file_user_proto_rawDesc)))
+	})
+	return file_user_proto_rawDescData
+}
+
+var file_user_proto_msgTypes = make([]protoimpl.MessageInfo, 26)
+var file_user_proto_goTypes = []any{
+	(*CreateUserRequest)(nil),      // 0: user.CreateUserRequest
+	(*GetUserRequest)(nil),         // 1: user.GetUserRequest
+	(*ListUsersRequest)(nil),       // 2: user.ListUsersRequest
+	(*UserResponse)(nil),           // 3: user.UserResponse
+	(*ListUsersResponse)(nil),      // 4: user.ListUsersResponse
+	(*UpdateUserRequest)(nil),      // 5: user.UpdateUserRequest
+	(*DeleteUserRequest)(nil),      // 6: user.DeleteUserRequest
+	(*ExportUserDataRequest)(nil),  // 7: user.ExportUserDataRequest
+	(*UserDataExport)(nil),         // 8: user.UserDataExport
+	(*EraseUserRequest)(nil),       // 9: user.EraseUserRequest
+	(*LookupUserRequest)(nil),      // 10: user.LookupUserRequest
+	(*SearchUsersRequest)(nil),     // 11: user.SearchUsersRequest
+	(*SearchUsersResponse)(nil),    // 12: user.SearchUsersResponse
+	(*UserMatch)(nil),              // 13: user.UserMatch
+	(*GetUserStatsRequest)(nil),    // 14: user.GetUserStatsRequest
+	(*UserStatsResponse)(nil),      // 15: user.UserStatsResponse
+	(*AgeBucketCount)(nil),         // 16: user.AgeBucketCount
+	(*DomainCount)(nil),            // 17: user.DomainCount
+	(*SignupCount)(nil),            // 18: user.SignupCount
+	(*MetaData)(nil),               // 19: user.MetaData
+	(*Empty)(nil),                  // 20: user.Empty
+	nil,                            // 21: user.CreateUserRequest.AttributesEntry
+	nil,                            // 22: user.ListUsersRequest.AttributesEntry
+	nil,                            // 23: user.UserResponse.AttributesEntry
+	nil,                            // 24: user.UpdateUserRequest.AttributesEntry
+	nil,                            // 25: user.GetUserStatsRequest.AttributesEntry
+	(*timestamppb.Timestamp)(nil),  // 26: google.protobuf.Timestamp
+	(*wrapperspb.StringValue)(nil), // 27: google.protobuf.StringValue
+	(*wrapperspb.Int32Value)(nil),  // 28: google.protobuf.Int32Value
+}
+var file_user_proto_depIdxs = []int32{
+	21, // 0: user.CreateUserRequest.attributes:type_name -> user.CreateUserRequest.AttributesEntry
+	22, // 1: user.ListUsersRequest.attributes:type_name -> user.ListUsersRequest.AttributesEntry
+	26, // 2: user.UserResponse.created_at:type_name -> google.protobuf.Timestamp
+	26, // 3: user.UserResponse.updated_at:type_name -> google.protobuf.Timestamp
+	23, // 4: user.UserResponse.attributes:type_name -> user.UserResponse.AttributesEntry
+	26, // 5: user.UserResponse.erased_at:type_name -> google.protobuf.Timestamp
+	3,  // 6: user.ListUsersResponse.users:type_name -> user.UserResponse
+	19, // 7: user.ListUsersResponse.metadata:type_name -> user.MetaData
+	27, // 8: user.UpdateUserRequest.name:type_name -> google.protobuf.StringValue
+	27, // 9: user.UpdateUserRequest.email:type_name -> google.protobuf.StringValue
+	28, // 10: user.UpdateUserRequest.age:type_name -> google.protobuf.Int32Value
+	27, // 11: user.UpdateUserRequest.display_name:type_name -> google.protobuf.StringValue
+	27, // 12: user.UpdateUserRequest.phone:type_name -> google.protobuf.StringValue
+	27, // 13: user.UpdateUserRequest.locale:type_name -> google.protobuf.StringValue
+	27, // 14: user.UpdateUserRequest.time_zone:type_name -> google.protobuf.StringValue
+	27, // 15: user.UpdateUserRequest.birthdate:type_name -> google.protobuf.StringValue
+	24, // 16: user.UpdateUserRequest.attributes:type_name -> user.UpdateUserRequest.AttributesEntry
+	3,  // 17: user.UserDataExport.user:type_name -> user.UserResponse
+	26, // 18: user.UserDataExport.exported_at:type_name -> google.protobuf.Timestamp
+	13, // 19: user.SearchUsersResponse.matches:type_name -> user.UserMatch
+	19, // 20: user.SearchUsersResponse.metadata:type_name -> user.MetaData
+	3,  // 21: user.UserMatch.user:type_name -> user.UserResponse
+	26, // 22: user.GetUserStatsRequest.created_from:type_name -> google.protobuf.Timestamp
+	26, // 23: user.GetUserStatsRequest.created_to:type_name -> google.protobuf.Timestamp
+	25, // 24: user.GetUserStatsRequest.attributes:type_name -> user.GetUserStatsRequest.AttributesEntry
+	16, // 25: user.UserStatsResponse.age_buckets:type_name -> user.AgeBucketCount
+	17, // 26: user.UserStatsResponse.top_domains:type_name -> user.DomainCount
+	18, // 27: user.UserStatsResponse.signups:type_name -> user.SignupCount
+	26, // 28: user.UserStatsResponse.generated_at:type_name -> google.protobuf.Timestamp
+	26, // 29: user.SignupCount.period_start:type_name -> google.protobuf.Timestamp
+	0,  // 30: user.UserService.CreateUser:input_type -> user.CreateUserRequest
+	1,  // 31: user.UserService.GetUser:input_type -> user.GetUserRequest
+	2,  // 32: user.UserService.ListUsers:input_type -> user.ListUsersRequest
+	5,  // 33: user.UserService.UpdateUser:input_type -> user.UpdateUserRequest
+	6,  // 34: user.UserService.DeleteUser:input_type -> user.DeleteUserRequest
+	10, // 35: user.UserService.LookupUser:input_type -> user.LookupUserRequest
+	11, // 36: user.UserService.SearchUsers:input_type -> user.SearchUsersRequest
+	14, // 37: user.UserService.GetUserStats:input_type -> user.GetUserStatsRequest
+	7,  // 38: user.UserService.ExportUserData:input_type -> user.ExportUserDataRequest
+	9,  // 39: user.UserService.EraseUser:input_type -> user.EraseUserRequest
+	3,  // 40: user.UserService.CreateUser:output_type -> user.UserResponse
+	3,  // 41: user.UserService.GetUser:output_type -> user.UserResponse
+	4,  // 42: user.UserService.ListUsers:output_type -> user.ListUsersResponse
+	3,  // 43: user.UserService.UpdateUser:output_type -> user.UserResponse
+	3,  // 44: user.UserService.DeleteUser:output_type -> user.UserResponse
+	3,  // 45: user.UserService.LookupUser:output_type -> user.UserResponse
+	12, // 46: user.UserService.SearchUsers:output_type -> user.SearchUsersResponse
+	15, // 47: user.UserService.GetUserStats:output_type -> user.UserStatsResponse
+	8,  // 48: user.UserService.ExportUserData:output_type -> user.UserDataExport
+	3,  // 49: user.UserService.EraseUser:output_type -> user.UserResponse
+	40, // [40:50] is the sub-list for method output_type
+	30, // [30:40] is the sub-list for method input_type
+	30, // [30:30] is the sub-list for extension type_name
+	30, // [30:30] is the sub-list for extension extendee
+	0,  // [0:30] is the sub-list for field type_name
+}
+
+func init() { file_user_proto_init() }
+func file_user_proto_init() {
+	if File_user_proto != nil {
 		return
 	}
+	file_user_proto_msgTypes[10].OneofWrappers = []any{
+		(*LookupUserRequest_Id)(nil),
+		(*LookupUserRequest_Email)(nil),
+	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_user_proto_rawDesc), len(file_user_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   9,
+			NumMessages:   26,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
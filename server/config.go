@@ -0,0 +1,397 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Vadim-Makhnev/grpc/internal/data"
+	"github.com/Vadim-Makhnev/grpc/internal/validator"
+	"gopkg.in/yaml.v3"
+)
+
+const envPrefix = "GRPC_"
+
+var dsnPasswordRX = regexp.MustCompile(`(password\s*=\s*)('[^']*'|\S+)`)
+
+type config struct {
+	port     int
+	httpPort int
+	env      string
+	db       struct {
+		dsn              string
+		maxOpenConns     int
+		maxIdleConns     int
+		maxIdleTime      time.Duration
+		txIsolation      string
+		rowLevelSecurity bool
+		retry            struct {
+			maxAttempts int
+			baseDelay   time.Duration
+			maxDelay    time.Duration
+		}
+		breaker struct {
+			enabled          bool
+			window           time.Duration
+			minRequests      int
+			failureRatio     float64
+			slowCallDuration time.Duration
+			slowCallRatio    float64
+			openTimeout      time.Duration
+			halfOpenProbes   int
+		}
+	}
+	cors struct {
+		trustedOrigins stringList
+	}
+	tls struct {
+		mode         string
+		certFile     string
+		keyFile      string
+		clientCAFile string
+	}
+	tracing struct {
+		exporter    string
+		sampleRatio float64
+	}
+	tenancy struct {
+		defaultTenant string
+	}
+	cache struct {
+		size int
+		ttl  time.Duration
+	}
+	stats struct {
+		cacheSize int
+		cacheTTL  time.Duration
+	}
+	encryption struct {
+		keyFile           string
+		reencryptInterval time.Duration
+		reencryptBatch    int
+	}
+}
+
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, " ")
+}
+
+func (l *stringList) Set(val string) error {
+	*l = strings.Fields(val)
+	return nil
+}
+
+var txIsolationLevels = map[string]sql.IsolationLevel{
+	"read-committed":  sql.LevelReadCommitted,
+	"repeatable-read": sql.LevelRepeatableRead,
+	"serializable":    sql.LevelSerializable,
+}
+
+var secretFlags = map[string]func(string) string{
+	"db-dsn": redactDSN,
+}
+
+func (cfg *config) flagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+
+	fs.IntVar(&cfg.port, "port", 4000, "API server port")
+	fs.IntVar(&cfg.httpPort, "http-port", 8080, "HTTP/JSON gateway and gRPC-Web port")
+	fs.StringVar(&cfg.env, "env", "development", "Environment (development|staging|production)")
+
+	fs.StringVar(&cfg.db.dsn, "db-dsn", "", "PostgreSQL DSN")
+
+	fs.IntVar(&cfg.db.maxOpenConns, "db-max-open-conns", 25, "PostgreSQL max open connections")
+	fs.IntVar(&cfg.db.maxIdleConns, "db-max-idle-conns", 25, "PostgreSQL max idle connections")
+	fs.DurationVar(&cfg.db.maxIdleTime, "db-max-idle-time", 15*time.Minute, "PostgreSQL max connection idle time")
+	fs.BoolVar(&cfg.db.rowLevelSecurity, "db-row-level-security", false, "Set app.tenant_id on every statement for PostgreSQL row-level security policies")
+	fs.StringVar(&cfg.db.txIsolation, "db-tx-isolation", "read-committed", "Default transaction isolation level (read-committed|repeatable-read|serializable)")
+
+	fs.IntVar(&cfg.db.retry.maxAttempts, "db-retry-max-attempts", 3, "Maximum attempts for storage calls failing with transient errors (1 disables retries)")
+	fs.DurationVar(&cfg.db.retry.baseDelay, "db-retry-base-delay", 50*time.Millisecond, "Initial backoff between retries")
+	fs.DurationVar(&cfg.db.retry.maxDelay, "db-retry-max-delay", time.Second, "Maximum backoff between retries")
+
+	fs.BoolVar(&cfg.db.breaker.enabled, "db-breaker-enabled", true, "Fail storage calls fast while the database is unhealthy")
+	fs.DurationVar(&cfg.db.breaker.window, "db-breaker-window", 10*time.Second, "Window over which the circuit breaker measures error and slow call rates")
+	fs.IntVar(&cfg.db.breaker.minRequests, "db-breaker-min-requests", 20, "Minimum calls in a window before the circuit breaker can open")
+	fs.Float64Var(&cfg.db.breaker.failureRatio, "db-breaker-failure-ratio", 0.5, "Failed call ratio that opens the circuit breaker (0 disables)")
+	fs.DurationVar(&cfg.db.breaker.slowCallDuration, "db-breaker-slow-call-duration", 2*time.Second, "Storage call duration counted as slow by the circuit breaker")
+	fs.Float64Var(&cfg.db.breaker.slowCallRatio, "db-breaker-slow-call-ratio", 0.8, "Slow call ratio that opens the circuit breaker (0 disables)")
+	fs.DurationVar(&cfg.db.breaker.openTimeout, "db-breaker-open-timeout", 5*time.Second, "Time the circuit breaker stays open before probing the database")
+	fs.IntVar(&cfg.db.breaker.halfOpenProbes, "db-breaker-half-open-probes", 3, "Successful probe calls required to close the circuit breaker")
+
+	fs.Var(&cfg.cors.trustedOrigins, "cors-trusted-origins", "Trusted CORS origins for gRPC-Web (space separated)")
+
+	fs.StringVar(&cfg.tls.mode, "tls-mode", tlsModeOff, "TLS mode of the gRPC and HTTP listeners (off|tls|mtls)")
+	fs.StringVar(&cfg.tls.certFile, "tls-cert-file", "", "Path to the server TLS certificate")
+	fs.StringVar(&cfg.tls.keyFile, "tls-key-file", "", "Path to the server TLS private key")
+	fs.StringVar(&cfg.tls.clientCAFile, "tls-client-ca-file", "", "Path to the CA bundle used to verify client certificates in mtls mode")
+
+	fs.StringVar(&cfg.tracing.exporter, "tracing-exporter", tracingExporterNone, "OpenTelemetry trace exporter (none|stdout)")
+	fs.Float64Var(&cfg.tracing.sampleRatio, "tracing-sample-ratio", 1, "Fraction of new traces to sample (0-1)")
+
+	fs.StringVar(&cfg.tenancy.defaultTenant, "tenant-default", "default", "Tenant used when a request names none (empty requires x-tenant-id)")
+
+	fs.IntVar(&cfg.cache.size, "cache-size", 0, "Maximum number of users kept in the read-through cache (0 disables the cache)")
+	fs.DurationVar(&cfg.cache.ttl, "cache-ttl", 30*time.Second, "Time to live of cached users")
+
+	fs.IntVar(&cfg.stats.cacheSize, "stats-cache-size", 1000, "Maximum number of user statistics results kept in the cache")
+	fs.DurationVar(&cfg.stats.cacheTTL, "stats-cache-ttl", time.Minute, "Time to live of cached user statistics (0 disables the cache)")
+
+	fs.StringVar(&cfg.encryption.keyFile, "encryption-key-file", "", "Path to the JSON key file used to encrypt user names and emails (empty stores them in plaintext)")
+	fs.DurationVar(&cfg.encryption.reencryptInterval, "encryption-reencrypt-interval", time.Hour, "Interval of the job that re-encrypts users stored in plaintext or under an old key (0 disables the job)")
+	fs.IntVar(&cfg.encryption.reencryptBatch, "encryption-reencrypt-batch-size", 100, "Users re-encrypted per transaction")
+
+	return fs
+}
+
+func loadConfig(name string, args []string, environ []string) (config, error) {
+	var cfg config
+
+	fs := cfg.flagSet(name)
+
+	var configFile string
+	fs.StringVar(&configFile, "config", "", "Path to a YAML or JSON config file")
+
+	if err := fs.Parse(args); err != nil {
+		return config{}, err
+	}
+
+	explicit := map[string]string{}
+	fs.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = f.Value.String()
+	})
+
+	env := map[string]string{}
+	for _, kv := range environ {
+		key, value, ok := strings.Cut(kv, "=")
+		if !ok || !strings.HasPrefix(key, envPrefix) {
+			continue
+		}
+
+		flagName := strings.ToLower(strings.ReplaceAll(strings.TrimPrefix(key, envPrefix), "_", "-"))
+		if fs.Lookup(flagName) != nil {
+			env[flagName] = value
+		}
+	}
+
+	if _, ok := explicit["config"]; !ok && env["config"] != "" {
+		configFile = env["config"]
+	}
+
+	if configFile != "" {
+		values, err := readConfigFile(configFile)
+		if err != nil {
+			return config{}, err
+		}
+
+		for flagName, value := range values {
+			if flagName == "config" || fs.Lookup(flagName) == nil {
+				return config{}, fmt.Errorf("config file %s: unknown key %q", configFile, flagName)
+			}
+
+			if err := fs.Set(flagName, value); err != nil {
+				return config{}, fmt.Errorf("config file %s: %s: %w", configFile, flagName, err)
+			}
+		}
+	}
+
+	for flagName, value := range env {
+		if flagName == "config" {
+			continue
+		}
+
+		if err := fs.Set(flagName, value); err != nil {
+			return config{}, fmt.Errorf("environment %s%s: %w", envPrefix, strings.ToUpper(strings.ReplaceAll(flagName, "-", "_")), err)
+		}
+	}
+
+	for flagName, value := range explicit {
+		if err := fs.Set(flagName, value); err != nil {
+			return config{}, err
+		}
+	}
+
+	v := validator.New()
+
+	if validateConfig(v, cfg); !v.Valid() {
+		return config{}, configError(v.Errors)
+	}
+
+	return cfg, nil
+}
+
+func readConfigFile(path string) (map[string]string, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc map[string]any
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml", ".json":
+		if err := yaml.Unmarshal(raw, &doc); err != nil {
+			return nil, fmt.Errorf("config file %s: %w", path, err)
+		}
+	default:
+		return nil, fmt.Errorf("config file %s: unsupported format, expected .yaml, .yml or .json", path)
+	}
+
+	values := map[string]string{}
+	flattenConfig("", doc, values)
+
+	return values, nil
+}
+
+func flattenConfig(prefix string, doc map[string]any, out map[string]string) {
+	for key, value := range doc {
+		key = strings.ToLower(strings.ReplaceAll(key, "_", "-"))
+		if prefix != "" {
+			key = prefix + "-" + key
+		}
+
+		switch value := value.(type) {
+		case map[string]any:
+			flattenConfig(key, value, out)
+		case []any:
+			items := make([]string, len(value))
+			for i, item := range value {
+				items[i] = fmt.Sprint(item)
+			}
+			out[key] = strings.Join(items, " ")
+		case nil:
+			out[key] = ""
+		default:
+			out[key] = fmt.Sprint(value)
+		}
+	}
+}
+
+func validateConfig(v *validator.Validator, cfg config) {
+	v.Check(validator.In(cfg.env, "development", "staging", "production"), "env", "must be one of development, staging or production")
+
+	v.Check(cfg.port > 0 && cfg.port <= 65535, "port", "must be between 1 and 65535")
+	v.Check(cfg.httpPort > 0 && cfg.httpPort <= 65535, "http-port", "must be between 1 and 65535")
+	v.Check(cfg.port != cfg.httpPort, "http-port", "must differ from port")
+
+	v.Check(cfg.db.dsn != "", "db-dsn", "must be provided")
+	v.Check(cfg.db.maxOpenConns > 0, "db-max-open-conns", "must be greater than zero")
+	v.Check(cfg.db.maxOpenConns <= 1000, "db-max-open-conns", "must be a maximum of 1000")
+	v.Check(cfg.db.maxIdleConns >= 0, "db-max-idle-conns", "must not be negative")
+	v.Check(cfg.db.maxIdleConns <= cfg.db.maxOpenConns, "db-max-idle-conns", "must not exceed db-max-open-conns")
+	v.Check(cfg.db.maxIdleTime >= 0, "db-max-idle-time", "must not be negative")
+	v.Check(validator.In(cfg.db.txIsolation, "read-committed", "repeatable-read", "serializable"), "db-tx-isolation", "must be one of read-committed, repeatable-read or serializable")
+	v.Check(cfg.db.retry.maxAttempts >= 1, "db-retry-max-attempts", "must be at least 1")
+	v.Check(cfg.db.retry.maxAttempts <= 10, "db-retry-max-attempts", "must be a maximum of 10")
+	v.Check(cfg.db.retry.baseDelay > 0, "db-retry-base-delay", "must be greater than zero")
+	v.Check(cfg.db.retry.maxDelay >= cfg.db.retry.baseDelay, "db-retry-max-delay", "must not be less than db-retry-base-delay")
+
+	if cfg.db.breaker.enabled {
+		v.Check(cfg.db.breaker.window > 0, "db-breaker-window", "must be greater than zero")
+		v.Check(cfg.db.breaker.minRequests >= 1, "db-breaker-min-requests", "must be at least 1")
+		v.Check(cfg.db.breaker.failureRatio >= 0 && cfg.db.breaker.failureRatio <= 1, "db-breaker-failure-ratio", "must be between 0 and 1")
+		v.Check(cfg.db.breaker.slowCallDuration >= 0, "db-breaker-slow-call-duration", "must not be negative")
+		v.Check(cfg.db.breaker.slowCallRatio >= 0 && cfg.db.breaker.slowCallRatio <= 1, "db-breaker-slow-call-ratio", "must be between 0 and 1")
+		v.Check(cfg.db.breaker.openTimeout > 0, "db-breaker-open-timeout", "must be greater than zero")
+		v.Check(cfg.db.breaker.halfOpenProbes >= 1, "db-breaker-half-open-probes", "must be at least 1")
+	}
+
+	v.Check(validator.In(cfg.tls.mode, tlsModeOff, tlsModeTLS, tlsModeMTLS), "tls-mode", "must be one of off, tls or mtls")
+
+	if cfg.tls.mode == tlsModeTLS || cfg.tls.mode == tlsModeMTLS {
+		v.Check(cfg.tls.certFile != "", "tls-cert-file", "must be provided when TLS is enabled")
+		v.Check(cfg.tls.keyFile != "", "tls-key-file", "must be provided when TLS is enabled")
+	}
+
+	if cfg.tls.mode == tlsModeMTLS {
+		v.Check(cfg.tls.clientCAFile != "", "tls-client-ca-file", "must be provided in mtls mode")
+	}
+
+	v.Check(validator.In(cfg.tracing.exporter, tracingExporterNone, tracingExporterStdout), "tracing-exporter", "must be one of none or stdout")
+	v.Check(cfg.tracing.sampleRatio >= 0 && cfg.tracing.sampleRatio <= 1, "tracing-sample-ratio", "must be between 0 and 1")
+
+	if cfg.tenancy.defaultTenant != "" {
+		v.Check(validator.Matches(cfg.tenancy.defaultTenant, data.TenantRX), "tenant-default", "must contain only lowercase letters, digits, '-' and '_' (max 63 characters)")
+	}
+
+	v.Check(cfg.cache.size >= 0, "cache-size", "must not be negative")
+	v.Check(cfg.cache.size <= 1_000_000, "cache-size", "must be a maximum of 1000000")
+	v.Check(cfg.cache.size == 0 || cfg.cache.ttl > 0, "cache-ttl", "must be greater than zero when the cache is enabled")
+
+	v.Check(cfg.stats.cacheSize >= 1 && cfg.stats.cacheSize <= 100_000, "stats-cache-size", "must be between 1 and 100000")
+	v.Check(cfg.stats.cacheTTL >= 0, "stats-cache-ttl", "must not be negative")
+
+	v.Check(cfg.encryption.reencryptInterval >= 0, "encryption-reencrypt-interval", "must not be negative")
+	v.Check(cfg.encryption.reencryptBatch >= 1 && cfg.encryption.reencryptBatch <= 10_000, "encryption-reencrypt-batch-size", "must be between 1 and 10000")
+
+	if cfg.encryption.keyFile != "" && cfg.db.rowLevelSecurity {
+		v.Check(cfg.encryption.reencryptInterval == 0, "encryption-reencrypt-interval", "must be 0 with db-row-level-security, which hides other tenants from the job; run usersbulk reencrypt for each tenant instead")
+	}
+}
+
+func configError(errs map[string]string) error {
+	keys := make([]string, 0, len(errs))
+	for key := range errs {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	msgs := make([]string, len(keys))
+	for i, key := range keys {
+		msgs[i] = key + ": " + errs[key]
+	}
+
+	return errors.New("invalid configuration: " + strings.Join(msgs, "; "))
+}
+
+func printConfig(w io.Writer, cfg config) error {
+	var c config
+
+	fs := c.flagSet("")
+	c = cfg
+
+	doc := &yaml.Node{Kind: yaml.MappingNode}
+
+	fs.VisitAll(func(f *flag.Flag) {
+		value := f.Value.String()
+		if redact, ok := secretFlags[f.Name]; ok {
+			value = redact(value)
+		}
+
+		doc.Content = append(doc.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: f.Name},
+			&yaml.Node{Kind: yaml.ScalarNode, Value: value},
+		)
+	})
+
+	out, err := yaml.Marshal(doc)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(out)
+	return err
+}
+
+func redactDSN(dsn string) string {
+	if dsn == "" {
+		return ""
+	}
+
+	if u, err := url.Parse(dsn); err == nil && u.Scheme != "" {
+		return u.Redacted()
+	}
+
+	return dsnPasswordRX.ReplaceAllString(dsn, "${1}xxxxx")
+}
@@ -0,0 +1,27 @@
+package main
+
+import "context"
+
+type contextKey string
+
+const clientSubjectContextKey = contextKey("clientSubject")
+
+func contextSetClientSubject(ctx context.Context, subject string) context.Context {
+	return context.WithValue(ctx, clientSubjectContextKey, subject)
+}
+
+func contextGetClientSubject(ctx context.Context) (string, bool) {
+	subject, ok := ctx.Value(clientSubjectContextKey).(string)
+	return subject, ok
+}
+
+const requestIDContextKey = contextKey("requestID")
+
+func contextSetRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDContextKey, requestID)
+}
+
+func contextGetRequestID(ctx context.Context) (string, bool) {
+	requestID, ok := ctx.Value(requestIDContextKey).(string)
+	return requestID, ok
+}
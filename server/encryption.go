@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Vadim-Makhnev/grpc/internal/data"
+	"github.com/Vadim-Makhnev/grpc/internal/encryption"
+)
+
+// newCipher loads the keys of the configured key file. It returns nil without
+// one, which keeps names and emails in plaintext.
+func newCipher(cfg config) (*encryption.Cipher, error) {
+	if cfg.encryption.keyFile == "" {
+		return nil, nil
+	}
+
+	keys, err := encryption.LoadKeyFile(cfg.encryption.keyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return encryption.New(keys), nil
+}
+
+// backfillEmailHashes gives every user stored in plaintext an email hash
+// before the server starts taking requests, so that the unique index on
+// email_hash covers all users. Writes claim the hash of a plaintext user
+// with the same email themselves; the backfill makes sure nothing is left
+// to claim. It can't see any tenant with row-level security, where usersbulk
+// reencrypt has to be run for each of them instead.
+func (app *application) backfillEmailHashes(ctx context.Context, users data.UserModel) error {
+	tenants, err := users.ReencryptionTenants(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to find users without an email hash: %w", err)
+	}
+
+	for _, tenant := range tenants {
+		tenantCtx := data.ContextWithTenant(ctx, tenant)
+		total := 0
+
+		for {
+			n, err := users.BackfillEmailHashes(tenantCtx, app.config.encryption.reencryptBatch)
+			if err != nil {
+				return fmt.Errorf("unable to backfill email hashes of tenant %s: %w", tenant, err)
+			}
+
+			if n == 0 {
+				break
+			}
+
+			total += n
+		}
+
+		if total > 0 {
+			app.logger.Info("email hashes backfilled", "tenant", tenant, "count", total)
+		}
+	}
+
+	return nil
+}
+
+// reencryptUsers brings every user to the current key, right away and then
+// every reencrypt interval: users stored before encryption was enabled get
+// encrypted, and after a key rotation the others move off the old key, which
+// can be dropped from the key file once a run finds nothing left to do. It
+// returns once ctx is cancelled; a batch in progress is rolled back.
+func (app *application) reencryptUsers(ctx context.Context, users data.UserModel) {
+	ticker := time.NewTicker(app.config.encryption.reencryptInterval)
+	defer ticker.Stop()
+
+	for {
+		app.reencryptTenants(ctx, users)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (app *application) reencryptTenants(ctx context.Context, users data.UserModel) {
+	tenants, err := users.ReencryptionTenants(ctx)
+	if ctx.Err() != nil {
+		return
+	}
+
+	if err != nil {
+		app.logger.Error("unable to find users to re-encrypt", "error", err)
+		return
+	}
+
+	for _, tenant := range tenants {
+		tenantCtx := data.ContextWithTenant(ctx, tenant)
+		total := 0
+
+		for {
+			n, err := users.ReencryptUsers(tenantCtx, app.config.encryption.reencryptBatch)
+			if ctx.Err() != nil {
+				return
+			}
+
+			if err != nil {
+				app.logger.Error("unable to re-encrypt users", "tenant", tenant, "error", err)
+				break
+			}
+
+			total += n
+
+			if n == 0 {
+				break
+			}
+		}
+
+		if total > 0 {
+			app.logger.Info("users re-encrypted", "tenant", tenant, "count", total, "key_version", users.Cipher.CurrentKeyVersion())
+		}
+	}
+}
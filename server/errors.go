@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Vadim-Makhnev/grpc/internal/data"
+	"github.com/Vadim-Makhnev/grpc/internal/grpcutils"
+	"github.com/Vadim-Makhnev/grpc/internal/i18n"
+	"github.com/Vadim-Makhnev/grpc/internal/validator"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// errorMapping translates the errors it recognizes into status errors and
+// returns nil for any other error.
+type errorMapping func(app *application, ctx context.Context, err error) error
+
+// errorMappings are tried in order; the first one that recognizes an error
+// wins. Errors nobody recognizes become Internal. Support for a new domain
+// error is added by appending a mapping here.
+var errorMappings = []errorMapping{
+	mapValidationError,
+	mapSentinel(data.ErrRecordNotFound, func(app *application, ctx context.Context, err error) error {
+		return grpcutils.NotFound("")
+	}),
+	mapSentinel(data.ErrInvalidArgument, func(app *application, ctx context.Context, err error) error {
+		return grpcutils.InvalidArgument(app.requestLogger(ctx), err, "")
+	}),
+	mapSentinel(data.ErrEditConflict, func(app *application, ctx context.Context, err error) error {
+		return grpcutils.EditConflict(app.requestLogger(ctx), err, "")
+	}),
+	mapSentinel(data.ErrDuplicateEmail, func(app *application, ctx context.Context, err error) error {
+		return grpcutils.InvalidField("email", i18n.ReasonDuplicateEmail)
+	}),
+	mapCircuitOpen,
+	mapSentinel(context.Canceled, func(app *application, ctx context.Context, err error) error {
+		return grpcutils.New(codes.Canceled, i18n.New(i18n.ReasonCanceled))
+	}),
+	mapSentinel(context.DeadlineExceeded, func(app *application, ctx context.Context, err error) error {
+		return grpcutils.New(codes.DeadlineExceeded, i18n.New(i18n.ReasonDeadlineExceeded))
+	}),
+}
+
+// statusError converts an error returned by a handler into a status error.
+// Errors that already carry a status are returned unchanged.
+func (app *application) statusError(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	for _, mapping := range errorMappings {
+		if st := mapping(app, ctx, err); st != nil {
+			return st
+		}
+	}
+
+	return grpcutils.Internal(app.requestLogger(ctx), err, "")
+}
+
+func (app *application) errorsUnary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	resp, err := handler(ctx, req)
+	if err != nil {
+		return nil, app.statusError(ctx, err)
+	}
+
+	return resp, nil
+}
+
+func (app *application) errorsStream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return app.statusError(ss.Context(), handler(srv, ss))
+}
+
+func mapSentinel(target error, fn errorMapping) errorMapping {
+	return func(app *application, ctx context.Context, err error) error {
+		if !errors.Is(err, target) {
+			return nil
+		}
+		return fn(app, ctx, err)
+	}
+}
+
+func mapValidationError(app *application, ctx context.Context, err error) error {
+	var validationErr *validator.ValidationError
+	if !errors.As(err, &validationErr) {
+		return nil
+	}
+
+	app.requestLogger(ctx).Warn("validation failed", "error", err)
+
+	return grpcutils.FailedValidationFields(validationErr.Fields)
+}
+
+func mapCircuitOpen(app *application, ctx context.Context, err error) error {
+	var openErr *data.CircuitOpenError
+	if !errors.As(err, &openErr) {
+		return nil
+	}
+
+	return grpcutils.Unavailable(app.requestLogger(ctx), err, openErr.RetryAfter)
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/Vadim-Makhnev/grpc/internal/data"
+	"github.com/Vadim-Makhnev/grpc/internal/grpcutils"
+	"github.com/Vadim-Makhnev/grpc/internal/validator"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestApplication_StatusError(t *testing.T) {
+	app := &application{logger: slog.New(slog.NewJSONHandler(io.Discard, nil))}
+
+	v := validator.New()
+	v.AddError("name", "must be provided")
+
+	tests := []struct {
+		name       string
+		err        error
+		wantCode   codes.Code
+		wantReason string
+	}{
+		{name: "not found", err: data.ErrRecordNotFound, wantCode: codes.NotFound, wantReason: "USER_NOT_FOUND"},
+		{name: "wrapped not found", err: fmt.Errorf("get user: %w", data.ErrRecordNotFound), wantCode: codes.NotFound, wantReason: "USER_NOT_FOUND"},
+		{name: "invalid argument", err: data.ErrInvalidArgument, wantCode: codes.InvalidArgument, wantReason: "INVALID_ARGUMENT"},
+		{name: "edit conflict", err: data.ErrEditConflict, wantCode: codes.Aborted, wantReason: "EDIT_CONFLICT"},
+		{name: "duplicate email", err: data.ErrDuplicateEmail, wantCode: codes.InvalidArgument, wantReason: "INVALID_REQUEST"},
+		{name: "validation", err: v.Err(), wantCode: codes.InvalidArgument, wantReason: "INVALID_REQUEST"},
+		{name: "circuit open", err: &data.CircuitOpenError{}, wantCode: codes.Unavailable, wantReason: "UNAVAILABLE"},
+		{name: "canceled", err: context.Canceled, wantCode: codes.Canceled, wantReason: "CANCELED"},
+		{name: "deadline", err: fmt.Errorf("query: %w", context.DeadlineExceeded), wantCode: codes.DeadlineExceeded, wantReason: "DEADLINE_EXCEEDED"},
+		{name: "unknown", err: errors.New("pq: connection reset"), wantCode: codes.Internal, wantReason: "INTERNAL"},
+		{name: "status passes through", err: grpcutils.NotFound(""), wantCode: codes.NotFound, wantReason: "USER_NOT_FOUND"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := app.statusError(context.Background(), tt.err)
+
+			assert.Equal(t, tt.wantCode, status.Code(err))
+			assert.Equal(t, tt.wantReason, grpcutils.Reason(err))
+		})
+	}
+
+	assert.NoError(t, app.statusError(context.Background(), nil))
+}
+
+func TestApplication_ErrorsUnary(t *testing.T) {
+	app := &application{logger: slog.New(slog.NewJSONHandler(io.Discard, nil))}
+
+	handler := func(ctx context.Context, req any) (any, error) {
+		return nil, data.ErrInvalidArgument
+	}
+
+	_, err := app.errorsUnary(context.Background(), nil, &grpc.UnaryServerInfo{}, handler)
+	require.Error(t, err)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
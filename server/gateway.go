@@ -0,0 +1,412 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Vadim-Makhnev/grpc/internal/grpcutils"
+	"github.com/Vadim-Makhnev/grpc/internal/i18n"
+	"github.com/Vadim-Makhnev/grpc/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	protobuf "google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const maxGatewayBodyBytes = 1_048_576
+
+var gatewayMarshaler = protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true}
+
+type gateway struct {
+	app     *application
+	service proto.UserServiceServer
+}
+
+func (app *application) gatewayRoutes(service proto.UserServiceServer) http.Handler {
+	gw := &gateway{app: app, service: service}
+
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("POST /v1/users", gw.createUser)
+	mux.HandleFunc("GET /v1/users", gw.listUsers)
+	mux.HandleFunc("GET /v1/users/{id}", gw.getUser)
+	mux.HandleFunc("GET /v1/users:lookup", gw.lookupUser)
+	mux.HandleFunc("GET /v1/users:search", gw.searchUsers)
+	mux.HandleFunc("GET /v1/users:stats", gw.userStats)
+	mux.HandleFunc("PATCH /v1/users/{id}", gw.updateUser)
+	mux.HandleFunc("DELETE /v1/users/{id}", gw.deleteUser)
+	mux.HandleFunc("GET /v1/users/{id}/export", gw.exportUserData)
+	mux.HandleFunc("POST /v1/users/{id}/erase", gw.eraseUser)
+
+	return gw.requestID(gw.clientIdentity(gw.locale(gw.tenant(mux))))
+}
+
+func (gw *gateway) createUser(w http.ResponseWriter, r *http.Request) {
+	var req proto.CreateUserRequest
+
+	if err := gw.readBody(w, r, &req); err != nil {
+		gw.writeError(w, r, err)
+		return
+	}
+
+	resp, err := gw.service.CreateUser(r.Context(), &req)
+	if err != nil {
+		gw.writeError(w, r, err)
+		return
+	}
+
+	gw.writeMessage(w, r, http.StatusCreated, resp)
+}
+
+func (gw *gateway) getUser(w http.ResponseWriter, r *http.Request) {
+	id, err := gw.readIDParam(r)
+	if err != nil {
+		gw.writeError(w, r, err)
+		return
+	}
+
+	resp, err := gw.service.GetUser(r.Context(), &proto.GetUserRequest{Id: id})
+	if err != nil {
+		gw.writeError(w, r, err)
+		return
+	}
+
+	gw.writeMessage(w, r, http.StatusOK, resp)
+}
+
+func (gw *gateway) lookupUser(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+
+	var req proto.LookupUserRequest
+
+	switch {
+	case qs.Has("id") && qs.Has("email"):
+		gw.writeError(w, r, grpcutils.InvalidField("key", i18n.ReasonLookupKeyConflict))
+		return
+	case qs.Has("id"):
+		id, err := strconv.ParseInt(qs.Get("id"), 10, 64)
+		if err != nil || id < 1 {
+			gw.writeError(w, r, grpcutils.InvalidField("id", i18n.ReasonNotPositive))
+			return
+		}
+		req.Key = &proto.LookupUserRequest_Id{Id: id}
+	case qs.Has("email"):
+		req.Key = &proto.LookupUserRequest_Email{Email: qs.Get("email")}
+	}
+
+	resp, err := gw.service.LookupUser(r.Context(), &req)
+	if err != nil {
+		gw.writeError(w, r, err)
+		return
+	}
+
+	gw.writeMessage(w, r, http.StatusOK, resp)
+}
+
+func (gw *gateway) listUsers(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+
+	page, err := gw.readInt32Query(qs.Get("page"), "page")
+	if err != nil {
+		gw.writeError(w, r, err)
+		return
+	}
+
+	pageSize, err := gw.readInt32Query(qs.Get("page_size"), "page_size")
+	if err != nil {
+		gw.writeError(w, r, err)
+		return
+	}
+
+	resp, err := gw.service.ListUsers(r.Context(), &proto.ListUsersRequest{
+		Page:          page,
+		PageSize:      pageSize,
+		Sort:          qs.Get("sort"),
+		Attributes:    gw.readAttributesQuery(qs),
+		HasAttributes: gw.readListQuery(qs, "has_attributes"),
+	})
+	if err != nil {
+		gw.writeError(w, r, err)
+		return
+	}
+
+	gw.writeMessage(w, r, http.StatusOK, resp)
+}
+
+func (gw *gateway) searchUsers(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+
+	page, err := gw.readInt32Query(qs.Get("page"), "page")
+	if err != nil {
+		gw.writeError(w, r, err)
+		return
+	}
+
+	pageSize, err := gw.readInt32Query(qs.Get("page_size"), "page_size")
+	if err != nil {
+		gw.writeError(w, r, err)
+		return
+	}
+
+	resp, err := gw.service.SearchUsers(r.Context(), &proto.SearchUsersRequest{
+		Query:    qs.Get("query"),
+		Page:     page,
+		PageSize: pageSize,
+	})
+	if err != nil {
+		gw.writeError(w, r, err)
+		return
+	}
+
+	gw.writeMessage(w, r, http.StatusOK, resp)
+}
+
+func (gw *gateway) userStats(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+
+	topDomains, err := gw.readInt32Query(qs.Get("top_domains"), "top_domains")
+	if err != nil {
+		gw.writeError(w, r, err)
+		return
+	}
+
+	createdFrom, err := gw.readTimeQuery(qs.Get("created_from"), "created_from")
+	if err != nil {
+		gw.writeError(w, r, err)
+		return
+	}
+
+	createdTo, err := gw.readTimeQuery(qs.Get("created_to"), "created_to")
+	if err != nil {
+		gw.writeError(w, r, err)
+		return
+	}
+
+	resp, err := gw.service.GetUserStats(r.Context(), &proto.GetUserStatsRequest{
+		CreatedFrom: createdFrom,
+		CreatedTo:   createdTo,
+		Attributes:  gw.readAttributesQuery(qs),
+		Interval:    qs.Get("interval"),
+		TopDomains:  topDomains,
+	})
+	if err != nil {
+		gw.writeError(w, r, err)
+		return
+	}
+
+	gw.writeMessage(w, r, http.StatusOK, resp)
+}
+
+func (gw *gateway) updateUser(w http.ResponseWriter, r *http.Request) {
+	id, err := gw.readIDParam(r)
+	if err != nil {
+		gw.writeError(w, r, err)
+		return
+	}
+
+	var req proto.UpdateUserRequest
+
+	if err := gw.readBody(w, r, &req); err != nil {
+		gw.writeError(w, r, err)
+		return
+	}
+
+	req.Id = id
+
+	resp, err := gw.service.UpdateUser(r.Context(), &req)
+	if err != nil {
+		gw.writeError(w, r, err)
+		return
+	}
+
+	gw.writeMessage(w, r, http.StatusOK, resp)
+}
+
+func (gw *gateway) deleteUser(w http.ResponseWriter, r *http.Request) {
+	id, err := gw.readIDParam(r)
+	if err != nil {
+		gw.writeError(w, r, err)
+		return
+	}
+
+	resp, err := gw.service.DeleteUser(r.Context(), &proto.DeleteUserRequest{Id: id})
+	if err != nil {
+		gw.writeError(w, r, err)
+		return
+	}
+
+	gw.writeMessage(w, r, http.StatusOK, resp)
+}
+
+func (gw *gateway) exportUserData(w http.ResponseWriter, r *http.Request) {
+	id, err := gw.readIDParam(r)
+	if err != nil {
+		gw.writeError(w, r, err)
+		return
+	}
+
+	resp, err := gw.service.ExportUserData(r.Context(), &proto.ExportUserDataRequest{Id: id})
+	if err != nil {
+		gw.writeError(w, r, err)
+		return
+	}
+
+	gw.writeMessage(w, r, http.StatusOK, resp)
+}
+
+func (gw *gateway) eraseUser(w http.ResponseWriter, r *http.Request) {
+	id, err := gw.readIDParam(r)
+	if err != nil {
+		gw.writeError(w, r, err)
+		return
+	}
+
+	resp, err := gw.service.EraseUser(r.Context(), &proto.EraseUserRequest{Id: id})
+	if err != nil {
+		gw.writeError(w, r, err)
+		return
+	}
+
+	gw.writeMessage(w, r, http.StatusOK, resp)
+}
+
+func (gw *gateway) readIDParam(r *http.Request) (int64, error) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || id < 1 {
+		return 0, grpcutils.InvalidField("id", i18n.ReasonNotPositive)
+	}
+
+	return id, nil
+}
+
+func (gw *gateway) readInt32Query(value, key string) (int32, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	i, err := strconv.ParseInt(value, 10, 32)
+	if err != nil {
+		return 0, grpcutils.InvalidField(key, i18n.ReasonNotInteger)
+	}
+
+	return int32(i), nil
+}
+
+// readTimeQuery accepts a date (YYYY-MM-DD, midnight UTC) or an RFC 3339 timestamp.
+func (gw *gateway) readTimeQuery(value, key string) (*timestamppb.Timestamp, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	for _, layout := range []string{time.DateOnly, time.RFC3339} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return timestamppb.New(t), nil
+		}
+	}
+
+	return nil, grpcutils.InvalidField(key, i18n.ReasonInvalidTimestamp)
+}
+
+// readAttributesQuery collects attribute filters given as
+// ?attributes.department=sales&attributes.team=core.
+func (gw *gateway) readAttributesQuery(qs url.Values) map[string]string {
+	var attrs map[string]string
+
+	for key, values := range qs {
+		name, ok := strings.CutPrefix(key, "attributes.")
+		if !ok {
+			continue
+		}
+
+		if attrs == nil {
+			attrs = make(map[string]string)
+		}
+		attrs[name] = values[0]
+	}
+
+	return attrs
+}
+
+// readListQuery accepts both ?key=a,b and ?key=a&key=b.
+func (gw *gateway) readListQuery(qs url.Values, key string) []string {
+	var list []string
+
+	for _, value := range qs[key] {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+	}
+
+	return list
+}
+
+func (gw *gateway) readBody(w http.ResponseWriter, r *http.Request, dst protobuf.Message) error {
+	r.Body = http.MaxBytesReader(w, r.Body, maxGatewayBodyBytes)
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		var maxBytesError *http.MaxBytesError
+
+		switch {
+		case errors.As(err, &maxBytesError):
+			return grpcutils.New(codes.InvalidArgument, i18n.New(i18n.ReasonBodyTooLarge, maxBytesError.Limit))
+		default:
+			return grpcutils.New(codes.InvalidArgument, i18n.New(i18n.ReasonInvalidRequest))
+		}
+	}
+
+	if len(body) == 0 {
+		return grpcutils.New(codes.InvalidArgument, i18n.New(i18n.ReasonBodyEmpty))
+	}
+
+	if err := protojson.Unmarshal(body, dst); err != nil {
+		return grpcutils.New(codes.InvalidArgument, i18n.New(i18n.ReasonBodyMalformed))
+	}
+
+	return nil
+}
+
+func (gw *gateway) writeMessage(w http.ResponseWriter, r *http.Request, code int, msg protobuf.Message) {
+	js, err := gatewayMarshaler.Marshal(msg)
+	if err != nil {
+		gw.writeError(w, r, grpcutils.Internal(gw.app.logger, err, ""))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(js)
+}
+
+func (gw *gateway) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	err = gw.app.statusError(r.Context(), err)
+
+	if locale, ok := i18n.LocaleFromContext(r.Context()); ok {
+		err = grpcutils.Localize(err, locale)
+	}
+
+	if requestID, ok := contextGetRequestID(r.Context()); ok {
+		err = grpcutils.WithRequestInfo(err, requestID)
+	}
+
+	st := status.Convert(err)
+
+	js, err := gatewayMarshaler.Marshal(st.Proto())
+	if err != nil {
+		gw.app.logger.Error("unable to marshal error response", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(grpcutils.HTTPStatusFromCode(st.Code()))
+	w.Write(js)
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Vadim-Makhnev/grpc/internal/data"
+	"github.com/Vadim-Makhnev/grpc/internal/data/mocks"
+	"github.com/Vadim-Makhnev/grpc/proto"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+func newTestGateway() http.Handler {
+	models := data.Models{
+		Users: mocks.NewUserStorageMock(),
+	}
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	app := &application{
+		logger: logger,
+		models: models,
+	}
+	app.config.tenancy.defaultTenant = "default"
+
+	return app.gatewayRoutes(&UserService{app: app})
+}
+
+func TestGateway_CreateUser_Success(t *testing.T) {
+	handler := newTestGateway()
+
+	body := `{"name":"Andrew","email":"andrew@google.com","age":31}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/users", strings.NewReader(body))
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+
+	var user proto.UserResponse
+	assert.NoError(t, protojson.Unmarshal(rr.Body.Bytes(), &user))
+	assert.Equal(t, int64(1), user.Id)
+	assert.Equal(t, "Andrew", user.Name)
+}
+
+func TestGateway_CreateUser_InvalidEmail(t *testing.T) {
+	handler := newTestGateway()
+
+	body := `{"name":"Andrew","email":"invalid-email","age":31}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/users", strings.NewReader(body))
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	var st status.Status
+	assert.NoError(t, protojson.Unmarshal(rr.Body.Bytes(), &st))
+	assert.Len(t, st.Details, 3)
+
+	var violation errdetails.BadRequest_FieldViolation
+	assert.NoError(t, st.Details[0].UnmarshalTo(&violation))
+	assert.Equal(t, "email", violation.Field)
+	assert.Equal(t, "INVALID_EMAIL", violation.Reason)
+
+	var info errdetails.ErrorInfo
+	assert.NoError(t, st.Details[1].UnmarshalTo(&info))
+	assert.Equal(t, "INVALID_REQUEST", info.Reason)
+
+	var requestInfo errdetails.RequestInfo
+	assert.NoError(t, st.Details[2].UnmarshalTo(&requestInfo))
+	assert.Equal(t, rr.Header().Get("X-Request-Id"), requestInfo.RequestId)
+}
+
+func TestGateway_RequestID(t *testing.T) {
+	handler := newTestGateway()
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/users/2", nil)
+	req.Header.Set("X-Request-Id", "client-req-42")
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.Equal(t, "client-req-42", rr.Header().Get("X-Request-Id"))
+
+	var st status.Status
+	assert.NoError(t, protojson.Unmarshal(rr.Body.Bytes(), &st))
+	assert.Len(t, st.Details, 2)
+
+	var info errdetails.ErrorInfo
+	assert.NoError(t, st.Details[0].UnmarshalTo(&info))
+	assert.Equal(t, "USER_NOT_FOUND", info.Reason)
+
+	var requestInfo errdetails.RequestInfo
+	assert.NoError(t, st.Details[1].UnmarshalTo(&requestInfo))
+	assert.Equal(t, "client-req-42", requestInfo.RequestId)
+}
+
+func TestGateway_CreateUser_BadJSON(t *testing.T) {
+	handler := newTestGateway()
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/users", strings.NewReader(`{"name":`))
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func TestGateway_GetUser_Success(t *testing.T) {
+	handler := newTestGateway()
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/users/1", nil)
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var user proto.UserResponse
+	assert.NoError(t, protojson.Unmarshal(rr.Body.Bytes(), &user))
+	assert.Equal(t, int64(1), user.Id)
+}
+
+func TestGateway_LookupUser(t *testing.T) {
+	handler := newTestGateway()
+
+	tests := []struct {
+		name     string
+		query    string
+		wantCode int
+	}{
+		{name: "by email", query: "email=andrew@Google.com", wantCode: http.StatusOK},
+		{name: "by id", query: "id=1", wantCode: http.StatusOK},
+		{name: "unknown email", query: "email=john@gmail.com", wantCode: http.StatusNotFound},
+		{name: "both keys", query: "id=1&email=andrew@google.com", wantCode: http.StatusBadRequest},
+		{name: "no key", query: "", wantCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/v1/users:lookup?"+tt.query, nil)
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.wantCode, rr.Code)
+
+			if tt.wantCode == http.StatusOK {
+				var user proto.UserResponse
+				assert.NoError(t, protojson.Unmarshal(rr.Body.Bytes(), &user))
+				assert.Equal(t, int64(1), user.Id)
+			}
+		})
+	}
+}
+
+func TestGateway_GetUser_NotFound(t *testing.T) {
+	handler := newTestGateway()
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/users/2", nil)
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
+
+func TestGateway_GetUser_InvalidID(t *testing.T) {
+	handler := newTestGateway()
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/users/abc", nil)
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func TestGateway_ListUsers_Success(t *testing.T) {
+	handler := newTestGateway()
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/users?page=1&page_size=10&sort=-id", nil)
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var users proto.ListUsersResponse
+	assert.NoError(t, protojson.Unmarshal(rr.Body.Bytes(), &users))
+	assert.Len(t, users.Users, 2)
+}
+
+func TestGateway_ListUsers_InvalidSort(t *testing.T) {
+	handler := newTestGateway()
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/users?sort=password", nil)
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func TestGateway_UpdateUser_Success(t *testing.T) {
+	handler := newTestGateway()
+
+	req := httptest.NewRequest(http.MethodPatch, "/v1/users/1", strings.NewReader(`{"email":"john@gmail.com"}`))
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var user proto.UserResponse
+	assert.NoError(t, protojson.Unmarshal(rr.Body.Bytes(), &user))
+	assert.Equal(t, "Andrew", user.Name)
+	assert.Equal(t, "john@gmail.com", user.Email)
+	assert.Equal(t, int32(2), user.Version)
+}
+
+func TestGateway_UpdateUser_EditConflict(t *testing.T) {
+	handler := newTestGateway()
+
+	req := httptest.NewRequest(http.MethodPatch, "/v1/users/3", strings.NewReader(`{"name":"John"}`))
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusConflict, rr.Code)
+}
+
+func TestGateway_DeleteUser_Success(t *testing.T) {
+	handler := newTestGateway()
+
+	req := httptest.NewRequest(http.MethodDelete, "/v1/users/1", nil)
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+}
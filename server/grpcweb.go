@@ -0,0 +1,23 @@
+package main
+
+import (
+	"github.com/improbable-eng/grpc-web/go/grpcweb"
+	"google.golang.org/grpc"
+)
+
+func (app *application) grpcWebHandler(grpcServer *grpc.Server) *grpcweb.WrappedGrpcServer {
+	return grpcweb.WrapServer(grpcServer,
+		grpcweb.WithOriginFunc(app.isTrustedOrigin),
+		grpcweb.WithAllowedRequestHeaders([]string{"*"}),
+	)
+}
+
+func (app *application) isTrustedOrigin(origin string) bool {
+	for _, trusted := range app.config.cors.trustedOrigins {
+		if trusted == "*" || trusted == origin {
+			return true
+		}
+	}
+
+	return false
+}
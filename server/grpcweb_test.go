@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strings"
+	"testing"
+
+	"github.com/Vadim-Makhnev/grpc/internal/data"
+	"github.com/Vadim-Makhnev/grpc/internal/data/mocks"
+	"github.com/Vadim-Makhnev/grpc/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc"
+	protobuf "google.golang.org/protobuf/proto"
+)
+
+func newTestGRPCWeb(trustedOrigins ...string) http.Handler {
+	models := data.Models{
+		Users: mocks.NewUserStorageMock(),
+	}
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	app := &application{
+		logger:  logger,
+		models:  models,
+		metrics: newMetrics(nil),
+	}
+	app.config.cors.trustedOrigins = trustedOrigins
+	app.config.tenancy.defaultTenant = "default"
+
+	grpcServer := grpc.NewServer(app.interceptors()...)
+	service := &UserService{app: app}
+	proto.RegisterUserServiceServer(grpcServer, service)
+
+	return app.httpRoutes(grpcServer, service)
+}
+
+func grpcWebRequest(t *testing.T, method string, msg protobuf.Message) *http.Request {
+	payload, err := protobuf.Marshal(msg)
+	require.NoError(t, err)
+
+	frame := make([]byte, 5+len(payload))
+	binary.BigEndian.PutUint32(frame[1:5], uint32(len(payload)))
+	copy(frame[5:], payload)
+
+	req := httptest.NewRequest(http.MethodPost, "/user.UserService/"+method, bytes.NewReader(frame))
+	req.Header.Set("Content-Type", "application/grpc-web+proto")
+	req.Header.Set("X-Grpc-Web", "1")
+
+	return req
+}
+
+func grpcWebTrailers(t *testing.T, rr *httptest.ResponseRecorder) (http.Header, [][]byte) {
+	trailers := rr.Header().Clone()
+	var messages [][]byte
+
+	body := rr.Body.Bytes()
+	for len(body) >= 5 {
+		flag := body[0]
+		length := binary.BigEndian.Uint32(body[1:5])
+		require.GreaterOrEqual(t, len(body)-5, int(length))
+		chunk := body[5 : 5+length]
+		body = body[5+length:]
+
+		if flag&0x80 == 0 {
+			messages = append(messages, chunk)
+			continue
+		}
+
+		tp := textproto.NewReader(bufio.NewReader(io.MultiReader(bytes.NewReader(chunk), strings.NewReader("\r\n"))))
+		mime, err := tp.ReadMIMEHeader()
+		require.NoError(t, err)
+		for k, v := range mime {
+			trailers[k] = v
+		}
+	}
+
+	return trailers, messages
+}
+
+func TestGRPCWeb_GetUser_Success(t *testing.T) {
+	handler := newTestGRPCWeb()
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, grpcWebRequest(t, "GetUser", &proto.GetUserRequest{Id: 1}))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	trailers, messages := grpcWebTrailers(t, rr)
+	assert.Equal(t, "0", trailers.Get("Grpc-Status"))
+	require.Len(t, messages, 1)
+
+	var user proto.UserResponse
+	require.NoError(t, protobuf.Unmarshal(messages[0], &user))
+	assert.Equal(t, int64(1), user.Id)
+}
+
+func TestGRPCWeb_CreateUser_StatusDetails(t *testing.T) {
+	handler := newTestGRPCWeb()
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, grpcWebRequest(t, "CreateUser", &proto.CreateUserRequest{
+		Name:  "Andrew",
+		Email: "invalid-email",
+		Age:   31,
+	}))
+
+	trailers, _ := grpcWebTrailers(t, rr)
+	assert.Equal(t, "3", trailers.Get("Grpc-Status"))
+
+	raw, err := base64.RawStdEncoding.DecodeString(strings.TrimRight(trailers.Get("Grpc-Status-Details-Bin"), "="))
+	require.NoError(t, err)
+
+	var st spb.Status
+	require.NoError(t, protobuf.Unmarshal(raw, &st))
+	require.Len(t, st.Details, 3)
+
+	var violation errdetails.BadRequest_FieldViolation
+	require.NoError(t, st.Details[0].UnmarshalTo(&violation))
+	assert.Equal(t, "email", violation.Field)
+
+	var info errdetails.ErrorInfo
+	require.NoError(t, st.Details[1].UnmarshalTo(&info))
+	assert.Equal(t, "INVALID_REQUEST", info.Reason)
+
+	var requestInfo errdetails.RequestInfo
+	require.NoError(t, st.Details[2].UnmarshalTo(&requestInfo))
+	assert.NotEmpty(t, requestInfo.RequestId)
+}
+
+func TestGRPCWeb_CORS(t *testing.T) {
+	handler := newTestGRPCWeb("https://admin.example.com")
+
+	tests := []struct {
+		name    string
+		origin  string
+		allowed bool
+	}{
+		{name: "trusted origin", origin: "https://admin.example.com", allowed: true},
+		{name: "untrusted origin", origin: "https://evil.example.com", allowed: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodOptions, "/user.UserService/GetUser", nil)
+			req.Header.Set("Origin", tt.origin)
+			req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+			req.Header.Set("Access-Control-Request-Headers", "content-type,x-grpc-web")
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if tt.allowed {
+				assert.Equal(t, tt.origin, rr.Header().Get("Access-Control-Allow-Origin"))
+			} else {
+				assert.Empty(t, rr.Header().Get("Access-Control-Allow-Origin"))
+			}
+		})
+	}
+}
+
+func TestGRPCWeb_GatewayFallthrough(t *testing.T) {
+	handler := newTestGRPCWeb()
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/users/1", nil)
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"log/slog"
+
+	"github.com/Vadim-Makhnev/grpc/internal/data"
+	"github.com/Vadim-Makhnev/grpc/proto"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func newHealthServer() *health.Server {
+	hs := health.NewServer()
+	hs.SetServingStatus(proto.UserService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	return hs
+}
+
+func breakerStateChanged(logger *slog.Logger, hs *health.Server) func(from, to data.BreakerState) {
+	return func(from, to data.BreakerState) {
+		servingStatus := healthpb.HealthCheckResponse_SERVING
+
+		if to == data.BreakerOpen {
+			servingStatus = healthpb.HealthCheckResponse_NOT_SERVING
+			logger.Error("database circuit breaker opened", "from", from.String(), "to", to.String())
+		} else {
+			logger.Info("database circuit breaker state changed", "from", from.String(), "to", to.String())
+		}
+
+		hs.SetServingStatus("", servingStatus)
+		hs.SetServingStatus(proto.UserService_ServiceDesc.ServiceName, servingStatus)
+	}
+}
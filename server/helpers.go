@@ -1,5 +1,16 @@
 package main
 
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/Vadim-Makhnev/grpc/internal/data"
+	"github.com/Vadim-Makhnev/grpc/internal/grpcutils"
+	"github.com/Vadim-Makhnev/grpc/internal/i18n"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
 func (app *application) getInt32(value int32, defaultValue int32) int32 {
 	if value <= 0 {
 		return defaultValue
@@ -13,3 +24,44 @@ func (app *application) getString(value string, defaultValue string) string {
 	}
 	return value
 }
+
+// parseDate parses a YYYY-MM-DD value of the named request field.
+func parseDate(field, value string) (time.Time, error) {
+	date, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return time.Time{}, grpcutils.InvalidField(field, i18n.ReasonInvalidDate)
+	}
+	return date, nil
+}
+
+func formatDate(date time.Time) string {
+	if date.IsZero() {
+		return ""
+	}
+	return date.Format(time.DateOnly)
+}
+
+func timestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
+
+func (app *application) requestLogger(ctx context.Context) *slog.Logger {
+	logger := app.logger
+
+	if requestID, ok := contextGetRequestID(ctx); ok {
+		logger = logger.With("request_id", requestID)
+	}
+
+	if subject, ok := contextGetClientSubject(ctx); ok {
+		logger = logger.With("client", subject)
+	}
+
+	if tenant, ok := data.TenantFromContext(ctx); ok {
+		logger = logger.With("tenant", tenant)
+	}
+
+	return logger
+}
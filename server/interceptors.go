@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"regexp"
+
+	"github.com/Vadim-Makhnev/grpc/internal/grpcutils"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+const requestIDMetadataKey = "x-request-id"
+
+var requestIDRX = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// interceptors returns the interceptor chains of the gRPC server. The error
+// interceptor runs closest to the handlers so that the others only ever see
+// status errors.
+func (app *application) interceptors() []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(app.metrics.unaryInterceptor, requestIDUnary, app.clientIdentityUnary, localeUnary, app.tenantUnary, app.errorsUnary),
+		grpc.ChainStreamInterceptor(app.metrics.streamInterceptor, requestIDStream, app.clientIdentityStream, localeStream, app.tenantStream, app.errorsStream),
+	}
+}
+
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+func (app *application) clientIdentityUnary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	return handler(withClientIdentity(ctx, peerTLSState(ctx)), req)
+}
+
+func (app *application) clientIdentityStream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return handler(srv, &serverStream{ServerStream: ss, ctx: withClientIdentity(ss.Context(), peerTLSState(ss.Context()))})
+}
+
+func (gw *gateway) clientIdentity(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(withClientIdentity(r.Context(), r.TLS)))
+	})
+}
+
+func withClientIdentity(ctx context.Context, state *tls.ConnectionState) context.Context {
+	cert, ok := verifiedClientCert(state)
+	if !ok {
+		return ctx
+	}
+
+	return contextSetClientSubject(ctx, cert.Subject.String())
+}
+
+// peerTLSState returns the TLS state of the connection a gRPC call came in
+// on, or nil for plaintext connections.
+func peerTLSState(ctx context.Context) *tls.ConnectionState {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil
+	}
+
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok {
+		return nil
+	}
+
+	return &tlsInfo.State
+}
+
+// verifiedClientCert returns the client certificate of a connection if it
+// was verified against the client CAs, which only happens in mtls mode.
+func verifiedClientCert(state *tls.ConnectionState) (*x509.Certificate, bool) {
+	if state == nil || len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return nil, false
+	}
+
+	return state.VerifiedChains[0][0], true
+}
+
+// requestIDUnary tags every call with the client's x-request-id, or a new one
+// if it is missing or malformed, echoes it in the response headers and adds it
+// to the RequestInfo of returned errors.
+func requestIDUnary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	requestID := requestIDFromMetadata(ctx)
+	grpc.SetHeader(ctx, metadata.Pairs(requestIDMetadataKey, requestID))
+
+	resp, err := handler(contextSetRequestID(ctx, requestID), req)
+	if err != nil {
+		return nil, grpcutils.WithRequestInfo(err, requestID)
+	}
+
+	return resp, nil
+}
+
+func requestIDStream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	requestID := requestIDFromMetadata(ss.Context())
+	ss.SetHeader(metadata.Pairs(requestIDMetadataKey, requestID))
+
+	err := handler(srv, &serverStream{ServerStream: ss, ctx: contextSetRequestID(ss.Context(), requestID)})
+	if err != nil {
+		return grpcutils.WithRequestInfo(err, requestID)
+	}
+
+	return nil
+}
+
+func (gw *gateway) requestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(requestIDMetadataKey)
+		if !requestIDRX.MatchString(requestID) {
+			requestID = rand.Text()
+		}
+
+		w.Header().Set(requestIDMetadataKey, requestID)
+
+		next.ServeHTTP(w, r.WithContext(contextSetRequestID(r.Context(), requestID)))
+	})
+}
+
+func requestIDFromMetadata(ctx context.Context) string {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(requestIDMetadataKey); len(values) > 0 && requestIDRX.MatchString(values[0]) {
+			return values[0]
+		}
+	}
+
+	return rand.Text()
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/Vadim-Makhnev/grpc/internal/grpcutils"
+	"github.com/Vadim-Makhnev/grpc/internal/i18n"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const acceptLanguageMetadataKey = "accept-language"
+
+// localeUnary negotiates the locale of clients that send accept-language and
+// attaches LocalizedMessage details to the errors they receive. Clients that
+// don't ask for a locale get the errors unchanged.
+func localeUnary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	locale, ok := localeFromMetadata(ctx)
+	if !ok {
+		return handler(ctx, req)
+	}
+
+	resp, err := handler(i18n.ContextWithLocale(ctx, locale), req)
+	if err != nil {
+		return nil, grpcutils.Localize(err, locale)
+	}
+
+	return resp, nil
+}
+
+func localeStream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	locale, ok := localeFromMetadata(ss.Context())
+	if !ok {
+		return handler(srv, ss)
+	}
+
+	ctx := i18n.ContextWithLocale(ss.Context(), locale)
+
+	if err := handler(srv, &serverStream{ServerStream: ss, ctx: ctx}); err != nil {
+		return grpcutils.Localize(err, locale)
+	}
+
+	return nil
+}
+
+func (gw *gateway) locale(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if header := r.Header.Get("Accept-Language"); header != "" {
+			r = r.WithContext(i18n.ContextWithLocale(r.Context(), i18n.Negotiate(header)))
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func localeFromMetadata(ctx context.Context) (string, bool) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", false
+	}
+
+	values := md.Get(acceptLanguageMetadataKey)
+	if len(values) == 0 || values[0] == "" {
+		return "", false
+	}
+
+	return i18n.Negotiate(values[0]), true
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Vadim-Makhnev/grpc/internal/grpcutils"
+	"github.com/Vadim-Makhnev/grpc/internal/i18n"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	rpcstatus "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+func TestLocaleUnary(t *testing.T) {
+	handler := func(ctx context.Context, req any) (any, error) {
+		locale, _ := i18n.LocaleFromContext(ctx)
+		assert.Equal(t, i18n.Russian, locale)
+		return nil, grpcutils.InvalidField("email", i18n.ReasonInvalidEmail)
+	}
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(acceptLanguageMetadataKey, "ru-RU,en;q=0.5"))
+
+	_, err := localeUnary(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/user.UserService/CreateUser"}, handler)
+
+	st := status.Convert(err)
+	assert.Equal(t, codes.InvalidArgument, st.Code())
+	assert.Equal(t, grpcutils.ErrMessageInvalidRequest, st.Message())
+	require.Len(t, st.Details(), 3)
+
+	violation, ok := st.Details()[0].(*errdetails.BadRequest_FieldViolation)
+	require.True(t, ok)
+	assert.Equal(t, "must be a valid email address", violation.Description)
+	assert.Equal(t, i18n.ReasonInvalidEmail, violation.Reason)
+	assert.Equal(t, "должно быть корректным адресом электронной почты", violation.LocalizedMessage.GetMessage())
+
+	localized, ok := st.Details()[2].(*errdetails.LocalizedMessage)
+	require.True(t, ok)
+	assert.Equal(t, "ru", localized.Locale)
+	assert.Equal(t, "некорректный запрос", localized.Message)
+}
+
+func TestLocaleUnary_NoAcceptLanguage(t *testing.T) {
+	handler := func(ctx context.Context, req any) (any, error) {
+		return nil, grpcutils.NotFound("")
+	}
+
+	_, err := localeUnary(context.Background(), nil, &grpc.UnaryServerInfo{}, handler)
+
+	st := status.Convert(err)
+	assert.Equal(t, grpcutils.ErrMessageUserNotFound, st.Message())
+	require.Len(t, st.Details(), 1)
+	assert.IsType(t, &errdetails.ErrorInfo{}, st.Details()[0])
+}
+
+func TestGateway_LocalizedErrors(t *testing.T) {
+	handler := newTestGateway()
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/users", strings.NewReader(`{"name":"Andrew","email":"invalid-email","age":31}`))
+	req.Header.Set("Accept-Language", "ru")
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	var st rpcstatus.Status
+	require.NoError(t, protojson.Unmarshal(rr.Body.Bytes(), &st))
+	require.Len(t, st.Details, 4)
+
+	var violation errdetails.BadRequest_FieldViolation
+	require.NoError(t, st.Details[0].UnmarshalTo(&violation))
+	assert.Equal(t, "email", violation.Field)
+	assert.Equal(t, "ru", violation.LocalizedMessage.GetLocale())
+
+	var localized errdetails.LocalizedMessage
+	require.NoError(t, st.Details[3].UnmarshalTo(&localized))
+	assert.Equal(t, "некорректный запрос", localized.Message)
+}
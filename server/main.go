@@ -3,54 +3,65 @@ package main
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"flag"
 	"fmt"
 	"log/slog"
 	"net"
+	"net/http"
 	"os"
+	"os/signal"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/Vadim-Makhnev/grpc/internal/data"
 	"github.com/Vadim-Makhnev/grpc/proto"
 	_ "github.com/lib/pq"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/reflection"
 )
 
 //const version = "1.0.0"
 
-type config struct {
-	port int
-	env  string
-	db   struct {
-		dsn          string
-		maxOpenConns int
-		maxIdleConns int
-		maxIdleTime  time.Duration
-	}
-}
-
 type application struct {
-	config config
-	logger *slog.Logger
-	models data.Models
+	config  config
+	logger  *slog.Logger
+	models  data.Models
+	metrics *metrics
 }
 
 func main() {
-	var cfg config
+	args := os.Args[1:]
 
-	flag.IntVar(&cfg.port, "port", 4000, "API server port")
-	flag.StringVar(&cfg.env, "env", "development", "Environment (development|staging|production)")
+	if len(args) > 0 && args[0] == "config" {
+		os.Exit(runConfigCommand(args[1:]))
+	}
 
-	flag.StringVar(&cfg.db.dsn, "db-dsn", os.Getenv("GRPC_DB_DSN"), "PostgreSQL DSN")
+	cfg, err := loadConfig(os.Args[0], args, os.Environ())
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(0)
+		}
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
 
-	flag.IntVar(&cfg.db.maxOpenConns, "db-max-open-conns", 25, "PostgreSQL max open connections")
-	flag.IntVar(&cfg.db.maxIdleConns, "db-max-idle-conns", 25, "PostgreSQL max idle connections")
-	flag.DurationVar(&cfg.db.maxIdleTime, "db-max-idle-time", 15*time.Minute, "PostgreSQL max connection idle time")
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
 
-	flag.Parse()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	shutdownTracing, err := setupTracing(cfg)
+	if err != nil {
+		logger.Error(err.Error())
+		os.Exit(1)
+	}
+
+	defer shutdownTracing(context.Background())
 
 	db, err := openDB(cfg)
 	if err != nil {
@@ -62,30 +73,182 @@ func main() {
 
 	logger.Info("database connection pool established")
 
+	cipher, err := newCipher(cfg)
+	if err != nil {
+		logger.Error(err.Error())
+		os.Exit(1)
+	}
+
+	if cipher != nil {
+		logger.Info("field encryption enabled", "key_version", cipher.CurrentKeyVersion())
+	}
+
+	models := data.NewModels(db, cfg.db.rowLevelSecurity, cipher)
+	models.TxIsolation = txIsolationLevels[cfg.db.txIsolation]
+
+	healthServer := newHealthServer()
+
+	if cfg.db.retry.maxAttempts > 1 {
+		models.Users = data.NewRetryingUserStorage(models.Users, cfg.db.retry.maxAttempts, cfg.db.retry.baseDelay, cfg.db.retry.maxDelay)
+	}
+
+	if cfg.db.breaker.enabled {
+		breaker := data.NewCircuitBreaker(data.BreakerConfig{
+			Window:            cfg.db.breaker.window,
+			MinRequests:       cfg.db.breaker.minRequests,
+			FailureRatio:      cfg.db.breaker.failureRatio,
+			SlowCallDuration:  cfg.db.breaker.slowCallDuration,
+			SlowCallRatio:     cfg.db.breaker.slowCallRatio,
+			OpenTimeout:       cfg.db.breaker.openTimeout,
+			HalfOpenMaxProbes: cfg.db.breaker.halfOpenProbes,
+			OnStateChange:     breakerStateChanged(logger, healthServer),
+		})
+		models.Users = data.NewBreakingUserStorage(models.Users, breaker)
+	}
+
+	if cfg.cache.size > 0 {
+		models.Users = data.NewCachedUserStorage(models.Users, cfg.cache.size, cfg.cache.ttl)
+		logger.Info("user cache enabled", "size", cfg.cache.size, "ttl", cfg.cache.ttl)
+	}
+
+	if cfg.stats.cacheTTL > 0 {
+		models.Users = data.NewStatsCachingUserStorage(models.Users, cfg.stats.cacheSize, cfg.stats.cacheTTL)
+		logger.Info("user stats cache enabled", "size", cfg.stats.cacheSize, "ttl", cfg.stats.cacheTTL)
+	}
+
 	app := &application{
-		config: cfg,
-		logger: logger,
-		models: data.NewModels(db),
+		config:  cfg,
+		logger:  logger,
+		models:  models,
+		metrics: newMetrics(db),
 	}
 
-	grpcServer := grpc.NewServer()
+	// background holds the goroutines that use the database pool and must
+	// finish before it is closed.
+	var background sync.WaitGroup
+
+	switch {
+	case cipher != nil && cfg.db.rowLevelSecurity:
+		// Row-level security hides every tenant from a connection without
+		// app.tenant_id, so the backfill would find nothing to do.
+		logger.Warn("email hashes are not backfilled with db-row-level-security; run usersbulk reencrypt for each tenant")
+	case cipher != nil:
+		if err := app.backfillEmailHashes(ctx, data.UserModel{DB: db, Cipher: cipher}); err != nil {
+			logger.Error(err.Error())
+			os.Exit(1)
+		}
+	}
+
+	if cipher != nil && cfg.encryption.reencryptInterval > 0 {
+		background.Add(1)
+		go func() {
+			defer background.Done()
+			app.reencryptUsers(ctx, data.UserModel{DB: db, Cipher: cipher})
+		}()
+	}
+
+	tlsConfig, err := app.serverTLSConfig()
+	if err != nil {
+		logger.Error(err.Error())
+		os.Exit(1)
+	}
+
+	serverOpts := append([]grpc.ServerOption{grpc.StatsHandler(otelgrpc.NewServerHandler())}, app.interceptors()...)
+
+	if tlsConfig != nil {
+		serverOpts = append(serverOpts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+
+	grpcServer := grpc.NewServer(serverOpts...)
 
 	userService := &UserService{app: app}
 	proto.RegisterUserServiceServer(grpcServer, userService)
+	healthpb.RegisterHealthServer(grpcServer, healthServer)
 
 	reflection.Register(grpcServer)
 
+	httpServer := &http.Server{
+		Addr:         fmt.Sprintf(":%d", cfg.httpPort),
+		Handler:      app.httpRoutes(grpcServer, userService),
+		TLSConfig:    tlsConfig,
+		IdleTimeout:  time.Minute,
+		ReadTimeout:  5 * time.Second,
+		WriteTimeout: 10 * time.Second,
+		ErrorLog:     slog.NewLogLogger(logger.Handler(), slog.LevelError),
+	}
+
+	go func() {
+		logger.Info("HTTP server starting", "port", cfg.httpPort, "tls", cfg.tls.mode)
+
+		// The gateway and gRPC-Web resolve tenants and client identities
+		// from the same certificates as the gRPC listener, so they are
+		// served with the same TLS config.
+		var err error
+		if tlsConfig != nil {
+			err = httpServer.ListenAndServeTLS("", "")
+		} else {
+			err = httpServer.ListenAndServe()
+		}
+
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error(err.Error())
+		}
+	}()
+
+	background.Add(1)
+	go func() {
+		defer background.Done()
+
+		<-ctx.Done()
+		logger.Info("shutting down")
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		if err := httpServer.Shutdown(shutdownCtx); err != nil {
+			logger.Error(err.Error())
+		}
+
+		grpcServer.GracefulStop()
+	}()
+
 	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", cfg.port))
 	if err != nil {
 		logger.Error(err.Error())
 		os.Exit(1)
 	}
 
-	logger.Info("gRPC server starting", "port", cfg.port, "env", cfg.env)
+	logger.Info("gRPC server starting", "port", cfg.port, "env", cfg.env, "tls", cfg.tls.mode)
 
 	if err := grpcServer.Serve(lis); err != nil {
 		logger.Error(err.Error())
 	}
+
+	stop()
+	background.Wait()
+}
+
+func runConfigCommand(args []string) int {
+	if len(args) == 0 || args[0] != "print" {
+		fmt.Fprintln(os.Stderr, "usage: config print [flags]")
+		return 2
+	}
+
+	cfg, err := loadConfig("config print", args[1:], os.Environ())
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	if err := printConfig(os.Stdout, cfg); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	return 0
 }
 
 func openDB(cfg config) (*sql.DB, error) {
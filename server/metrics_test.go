@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestMetrics_UnaryInterceptor(t *testing.T) {
+	db, err := sql.Open("postgres", "host=localhost dbname=users sslmode=disable")
+	require.NoError(t, err)
+	defer db.Close()
+
+	m := newMetrics(db)
+
+	info := &grpc.UnaryServerInfo{FullMethod: "/user.UserService/GetUser"}
+
+	okHandler := func(ctx context.Context, req any) (any, error) {
+		return "ok", nil
+	}
+	notFoundHandler := func(ctx context.Context, req any) (any, error) {
+		return nil, status.Error(codes.NotFound, "user not found")
+	}
+
+	_, err = m.unaryInterceptor(context.Background(), nil, info, okHandler)
+	assert.NoError(t, err)
+	_, err = m.unaryInterceptor(context.Background(), nil, info, okHandler)
+	assert.NoError(t, err)
+	_, err = m.unaryInterceptor(context.Background(), nil, info, notFoundHandler)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	rr := httptest.NewRecorder()
+	m.handler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	body := rr.Body.String()
+	assert.Contains(t, body, `users_grpc_requests_total{code="OK",method="/user.UserService/GetUser"} 2`)
+	assert.Contains(t, body, `users_grpc_requests_total{code="NotFound",method="/user.UserService/GetUser"} 1`)
+	assert.Contains(t, body, `users_grpc_request_duration_seconds_count{code="OK",method="/user.UserService/GetUser"} 2`)
+	assert.Contains(t, body, `go_sql_open_connections{db_name="users"} 0`)
+	assert.Contains(t, body, `go_sql_in_use_connections{db_name="users"} 0`)
+	assert.Contains(t, body, `go_sql_idle_connections{db_name="users"} 0`)
+	assert.Contains(t, body, `go_sql_wait_count_total{db_name="users"} 0`)
+	assert.Contains(t, body, `go_sql_wait_duration_seconds_total{db_name="users"} 0`)
+	assert.Contains(t, body, `go_sql_max_open_connections{db_name="users"} 0`)
+}
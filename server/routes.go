@@ -0,0 +1,25 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/Vadim-Makhnev/grpc/proto"
+	"google.golang.org/grpc"
+)
+
+func (app *application) httpRoutes(grpcServer *grpc.Server, service proto.UserServiceServer) http.Handler {
+	grpcWeb := app.grpcWebHandler(grpcServer)
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", app.metrics.handler())
+	mux.Handle("/", app.gatewayRoutes(service))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if grpcWeb.IsGrpcWebRequest(r) || grpcWeb.IsAcceptableGrpcCorsRequest(r) {
+			grpcWeb.ServeHTTP(w, r)
+			return
+		}
+
+		mux.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"strings"
+
+	"github.com/Vadim-Makhnev/grpc/internal/data"
+	"github.com/Vadim-Makhnev/grpc/internal/grpcutils"
+	"github.com/Vadim-Makhnev/grpc/internal/i18n"
+	"github.com/Vadim-Makhnev/grpc/internal/validator"
+	"github.com/Vadim-Makhnev/grpc/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+)
+
+const tenantMetadataKey = "x-tenant-id"
+
+var tenantScopedPrefix = "/" + proto.UserService_ServiceDesc.ServiceName + "/"
+
+func (app *application) tenantUnary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if !strings.HasPrefix(info.FullMethod, tenantScopedPrefix) {
+		return handler(ctx, req)
+	}
+
+	ctx, err := app.withTenant(ctx, peerTLSState(ctx), tenantFromMetadata(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	return handler(ctx, req)
+}
+
+func (app *application) tenantStream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if !strings.HasPrefix(info.FullMethod, tenantScopedPrefix) {
+		return handler(srv, ss)
+	}
+
+	ctx, err := app.withTenant(ss.Context(), peerTLSState(ss.Context()), tenantFromMetadata(ss.Context()))
+	if err != nil {
+		return err
+	}
+
+	return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+}
+
+func (gw *gateway) tenant(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, err := gw.app.withTenant(r.Context(), r.TLS, r.Header.Get(tenantMetadataKey))
+		if err != nil {
+			gw.writeError(w, r, err)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// withTenant resolves the tenant of a request made over a connection with
+// the given TLS state, nil for plaintext. A tenant bound to the client
+// certificate always wins; the x-tenant-id header may only repeat it. Without
+// either, the configured default tenant is used.
+func (app *application) withTenant(ctx context.Context, state *tls.ConnectionState, requested string) (context.Context, error) {
+	tenant := requested
+
+	if certTenant, ok := tenantFromCertificate(state); ok {
+		if requested != "" && requested != certTenant {
+			return ctx, grpcutils.New(codes.PermissionDenied, i18n.New(i18n.ReasonTenantMismatch))
+		}
+		tenant = certTenant
+	}
+
+	if tenant == "" {
+		tenant = app.config.tenancy.defaultTenant
+	}
+
+	v := validator.New()
+	data.ValidateTenant(v, tenantMetadataKey, tenant)
+
+	if !v.Valid() {
+		return ctx, grpcutils.FailedValidationFields(v.FieldErrors)
+	}
+
+	return data.ContextWithTenant(ctx, tenant), nil
+}
+
+func tenantFromMetadata(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	values := md.Get(tenantMetadataKey)
+	if len(values) == 0 {
+		return ""
+	}
+
+	return values[0]
+}
+
+func tenantFromCertificate(state *tls.ConnectionState) (string, bool) {
+	cert, ok := verifiedClientCert(state)
+	if !ok || len(cert.Subject.Organization) == 0 {
+		return "", false
+	}
+
+	return cert.Subject.Organization[0], true
+}
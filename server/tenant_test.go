@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/Vadim-Makhnev/grpc/internal/data"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func TestTenantUnary(t *testing.T) {
+	app := &application{logger: slog.New(slog.NewJSONHandler(io.Discard, nil))}
+	app.config.tenancy.defaultTenant = "default"
+
+	ca := newTestCert(t, "test-ca", nil, true)
+	clientCert := newTestCert(t, "admin-tool", ca, false)
+
+	withCert := func(ctx context.Context) context.Context {
+		return peer.NewContext(ctx, &peer.Peer{AuthInfo: credentials.TLSInfo{
+			State: tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{clientCert.cert, ca.cert}}},
+		}})
+	}
+
+	withHeader := func(ctx context.Context, tenant string) context.Context {
+		return metadata.NewIncomingContext(ctx, metadata.Pairs(tenantMetadataKey, tenant))
+	}
+
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		method     string
+		wantTenant string
+		wantCode   codes.Code
+	}{
+		{name: "default tenant", ctx: context.Background(), wantTenant: "default"},
+		{name: "header", ctx: withHeader(context.Background(), "acme"), wantTenant: "acme"},
+		{name: "invalid header", ctx: withHeader(context.Background(), "Acme Corp"), wantCode: codes.InvalidArgument},
+		{name: "certificate", ctx: withCert(context.Background()), wantTenant: "users"},
+		{name: "certificate and matching header", ctx: withHeader(withCert(context.Background()), "users"), wantTenant: "users"},
+		{name: "certificate and other header", ctx: withHeader(withCert(context.Background()), "acme"), wantCode: codes.PermissionDenied},
+		{name: "other services are not scoped", ctx: withHeader(context.Background(), "Acme Corp"), method: "/grpc.health.v1.Health/Check"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method := tt.method
+			if method == "" {
+				method = "/user.UserService/GetUser"
+			}
+
+			var gotTenant string
+			handler := func(ctx context.Context, req any) (any, error) {
+				gotTenant, _ = data.TenantFromContext(ctx)
+				return nil, nil
+			}
+
+			_, err := app.tenantUnary(tt.ctx, nil, &grpc.UnaryServerInfo{FullMethod: method}, handler)
+
+			if tt.wantCode != codes.OK {
+				require.Error(t, err)
+				assert.Equal(t, tt.wantCode, status.Code(err))
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantTenant, gotTenant)
+		})
+	}
+}
+
+func TestTenantUnary_Required(t *testing.T) {
+	app := &application{logger: slog.New(slog.NewJSONHandler(io.Discard, nil))}
+
+	handler := func(ctx context.Context, req any) (any, error) {
+		return nil, nil
+	}
+
+	_, err := app.tenantUnary(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/user.UserService/GetUser"}, handler)
+
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	tlsModeOff  = "off"
+	tlsModeTLS  = "tls"
+	tlsModeMTLS = "mtls"
+)
+
+type certReloader struct {
+	certFile     string
+	keyFile      string
+	clientCAFile string
+	logger       *slog.Logger
+
+	mu          sync.Mutex
+	cert        *tls.Certificate
+	clientCAs   *x509.CertPool
+	certModTime time.Time
+	keyModTime  time.Time
+	caModTime   time.Time
+}
+
+func newCertReloader(certFile, keyFile, clientCAFile string, logger *slog.Logger) (*certReloader, error) {
+	r := &certReloader{
+		certFile:     certFile,
+		keyFile:      keyFile,
+		clientCAFile: clientCAFile,
+		logger:       logger,
+	}
+
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+func (r *certReloader) serverTLSConfig(mode string) *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			if err := r.reload(); err != nil {
+				r.logger.Error("unable to reload TLS certificates, using previous ones", "error", err)
+			}
+
+			r.mu.Lock()
+			defer r.mu.Unlock()
+
+			// The HTTP listener shares this config, and gRPC-Web and
+			// gateway clients may only speak HTTP/1.1.
+			cfg := &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				NextProtos:   []string{"h2", "http/1.1"},
+				Certificates: []tls.Certificate{*r.cert},
+			}
+
+			if mode == tlsModeMTLS {
+				cfg.ClientAuth = tls.RequireAndVerifyClientCert
+				cfg.ClientCAs = r.clientCAs
+			}
+
+			return cfg, nil
+		},
+	}
+}
+
+func (r *certReloader) reload() error {
+	certInfo, err := os.Stat(r.certFile)
+	if err != nil {
+		return err
+	}
+
+	keyInfo, err := os.Stat(r.keyFile)
+	if err != nil {
+		return err
+	}
+
+	var caModTime time.Time
+	if r.clientCAFile != "" {
+		caInfo, err := os.Stat(r.clientCAFile)
+		if err != nil {
+			return err
+		}
+		caModTime = caInfo.ModTime()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.cert == nil || !certInfo.ModTime().Equal(r.certModTime) || !keyInfo.ModTime().Equal(r.keyModTime) {
+		cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+		if err != nil {
+			return err
+		}
+
+		r.cert = &cert
+		r.certModTime = certInfo.ModTime()
+		r.keyModTime = keyInfo.ModTime()
+
+		r.logger.Info("TLS certificate loaded", "cert_file", r.certFile)
+	}
+
+	if r.clientCAFile != "" && (r.clientCAs == nil || !caModTime.Equal(r.caModTime)) {
+		pem, err := os.ReadFile(r.clientCAFile)
+		if err != nil {
+			return err
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificates found in %s", r.clientCAFile)
+		}
+
+		r.clientCAs = pool
+		r.caModTime = caModTime
+
+		r.logger.Info("TLS client CA loaded", "client_ca_file", r.clientCAFile)
+	}
+
+	return nil
+}
+
+func (app *application) serverTLSConfig() (*tls.Config, error) {
+	switch app.config.tls.mode {
+	case tlsModeOff:
+		return nil, nil
+	case tlsModeTLS, tlsModeMTLS:
+		clientCAFile := ""
+		if app.config.tls.mode == tlsModeMTLS {
+			clientCAFile = app.config.tls.clientCAFile
+		}
+
+		reloader, err := newCertReloader(app.config.tls.certFile, app.config.tls.keyFile, clientCAFile, app.logger)
+		if err != nil {
+			return nil, err
+		}
+
+		return reloader.serverTLSConfig(app.config.tls.mode), nil
+	default:
+		return nil, errors.New("unknown TLS mode: " + app.config.tls.mode)
+	}
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"log/slog"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Vadim-Makhnev/grpc/internal/data"
+	"github.com/Vadim-Makhnev/grpc/internal/data/mocks"
+	"github.com/Vadim-Makhnev/grpc/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+type testCert struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM []byte
+	keyPEM  []byte
+}
+
+func newTestCert(t *testing.T, commonName string, parent *testCert, isCA bool) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: commonName, Organization: []string{"users"}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+
+	if isCA {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+		tmpl.KeyUsage |= x509.KeyUsageCertSign
+	}
+
+	parentCert, parentKey := tmpl, key
+	if parent != nil {
+		parentCert, parentKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parentCert, &key.PublicKey, parentKey)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	return &testCert{
+		cert:    cert,
+		key:     key,
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPEM:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}
+}
+
+func writeTestCert(t *testing.T, dir, name string, c *testCert, modTime time.Time) (string, string) {
+	certFile := filepath.Join(dir, name+".crt")
+	keyFile := filepath.Join(dir, name+".key")
+
+	require.NoError(t, os.WriteFile(certFile, c.certPEM, 0o600))
+	require.NoError(t, os.WriteFile(keyFile, c.keyPEM, 0o600))
+	require.NoError(t, os.Chtimes(certFile, modTime, modTime))
+	require.NoError(t, os.Chtimes(keyFile, modTime, modTime))
+
+	return certFile, keyFile
+}
+
+func TestCertReloader_ReloadsOnChange(t *testing.T) {
+	dir := t.TempDir()
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	ca := newTestCert(t, "test-ca", nil, true)
+	first := newTestCert(t, "server-1", ca, false)
+	second := newTestCert(t, "server-2", ca, false)
+
+	certFile, keyFile := writeTestCert(t, dir, "server", first, time.Now().Add(-time.Minute))
+
+	reloader, err := newCertReloader(certFile, keyFile, "", logger)
+	require.NoError(t, err)
+
+	cfg, err := reloader.serverTLSConfig(tlsModeTLS).GetConfigForClient(&tls.ClientHelloInfo{})
+	require.NoError(t, err)
+	assert.Equal(t, first.cert.Raw, cfg.Certificates[0].Certificate[0])
+
+	writeTestCert(t, dir, "server", second, time.Now())
+
+	cfg, err = reloader.serverTLSConfig(tlsModeTLS).GetConfigForClient(&tls.ClientHelloInfo{})
+	require.NoError(t, err)
+	assert.Equal(t, second.cert.Raw, cfg.Certificates[0].Certificate[0])
+}
+
+func TestMutualTLS_ClientSubject(t *testing.T) {
+	dir := t.TempDir()
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	ca := newTestCert(t, "test-ca", nil, true)
+	serverCert := newTestCert(t, "localhost", ca, false)
+	clientCert := newTestCert(t, "admin-tool", ca, false)
+
+	certFile, keyFile := writeTestCert(t, dir, "server", serverCert, time.Now())
+	caFile := filepath.Join(dir, "ca.crt")
+	require.NoError(t, os.WriteFile(caFile, ca.certPEM, 0o600))
+
+	app := &application{
+		logger: logger,
+		models: data.Models{Users: mocks.NewUserStorageMock()},
+	}
+	app.config.tls.mode = tlsModeMTLS
+	app.config.tls.certFile = certFile
+	app.config.tls.keyFile = keyFile
+	app.config.tls.clientCAFile = caFile
+
+	tlsConfig, err := app.serverTLSConfig()
+	require.NoError(t, err)
+
+	var gotSubject string
+	recordSubject := func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		gotSubject, _ = contextGetClientSubject(ctx)
+		return handler(ctx, req)
+	}
+
+	grpcServer := grpc.NewServer(
+		grpc.Creds(credentials.NewTLS(tlsConfig)),
+		grpc.ChainUnaryInterceptor(app.clientIdentityUnary, recordSubject),
+	)
+	proto.RegisterUserServiceServer(grpcServer, &UserService{app: app})
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	go grpcServer.Serve(lis)
+	t.Cleanup(grpcServer.Stop)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+
+	dial := func(t *testing.T, certs []tls.Certificate) (proto.UserServiceClient, func()) {
+		creds := credentials.NewTLS(&tls.Config{RootCAs: roots, Certificates: certs, ServerName: "localhost"})
+
+		conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(creds))
+		require.NoError(t, err)
+
+		return proto.NewUserServiceClient(conn), func() { conn.Close() }
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	t.Run("with client certificate", func(t *testing.T) {
+		keyPair, err := tls.X509KeyPair(clientCert.certPEM, clientCert.keyPEM)
+		require.NoError(t, err)
+
+		client, closeConn := dial(t, []tls.Certificate{keyPair})
+		defer closeConn()
+
+		user, err := client.GetUser(ctx, &proto.GetUserRequest{Id: 1})
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), user.Id)
+		assert.Equal(t, "CN=admin-tool,O=users", gotSubject)
+	})
+
+	t.Run("without client certificate", func(t *testing.T) {
+		client, closeConn := dial(t, nil)
+		defer closeConn()
+
+		_, err := client.GetUser(ctx, &proto.GetUserRequest{Id: 1})
+		assert.Error(t, err)
+	})
+}
+
+func TestMutualTLS_Gateway(t *testing.T) {
+	dir := t.TempDir()
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	ca := newTestCert(t, "test-ca", nil, true)
+	serverCert := newTestCert(t, "localhost", ca, false)
+	clientCert := newTestCert(t, "admin-tool", ca, false)
+
+	certFile, keyFile := writeTestCert(t, dir, "server", serverCert, time.Now())
+	caFile := filepath.Join(dir, "ca.crt")
+	require.NoError(t, os.WriteFile(caFile, ca.certPEM, 0o600))
+
+	storage := data.NewMemoryUserStorage()
+	require.NoError(t, storage.CreateUser(data.ContextWithTenant(context.Background(), "users"), &data.User{Name: "Andrew", Email: "andrew@google.com", Age: 31}))
+
+	app := &application{
+		logger: logger,
+		models: data.Models{Users: storage},
+	}
+	app.config.tls.mode = tlsModeMTLS
+	app.config.tls.certFile = certFile
+	app.config.tls.keyFile = keyFile
+	app.config.tls.clientCAFile = caFile
+
+	tlsConfig, err := app.serverTLSConfig()
+	require.NoError(t, err)
+
+	httpServer := &http.Server{
+		Handler:   app.gatewayRoutes(&UserService{app: app}),
+		TLSConfig: tlsConfig,
+		ErrorLog:  slog.NewLogLogger(logger.Handler(), slog.LevelError),
+	}
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	go httpServer.ServeTLS(lis, "", "")
+	t.Cleanup(func() { httpServer.Close() })
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+
+	keyPair, err := tls.X509KeyPair(clientCert.certPEM, clientCert.keyPEM)
+	require.NoError(t, err)
+
+	get := func(t *testing.T, certs []tls.Certificate, tenant string) (*http.Response, error) {
+		client := &http.Client{
+			Timeout: 5 * time.Second,
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{RootCAs: roots, Certificates: certs, ServerName: "localhost"},
+			},
+		}
+
+		req, err := http.NewRequest(http.MethodGet, "https://"+lis.Addr().String()+"/v1/users/1", nil)
+		require.NoError(t, err)
+
+		if tenant != "" {
+			req.Header.Set(tenantMetadataKey, tenant)
+		}
+
+		return client.Do(req)
+	}
+
+	t.Run("tenant from client certificate", func(t *testing.T) {
+		resp, err := get(t, []tls.Certificate{keyPair}, "")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "HTTP/1.1", resp.Proto)
+	})
+
+	t.Run("other tenant header", func(t *testing.T) {
+		resp, err := get(t, []tls.Certificate{keyPair}, "acme")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	})
+
+	t.Run("without client certificate", func(t *testing.T) {
+		resp, err := get(t, nil, "users")
+		if err == nil {
+			resp.Body.Close()
+		}
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	tracingExporterNone   = "none"
+	tracingExporterStdout = "stdout"
+)
+
+var tracer = otel.Tracer("github.com/Vadim-Makhnev/grpc/server")
+
+func newSpanExporter(name string, w io.Writer) (sdktrace.SpanExporter, error) {
+	switch name {
+	case tracingExporterStdout:
+		return stdouttrace.New(stdouttrace.WithWriter(w))
+	default:
+		return nil, errors.New("unknown tracing exporter: " + name)
+	}
+}
+
+func newTracerProvider(exporter sdktrace.SpanExporter, sampleRatio float64) *sdktrace.TracerProvider {
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRatio))),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName("users-grpc"))),
+	)
+}
+
+func setupTracing(cfg config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	if cfg.tracing.exporter == tracingExporterNone {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := newSpanExporter(cfg.tracing.exporter, os.Stdout)
+	if err != nil {
+		return nil, err
+	}
+
+	tp := newTracerProvider(exporter, cfg.tracing.sampleRatio)
+	otel.SetTracerProvider(tp)
+
+	return tp.Shutdown, nil
+}
+
+func startSpan(ctx context.Context, name string) (context.Context, trace.Span) {
+	return tracer.Start(ctx, name)
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"testing"
+
+	"github.com/Vadim-Makhnev/grpc/internal/data"
+	"github.com/Vadim-Makhnev/grpc/internal/data/mocks"
+	"github.com/Vadim-Makhnev/grpc/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestTracing_ServerSpanFromTraceContext(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	prevProvider, prevPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(prevProvider)
+		otel.SetTextMapPropagator(prevPropagator)
+	})
+
+	app := &application{
+		logger: slog.New(slog.NewJSONHandler(io.Discard, nil)),
+		models: data.Models{Users: mocks.NewUserStorageMock()},
+	}
+
+	grpcServer := grpc.NewServer(grpc.StatsHandler(otelgrpc.NewServerHandler()))
+	proto.RegisterUserServiceServer(grpcServer, &UserService{app: app})
+
+	lis := bufconn.Listen(1024 * 1024)
+	go grpcServer.Serve(lis)
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(),
+		"traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+
+	_, err = proto.NewUserServiceClient(conn).ListUsers(ctx, &proto.ListUsersRequest{})
+	require.NoError(t, err)
+
+	spans := exporter.GetSpans()
+
+	names := map[string]tracetest.SpanStub{}
+	for _, span := range spans {
+		names[span.Name] = span
+	}
+
+	serverSpan, ok := names["user.UserService/ListUsers"]
+	require.True(t, ok, "expected a server span for ListUsers")
+	assert.Equal(t, traceID, serverSpan.SpanContext.TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", serverSpan.Parent.SpanID().String())
+
+	validationSpan, ok := names["ValidateFilters"]
+	require.True(t, ok, "expected a child span for filter validation")
+	assert.Equal(t, serverSpan.SpanContext.SpanID(), validationSpan.Parent.SpanID())
+}
@@ -5,13 +5,17 @@ import (
 	"io"
 	"log/slog"
 	"testing"
+	"time"
 
 	"github.com/Vadim-Makhnev/grpc/internal/data"
 	"github.com/Vadim-Makhnev/grpc/internal/data/mocks"
 	"github.com/Vadim-Makhnev/grpc/proto"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 	"google.golang.org/protobuf/types/known/wrapperspb"
 )
 
@@ -63,7 +67,7 @@ func TestUserService_CreateUser_InvalidEmail(t *testing.T) {
 	})
 
 	assert.Error(t, err)
-	st, _ := status.FromError(err)
+	st := status.Convert(app.statusError(context.Background(), err))
 	assert.Equal(t, codes.InvalidArgument, st.Code())
 }
 
@@ -112,7 +116,7 @@ func TestUserService_GetUser_NotFound(t *testing.T) {
 	})
 
 	assert.Error(t, err)
-	st, _ := status.FromError(err)
+	st := status.Convert(app.statusError(context.Background(), err))
 	assert.Equal(t, codes.NotFound, st.Code())
 }
 
@@ -161,7 +165,7 @@ func TestUserService_DeleteUser_NotFound(t *testing.T) {
 	})
 
 	assert.Error(t, err)
-	st, _ := status.FromError(err)
+	st := status.Convert(app.statusError(context.Background(), err))
 	assert.Equal(t, codes.NotFound, st.Code())
 }
 
@@ -216,7 +220,7 @@ func TestUserService_UpdateUser_EditConflict(t *testing.T) {
 	})
 
 	assert.Error(t, err)
-	st, _ := status.FromError(err)
+	st := status.Convert(app.statusError(context.Background(), err))
 	assert.Equal(t, codes.Aborted, st.Code())
 }
 
@@ -239,3 +243,350 @@ func TestUserService_GetAll_Success(t *testing.T) {
 	assert.NoError(t, err)
 	assert.NotEmpty(t, users)
 }
+
+type encryptedStorage struct {
+	mocks.UserStorageMock
+}
+
+func (encryptedStorage) CanSortBy(column string) bool {
+	return column != "name" && column != "email"
+}
+
+func TestUserService_ListUsers_SortSafelist(t *testing.T) {
+	app := &application{
+		logger: slog.New(slog.NewJSONHandler(io.Discard, nil)),
+		models: data.Models{Users: encryptedStorage{}},
+	}
+	service := &UserService{app: app}
+
+	ctx := context.Background()
+
+	_, err := service.ListUsers(ctx, &proto.ListUsersRequest{Sort: "-id"})
+	assert.NoError(t, err)
+
+	_, err = service.ListUsers(ctx, &proto.ListUsersRequest{Sort: "email"})
+
+	st := status.Convert(app.statusError(ctx, err))
+	assert.Equal(t, codes.InvalidArgument, st.Code())
+
+	var fields []string
+	for _, detail := range st.Details() {
+		if violation, ok := detail.(*errdetails.BadRequest_FieldViolation); ok {
+			fields = append(fields, violation.Field)
+		}
+	}
+	assert.Equal(t, []string{"sort"}, fields)
+}
+
+type unavailableStorage struct {
+	mocks.UserStorageMock
+}
+
+func (unavailableStorage) GetUser(ctx context.Context, id int64) (*data.User, error) {
+	return nil, &data.CircuitOpenError{RetryAfter: 3 * time.Second}
+}
+
+func TestUserService_GetUser_CircuitOpen(t *testing.T) {
+	models := data.Models{
+		Users: unavailableStorage{},
+	}
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	app := &application{
+		logger: logger,
+		models: models,
+	}
+	service := &UserService{app: app}
+
+	_, err := service.GetUser(context.Background(), &proto.GetUserRequest{Id: 1})
+
+	assert.Error(t, err)
+	st := status.Convert(app.statusError(context.Background(), err))
+	assert.Equal(t, codes.Unavailable, st.Code())
+
+	var retryInfo *errdetails.RetryInfo
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.RetryInfo); ok {
+			retryInfo = info
+		}
+	}
+
+	if assert.NotNil(t, retryInfo) {
+		assert.Equal(t, 3*time.Second, retryInfo.RetryDelay.AsDuration())
+	}
+}
+
+func TestUserService_LookupUser(t *testing.T) {
+	models := data.Models{
+		Users: mocks.NewUserStorageMock(),
+	}
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	app := &application{
+		logger: logger,
+		models: models,
+	}
+	service := &UserService{app: app}
+
+	tests := []struct {
+		name     string
+		req      *proto.LookupUserRequest
+		wantCode codes.Code
+	}{
+		{name: "by id", req: &proto.LookupUserRequest{Key: &proto.LookupUserRequest_Id{Id: 1}}},
+		{name: "by email", req: &proto.LookupUserRequest{Key: &proto.LookupUserRequest_Email{Email: " andrew@GOOGLE.com"}}},
+		{name: "unknown id", req: &proto.LookupUserRequest{Key: &proto.LookupUserRequest_Id{Id: 2}}, wantCode: codes.NotFound},
+		{name: "unknown email", req: &proto.LookupUserRequest{Key: &proto.LookupUserRequest_Email{Email: "john@gmail.com"}}, wantCode: codes.NotFound},
+		{name: "invalid email", req: &proto.LookupUserRequest{Key: &proto.LookupUserRequest_Email{Email: "andrew.com"}}, wantCode: codes.InvalidArgument},
+		{name: "missing key", req: &proto.LookupUserRequest{}, wantCode: codes.InvalidArgument},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user, err := service.LookupUser(context.Background(), tt.req)
+
+			if tt.wantCode != codes.OK {
+				st := status.Convert(app.statusError(context.Background(), err))
+				assert.Equal(t, tt.wantCode, st.Code())
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, int64(1), user.Id)
+		})
+	}
+}
+
+func TestUserService_CreateUser_Profile(t *testing.T) {
+	models := data.Models{
+		Users: mocks.NewUserStorageMock(),
+	}
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	app := &application{
+		logger: logger,
+		models: models,
+	}
+	service := &UserService{app: app}
+
+	birthdate := data.Today().AddDate(-31, 0, 0)
+
+	user, err := service.CreateUser(context.Background(), &proto.CreateUserRequest{
+		Name:      "Andrew",
+		Email:     "andrew@google.com",
+		Birthdate: birthdate.Format(time.DateOnly),
+		Phone:     "+1 415 555 0123",
+		Locale:    "ru-ru",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, birthdate.Format(time.DateOnly), user.Birthdate)
+	assert.Equal(t, int32(31), user.Age)
+	assert.Equal(t, "+14155550123", user.Phone)
+	assert.Equal(t, "ru-RU", user.Locale)
+
+	_, err = service.CreateUser(context.Background(), &proto.CreateUserRequest{
+		Name:      "Andrew",
+		Email:     "andrew@google.com",
+		Birthdate: "15/03/1994",
+	})
+
+	st := status.Convert(app.statusError(context.Background(), err))
+	assert.Equal(t, codes.InvalidArgument, st.Code())
+}
+
+func TestUserService_UpdateUser_MergesAttributes(t *testing.T) {
+	storage := data.NewMemoryUserStorage()
+	ctx := data.ContextWithTenant(context.Background(), "acme")
+
+	require.NoError(t, storage.CreateUser(ctx, &data.User{
+		Name:       "Andrew",
+		Email:      "andrew@google.com",
+		Age:        31,
+		Attributes: data.Attributes{"department": "sales", "team": "core"},
+	}))
+
+	app := &application{
+		logger: slog.New(slog.NewJSONHandler(io.Discard, nil)),
+		models: data.Models{Users: storage},
+	}
+	service := &UserService{app: app}
+
+	user, err := service.UpdateUser(ctx, &proto.UpdateUserRequest{
+		Id:         1,
+		Attributes: map[string]string{"team": "", "cost_center": "42"},
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"department": "sales", "cost_center": "42"}, user.Attributes)
+
+	list, err := service.ListUsers(ctx, &proto.ListUsersRequest{Attributes: map[string]string{"cost_center": "42"}})
+
+	require.NoError(t, err)
+	assert.Len(t, list.Users, 1)
+}
+
+func TestUserService_SearchUsers(t *testing.T) {
+	storage := data.NewMemoryUserStorage()
+	ctx := data.ContextWithTenant(context.Background(), "acme")
+
+	require.NoError(t, storage.CreateUser(ctx, &data.User{Name: "John Smith", Email: "john@gmail.com", Age: 31}))
+	require.NoError(t, storage.CreateUser(ctx, &data.User{Name: "Andrew", Email: "andrew@google.com", Age: 31}))
+
+	app := &application{
+		logger: slog.New(slog.NewJSONHandler(io.Discard, nil)),
+		models: data.Models{Users: storage},
+	}
+	service := &UserService{app: app}
+
+	resp, err := service.SearchUsers(ctx, &proto.SearchUsersRequest{Query: "jon smth"})
+
+	require.NoError(t, err)
+	require.Len(t, resp.Matches, 1)
+	assert.Equal(t, "John Smith", resp.Matches[0].User.Name)
+	assert.Positive(t, resp.Matches[0].Score)
+	assert.Equal(t, int32(1), resp.Metadata.TotalRecords)
+
+	_, err = service.SearchUsers(ctx, &proto.SearchUsersRequest{Query: "  "})
+
+	st := status.Convert(app.statusError(ctx, err))
+	assert.Equal(t, codes.InvalidArgument, st.Code())
+}
+
+func TestUserService_GetUserStats(t *testing.T) {
+	storage := data.NewMemoryUserStorage()
+	ctx := data.ContextWithTenant(context.Background(), "acme")
+
+	require.NoError(t, storage.CreateUser(ctx, &data.User{Name: "John", Email: "john@gmail.com", Age: 31}))
+	require.NoError(t, storage.CreateUser(ctx, &data.User{Name: "Andrew", Email: "andrew@google.com", Age: 20}))
+
+	app := &application{
+		logger: slog.New(slog.NewJSONHandler(io.Discard, nil)),
+		models: data.Models{Users: storage},
+	}
+	service := &UserService{app: app}
+
+	resp, err := service.GetUserStats(ctx, &proto.GetUserStatsRequest{})
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), resp.Total)
+	assert.Len(t, resp.AgeBuckets, 7)
+	assert.Len(t, resp.TopDomains, 2)
+	require.Len(t, resp.Signups, 1)
+	assert.Equal(t, int64(2), resp.Signups[0].Count)
+	assert.NotNil(t, resp.GeneratedAt)
+
+	_, err = service.GetUserStats(ctx, &proto.GetUserStatsRequest{
+		CreatedFrom: timestamppb.New(time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)),
+		CreatedTo:   timestamppb.New(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)),
+		Interval:    "hour",
+	})
+
+	st := status.Convert(app.statusError(ctx, err))
+	assert.Equal(t, codes.InvalidArgument, st.Code())
+
+	var fields []string
+	for _, detail := range st.Details() {
+		if violation, ok := detail.(*errdetails.BadRequest_FieldViolation); ok {
+			fields = append(fields, violation.Field)
+		}
+	}
+	assert.ElementsMatch(t, []string{"created_to", "interval"}, fields)
+}
+
+func TestUserService_EraseUser(t *testing.T) {
+	storage := data.NewMemoryUserStorage()
+	ctx := data.ContextWithTenant(context.Background(), "acme")
+
+	require.NoError(t, storage.CreateUser(ctx, &data.User{Name: "John", Email: "john@gmail.com", Phone: "+15551234567", Age: 31}))
+
+	app := &application{
+		logger: slog.New(slog.NewJSONHandler(io.Discard, nil)),
+		models: data.Models{Users: storage},
+	}
+	service := &UserService{app: app}
+
+	export, err := service.ExportUserData(ctx, &proto.ExportUserDataRequest{Id: 1})
+
+	require.NoError(t, err)
+	assert.Equal(t, "acme", export.TenantId)
+	assert.Equal(t, "john@gmail.com", export.User.Email)
+	assert.Equal(t, "+15551234567", export.User.Phone)
+	assert.Nil(t, export.User.ErasedAt)
+	assert.NotNil(t, export.ExportedAt)
+
+	user, err := service.EraseUser(ctx, &proto.EraseUserRequest{Id: 1})
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), user.Id)
+	assert.Equal(t, data.ErasedEmail(1), user.Email)
+	assert.Empty(t, user.Phone)
+	assert.NotNil(t, user.ErasedAt)
+	assert.Equal(t, int32(2), user.Version)
+
+	export, err = service.ExportUserData(ctx, &proto.ExportUserDataRequest{Id: 1})
+
+	require.NoError(t, err)
+	assert.Equal(t, data.ErasedName, export.User.Name)
+
+	user, err = service.EraseUser(ctx, &proto.EraseUserRequest{Id: 1})
+
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), user.Version, "erasing again changes nothing")
+
+	_, err = service.UpdateUser(ctx, &proto.UpdateUserRequest{Id: 1, Email: wrapperspb.String("john@gmail.com")})
+
+	st := status.Convert(app.statusError(ctx, err))
+	assert.Equal(t, codes.Aborted, st.Code(), "erased users can't be updated")
+
+	_, err = service.EraseUser(ctx, &proto.EraseUserRequest{Id: 2})
+
+	st = status.Convert(app.statusError(ctx, err))
+	assert.Equal(t, codes.NotFound, st.Code())
+}
+
+func TestUserService_DuplicateEmail(t *testing.T) {
+	storage := data.NewMemoryUserStorage()
+	ctx := data.ContextWithTenant(context.Background(), "acme")
+
+	require.NoError(t, storage.CreateUser(ctx, &data.User{Name: "Andrew", Email: "andrew@google.com", Age: 31}))
+	require.NoError(t, storage.CreateUser(ctx, &data.User{Name: "John", Email: "john@gmail.com", Age: 21}))
+
+	app := &application{
+		logger: slog.New(slog.NewJSONHandler(io.Discard, nil)),
+		models: data.Models{Users: storage},
+	}
+	service := &UserService{app: app}
+
+	assertDuplicate := func(t *testing.T, err error) {
+		t.Helper()
+
+		st := status.Convert(app.statusError(ctx, err))
+		assert.Equal(t, codes.InvalidArgument, st.Code())
+
+		var violations []*errdetails.BadRequest_FieldViolation
+		for _, detail := range st.Details() {
+			if violation, ok := detail.(*errdetails.BadRequest_FieldViolation); ok {
+				violations = append(violations, violation)
+			}
+		}
+		require.Len(t, violations, 1)
+		assert.Equal(t, "email", violations[0].Field)
+		assert.Equal(t, "DUPLICATE_EMAIL", violations[0].Reason)
+	}
+
+	t.Run("create", func(t *testing.T) {
+		_, err := service.CreateUser(ctx, &proto.CreateUserRequest{Name: "Andrew", Email: "Andrew@Google.com", Age: 31})
+		assertDuplicate(t, err)
+	})
+
+	t.Run("update", func(t *testing.T) {
+		_, err := service.UpdateUser(ctx, &proto.UpdateUserRequest{Id: 2, Email: wrapperspb.String("andrew@google.com")})
+		assertDuplicate(t, err)
+	})
+
+	t.Run("other tenant", func(t *testing.T) {
+		_, err := service.CreateUser(data.ContextWithTenant(context.Background(), "globex"), &proto.CreateUserRequest{Name: "Andrew", Email: "andrew@google.com", Age: 31})
+		assert.NoError(t, err)
+	})
+}
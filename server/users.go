@@ -2,10 +2,11 @@ package main
 
 import (
 	"context"
-	"errors"
+	"time"
 
 	"github.com/Vadim-Makhnev/grpc/internal/data"
 	"github.com/Vadim-Makhnev/grpc/internal/grpcutils"
+	"github.com/Vadim-Makhnev/grpc/internal/i18n"
 	"github.com/Vadim-Makhnev/grpc/internal/validator"
 	"github.com/Vadim-Makhnev/grpc/proto"
 )
@@ -16,60 +17,85 @@ type UserService struct {
 }
 
 func (u *UserService) CreateUser(ctx context.Context, req *proto.CreateUserRequest) (*proto.UserResponse, error) {
-
 	user := &data.User{
-		Name:  req.Name,
-		Email: req.Email,
-		Age:   req.Age,
+		Name:        req.Name,
+		DisplayName: req.DisplayName,
+		Email:       req.Email,
+		Phone:       req.Phone,
+		Locale:      req.Locale,
+		TimeZone:    req.TimeZone,
+		Age:         req.Age,
+		Attributes:  data.Attributes(nil).Merge(req.Attributes),
+	}
+
+	if req.Birthdate != "" {
+		birthdate, err := parseDate("birthdate", req.Birthdate)
+		if err != nil {
+			return nil, err
+		}
+		user.Birthdate = birthdate
 	}
 
 	v := validator.New()
 
-	if data.ValidateUser(v, user); !v.Valid() {
-		u.app.logger.Warn("validation failed", "errors", v.Errors)
-		return nil, grpcutils.FailedValidation(v.Errors)
-	}
+	_, span := startSpan(ctx, "ValidateUser")
+	data.ValidateUser(v, user)
+	span.End()
 
-	err := u.app.models.Users.CreateUser(user)
-	if err != nil {
-		return nil, grpcutils.Internal(u.app.logger, err, "")
+	if err := v.Err(); err != nil {
+		return nil, err
 	}
 
-	resp := &proto.UserResponse{
-		Id:      user.ID,
-		Name:    user.Name,
-		Email:   user.Email,
-		Age:     user.Age,
-		Version: user.Version,
+	if err := u.app.models.Users.CreateUser(ctx, user); err != nil {
+		return nil, err
 	}
 
-	return resp, nil
+	return userResponse(user), nil
 }
 
 func (u *UserService) GetUser(ctx context.Context, req *proto.GetUserRequest) (*proto.UserResponse, error) {
 	id := req.Id
 
-	user, err := u.app.models.Users.GetUser(id)
+	user, err := u.app.models.Users.GetUser(ctx, id)
 	if err != nil {
-		switch {
-		case errors.Is(err, data.ErrRecordNotFound):
-			return nil, grpcutils.NotFound("")
-		case errors.Is(err, data.ErrInvalidArgument):
-			return nil, grpcutils.InvalidArgument(u.app.logger, err, "")
-		default:
-			return nil, grpcutils.Internal(u.app.logger, err, "")
+		return nil, err
+	}
+
+	return userResponse(user), nil
+}
+
+func (u *UserService) LookupUser(ctx context.Context, req *proto.LookupUserRequest) (*proto.UserResponse, error) {
+	var (
+		user *data.User
+		err  error
+	)
+
+	switch key := req.Key.(type) {
+	case *proto.LookupUserRequest_Id:
+		user, err = u.app.models.Users.GetUser(ctx, key.Id)
+	case *proto.LookupUserRequest_Email:
+		email := data.NormalizeEmail(key.Email)
+
+		v := validator.New()
+		validator.Field(v, "email", email,
+			validator.NotBlank(),
+			validator.Match(validator.EmailRX, i18n.New(i18n.ReasonInvalidEmail)),
+		)
+
+		if err := v.Err(); err != nil {
+			return nil, err
 		}
+
+		user, err = u.app.models.Users.GetUserByEmail(ctx, email)
+	default:
+		return nil, grpcutils.InvalidField("key", i18n.ReasonLookupKeyMissing)
 	}
 
-	resp := &proto.UserResponse{
-		Id:      user.ID,
-		Name:    user.Name,
-		Email:   user.Email,
-		Age:     user.Age,
-		Version: user.Version,
+	if err != nil {
+		return nil, err
 	}
 
-	return resp, nil
+	return userResponse(user), nil
 }
 
 func (u *UserService) ListUsers(ctx context.Context, req *proto.ListUsersRequest) (*proto.ListUsersResponse, error) {
@@ -82,26 +108,26 @@ func (u *UserService) ListUsers(ctx context.Context, req *proto.ListUsersRequest
 	input.Page = int(u.app.getInt32(req.Page, 1))
 	input.PageSize = int(u.app.getInt32(req.PageSize, 20))
 	input.Sort = u.app.getString(req.Sort, "id")
-	input.SortSafelist = []string{"id", "-id", "name", "email", "age"}
+	input.SortSafelist = data.SortSafelist(u.app.models.Users, "id", "-id", "name", "email", "age")
+	input.Attributes = req.Attributes
+	input.HasAttributes = req.HasAttributes
 
-	if data.ValidateFilters(v, input.Filters); !v.Valid() {
-		return nil, grpcutils.FailedValidation(v.Errors)
+	_, span := startSpan(ctx, "ValidateFilters")
+	data.ValidateFilters(v, input.Filters)
+	span.End()
+
+	if err := v.Err(); err != nil {
+		return nil, err
 	}
 
-	users, metadata, err := u.app.models.Users.GetAll(input.Filters)
+	users, metadata, err := u.app.models.Users.GetAll(ctx, input.Filters)
 	if err != nil {
-		return nil, grpcutils.Internal(u.app.logger, err, "")
+		return nil, err
 	}
 
 	protoUsers := make([]*proto.UserResponse, len(users))
 	for i, user := range users {
-		protoUsers[i] = &proto.UserResponse{
-			Id:      user.ID,
-			Name:    user.Name,
-			Email:   user.Email,
-			Age:     user.Age,
-			Version: user.Version,
-		}
+		protoUsers[i] = userResponse(user)
 	}
 
 	protoMetadata := &proto.MetaData{
@@ -118,80 +144,221 @@ func (u *UserService) ListUsers(ctx context.Context, req *proto.ListUsersRequest
 	return resp, nil
 }
 
-func (u *UserService) DeleteUser(ctx context.Context, req *proto.DeleteUserRequest) (*proto.UserResponse, error) {
-	id := req.Id
+func (u *UserService) SearchUsers(ctx context.Context, req *proto.SearchUsersRequest) (*proto.SearchUsersResponse, error) {
+	filters := data.Filters{
+		Page:     int(u.app.getInt32(req.Page, 1)),
+		PageSize: int(u.app.getInt32(req.PageSize, 20)),
+	}
+
+	v := validator.New()
+
+	_, span := startSpan(ctx, "ValidateSearch")
+	data.ValidateSearch(v, req.Query, filters)
+	span.End()
 
-	user, err := u.app.models.Users.DeleteUserById(id)
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+
+	matches, metadata, err := u.app.models.Users.SearchUsers(ctx, req.Query, filters)
 	if err != nil {
-		switch {
-		case errors.Is(err, data.ErrRecordNotFound):
-			return nil, grpcutils.NotFound("")
-		case errors.Is(err, data.ErrInvalidArgument):
-			return nil, grpcutils.InvalidArgument(u.app.logger, err, "")
-		default:
-			return nil, grpcutils.Internal(u.app.logger, err, "")
+		return nil, err
+	}
+
+	protoMatches := make([]*proto.UserMatch, len(matches))
+	for i, match := range matches {
+		protoMatches[i] = &proto.UserMatch{
+			User:  userResponse(match.User),
+			Score: match.Score,
 		}
 	}
 
-	resp := &proto.UserResponse{
-		Id:      user.ID,
-		Name:    user.Name,
-		Email:   user.Email,
-		Age:     user.Age,
-		Version: user.Version,
+	resp := &proto.SearchUsersResponse{
+		Matches: protoMatches,
+		Metadata: &proto.MetaData{
+			TotalRecords: int32(metadata.TotalRecords),
+			Page:         int32(metadata.CurrentPage),
+			PageSize:     int32(metadata.PageSize),
+		},
 	}
 
 	return resp, nil
 }
 
-func (u *UserService) UpdateUser(ctx context.Context, req *proto.UpdateUserRequest) (*proto.UserResponse, error) {
-	id := req.Id
+func (u *UserService) GetUserStats(ctx context.Context, req *proto.GetUserStatsRequest) (*proto.UserStatsResponse, error) {
+	filters := data.StatsFilters{
+		Attributes: req.Attributes,
+		Interval:   u.app.getString(req.Interval, data.IntervalDay),
+		TopDomains: int(u.app.getInt32(req.TopDomains, 10)),
+	}
+
+	if req.CreatedFrom != nil {
+		filters.CreatedFrom = req.CreatedFrom.AsTime()
+	}
 
-	user, err := u.app.models.Users.GetUser(id)
+	if req.CreatedTo != nil {
+		filters.CreatedTo = req.CreatedTo.AsTime()
+	}
+
+	v := validator.New()
+
+	_, span := startSpan(ctx, "ValidateStatsFilters")
+	data.ValidateStatsFilters(v, filters)
+	span.End()
+
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+
+	stats, err := u.app.models.Users.GetUserStats(ctx, filters)
 	if err != nil {
-		switch {
-		case errors.Is(err, data.ErrRecordNotFound):
-			return nil, grpcutils.NotFound("")
-		default:
-			return nil, grpcutils.Internal(u.app.logger, err, "")
-		}
+		return nil, err
 	}
 
-	if req.Name != nil {
-		user.Name = req.Name.Value
+	resp := &proto.UserStatsResponse{
+		Total:       int64(stats.Total),
+		GeneratedAt: timestamp(stats.GeneratedAt),
 	}
 
-	if req.Email != nil {
-		user.Email = req.Email.Value
+	for _, bucket := range stats.AgeBuckets {
+		resp.AgeBuckets = append(resp.AgeBuckets, &proto.AgeBucketCount{Range: bucket.Range, Count: int64(bucket.Count)})
 	}
 
-	if req.Age != nil {
-		user.Age = req.Age.Value
+	for _, domain := range stats.TopDomains {
+		resp.TopDomains = append(resp.TopDomains, &proto.DomainCount{Domain: domain.Domain, Count: int64(domain.Count)})
 	}
 
-	v := validator.New()
+	for _, signup := range stats.Signups {
+		resp.Signups = append(resp.Signups, &proto.SignupCount{PeriodStart: timestamp(signup.PeriodStart), Count: int64(signup.Count)})
+	}
+
+	return resp, nil
+}
 
-	if data.ValidateUser(v, user); !v.Valid() {
-		return nil, grpcutils.FailedValidation(v.Errors)
+func (u *UserService) DeleteUser(ctx context.Context, req *proto.DeleteUserRequest) (*proto.UserResponse, error) {
+	id := req.Id
+
+	user, err := u.app.models.Users.DeleteUserById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return userResponse(user), nil
+}
+
+func (u *UserService) ExportUserData(ctx context.Context, req *proto.ExportUserDataRequest) (*proto.UserDataExport, error) {
+	user, err := u.app.models.Users.GetUser(ctx, req.Id)
+	if err != nil {
+		return nil, err
 	}
 
-	err = u.app.models.Users.UpdateUser(user)
+	return &proto.UserDataExport{
+		TenantId:   user.TenantID,
+		User:       userResponse(user),
+		ExportedAt: timestamp(time.Now()),
+	}, nil
+}
+
+func (u *UserService) EraseUser(ctx context.Context, req *proto.EraseUserRequest) (*proto.UserResponse, error) {
+	user, err := u.app.models.Users.EraseUser(ctx, req.Id)
 	if err != nil {
+		return nil, err
+	}
+
+	u.app.requestLogger(ctx).Info("user erased", "id", user.ID, "version", user.Version)
+
+	return userResponse(user), nil
+}
+
+func (u *UserService) UpdateUser(ctx context.Context, req *proto.UpdateUserRequest) (*proto.UserResponse, error) {
+	id := req.Id
+
+	var user *data.User
+
+	err := u.app.models.WithTx(ctx, func(tx data.Models) error {
+		var err error
+
+		user, err = tx.Users.GetUserForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if req.Name != nil {
+			user.Name = req.Name.Value
+		}
+
+		if req.Email != nil {
+			user.Email = req.Email.Value
+		}
+
+		if req.DisplayName != nil {
+			user.DisplayName = req.DisplayName.Value
+		}
+
+		if req.Phone != nil {
+			user.Phone = req.Phone.Value
+		}
+
+		if req.Locale != nil {
+			user.Locale = req.Locale.Value
+		}
+
+		if req.TimeZone != nil {
+			user.TimeZone = req.TimeZone.Value
+		}
+
+		if req.Attributes != nil {
+			user.Attributes = user.Attributes.Merge(req.Attributes)
+		}
+
 		switch {
-		case errors.Is(err, data.ErrEditConflict):
-			return nil, grpcutils.EditConflict(u.app.logger, err, "")
-		default:
-			return nil, grpcutils.Internal(u.app.logger, err, "")
+		case req.Birthdate != nil:
+			user.Birthdate, err = parseDate("birthdate", req.Birthdate.Value)
+			if err != nil {
+				return err
+			}
+		case req.Age != nil && req.Age.Value != user.Age:
+			// Old clients send the age they last read back with every update, so
+			// only a changed age replaces the birthdate. Clearing it makes
+			// ValidateUser derive a new one from the age.
+			user.Age = req.Age.Value
+			user.Birthdate = time.Time{}
+		}
+
+		v := validator.New()
+
+		_, span := startSpan(ctx, "ValidateUser")
+		data.ValidateUser(v, user)
+		span.End()
+
+		if err := v.Err(); err != nil {
+			return err
 		}
-	}
 
-	resp := &proto.UserResponse{
-		Id:      user.ID,
-		Name:    user.Name,
-		Email:   user.Email,
-		Age:     user.Age,
-		Version: user.Version,
+		return tx.Users.UpdateUser(ctx, user)
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return resp, nil
+	return userResponse(user), nil
+}
+
+func userResponse(user *data.User) *proto.UserResponse {
+	return &proto.UserResponse{
+		Id:          user.ID,
+		Name:        user.Name,
+		DisplayName: user.DisplayName,
+		Email:       user.Email,
+		Phone:       user.Phone,
+		Locale:      user.Locale,
+		TimeZone:    user.TimeZone,
+		Birthdate:   formatDate(user.Birthdate),
+		Attributes:  user.Attributes,
+		Age:         user.Age,
+		CreatedAt:   timestamp(user.CreatedAt),
+		UpdatedAt:   timestamp(user.UpdatedAt),
+		ErasedAt:    timestamp(user.ErasedAt),
+		Version:     user.Version,
+	}
 }